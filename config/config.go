@@ -3,21 +3,36 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	Moderation ModerationConfig `mapstructure:"moderation"`
+	Search     SearchConfig     `mapstructure:"search"`
+	Slug       SlugConfig       `mapstructure:"slug"`
+	Site       SiteConfig       `mapstructure:"site"`
+	Feed       FeedConfig       `mapstructure:"feed"`
+	Sitemap    SitemapConfig    `mapstructure:"sitemap"`
+	Theme      ThemeConfig      `mapstructure:"theme"`
+	Storage    StorageConfig    `mapstructure:"storage"`
+	Avatar     AvatarConfig     `mapstructure:"avatar"`
+	Media      MediaConfig      `mapstructure:"media"`
+	Trending   TrendingConfig   `mapstructure:"trending"`
+	Counter    CounterConfig    `mapstructure:"counter"`
 }
 
 type ServerConfig struct {
 	Port     int    `mapstructure:"port"`
 	Mode     string `mapstructure:"mode"`
 	GrpcPort int    `mapstructure:"grpc_port"`
+	// 可信的反向代理（IP或CIDR），只信任它们转发的 X-Forwarded-For/X-Real-IP
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
@@ -39,6 +54,107 @@ type JWTConfig struct {
 	Secret string `mapstructure:"secret"`
 }
 
+type SearchConfig struct {
+	// 全文索引目录
+	IndexPath string `mapstructure:"index_path"`
+}
+
+type SiteConfig struct {
+	Title       string `mapstructure:"title"`
+	Description string `mapstructure:"description"`
+	// 站点对外访问地址，用于生成订阅源中的链接
+	URL      string `mapstructure:"url"`
+	Language string `mapstructure:"language"`
+}
+
+type FeedConfig struct {
+	// 每个订阅源包含的文章数
+	Size int `mapstructure:"size"`
+	// 输出全文（false则只输出摘要）
+	FullContent bool `mapstructure:"full_content"`
+}
+
+type SitemapConfig struct {
+	// 站点地图缓存时间，过期后检查数据变化并只重新生成变化的分页
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// robots.txt 禁止抓取的路径
+	RobotsDisallow []string `mapstructure:"robots_disallow"`
+	// robots.txt 附加内容（原样输出）
+	RobotsExtra string `mapstructure:"robots_extra"`
+}
+
+type ThemeConfig struct {
+	// 主题根目录，每个子目录是一套主题
+	Dir string `mapstructure:"dir"`
+	// 使用的主题名（Dir 下的子目录）
+	Name string `mapstructure:"name"`
+	// 列表页每页文章数
+	PageSize int `mapstructure:"page_size"`
+}
+
+type StorageConfig struct {
+	// 上传文件的存储：local（本地目录）或 s3（S3 兼容的对象存储，如 MinIO）
+	Driver string             `mapstructure:"driver"`
+	Local  LocalStorageConfig `mapstructure:"local"`
+	S3     S3StorageConfig    `mapstructure:"s3"`
+}
+
+type LocalStorageConfig struct {
+	// 上传目录
+	Dir string `mapstructure:"dir"`
+	// 上传目录对外的路径前缀
+	URL string `mapstructure:"url"`
+}
+
+type S3StorageConfig struct {
+	// 服务地址，如 https://s3.amazonaws.com 或 http://localhost:9000
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	// 使用 endpoint/bucket/key 形式的地址（MinIO 等自建服务通常需要）
+	PathStyle bool `mapstructure:"path_style"`
+	// 对外访问地址（如CDN），为空时使用对象的存储地址
+	PublicURL string `mapstructure:"public_url"`
+}
+
+type AvatarConfig struct {
+	// 上传头像时生成的尺寸（像素，正方形），用户资料中保存最大的一个
+	Sizes []int `mapstructure:"sizes"`
+	// 没有头像的用户生成的默认头像：initials（名字首字母，中文取第一个字）或 identicon（对称色块）
+	DefaultStyle string `mapstructure:"default_style"`
+}
+
+type MediaConfig struct {
+	// 媒体库单个文件的大小上限（MB）
+	MaxFileSizeMB int64 `mapstructure:"max_file_size_mb"`
+	// 每个用户的存储配额（MB），0表示不限制；管理员不受限制
+	QuotaMB int64 `mapstructure:"quota_mb"`
+	// 上传图片时生成的缩小版本宽度（像素，用于封面等响应式图片），只生成小于原图的
+	ImageWidths []int `mapstructure:"image_widths"`
+}
+
+type TrendingConfig struct {
+	// 从数据库重建点赞、收藏、评论热度的间隔（平时由各操作实时累加）
+	RebuildInterval time.Duration `mapstructure:"rebuild_interval"`
+}
+
+type CounterConfig struct {
+	// 点赞/收藏/评论/浏览数从Redis批量写入数据库的间隔
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+}
+
+type SlugConfig struct {
+	// 中文slug生成方式：pinyin（转拼音）或 strip（去掉非ASCII字符）
+	Mode string `mapstructure:"mode"`
+}
+
+type ModerationConfig struct {
+	// 被多少个不同用户举报后自动隐藏内容，0表示不自动隐藏
+	AutoHideThreshold int `mapstructure:"auto_hide_threshold"`
+}
+
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -60,8 +176,34 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.mode", "debug")
 	viper.SetDefault("server.grpc_port", 50051)
+	viper.SetDefault("server.trusted_proxies", []string{"127.0.0.1", "::1"})
 	viper.SetDefault("redis.port", 6379)
 	viper.SetDefault("redis.db", 0)
+	viper.SetDefault("moderation.auto_hide_threshold", 3)
+	viper.SetDefault("search.index_path", "./data/search.bleve")
+	viper.SetDefault("slug.mode", "pinyin")
+	viper.SetDefault("site.title", "博客")
+	viper.SetDefault("site.description", "记录与分享")
+	viper.SetDefault("site.url", "http://localhost:8080")
+	viper.SetDefault("site.language", "zh-CN")
+	viper.SetDefault("feed.size", 20)
+	viper.SetDefault("feed.full_content", true)
+	viper.SetDefault("sitemap.cache_ttl", "1m")
+	viper.SetDefault("sitemap.robots_disallow", []string{"/api/"})
+	viper.SetDefault("theme.dir", "./templates")
+	viper.SetDefault("theme.name", "default")
+	viper.SetDefault("theme.page_size", 10)
+	viper.SetDefault("storage.driver", "local")
+	viper.SetDefault("storage.local.dir", "./uploads")
+	viper.SetDefault("storage.local.url", "/uploads")
+	viper.SetDefault("storage.s3.region", "us-east-1")
+	viper.SetDefault("avatar.sizes", []int{32, 64, 256})
+	viper.SetDefault("avatar.default_style", "initials")
+	viper.SetDefault("media.max_file_size_mb", 20)
+	viper.SetDefault("media.quota_mb", 500)
+	viper.SetDefault("media.image_widths", []int{320, 640, 1024, 1600})
+	viper.SetDefault("trending.rebuild_interval", "1h")
+	viper.SetDefault("counter.flush_interval", "5s")
 
 	// 尝试读取配置文件
 	if err := viper.ReadInConfig(); err != nil {
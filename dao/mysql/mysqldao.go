@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserSQL interface {
@@ -82,6 +83,75 @@ type StarSQL interface {
 	FindStars(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.UserStarPost, error)
 }
 
+// 提及
+type MentionSQL interface {
+	InsertMention(ctx context.Context, m *model.Mention) error
+	FindMentions(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Mention, error)
+	DeleteMentions(ctx context.Context, targetType model.MentionTarget, targetID uint) error
+	// DeleteUserMentions 删除目标内容中对指定用户的提及
+	DeleteUserMentions(ctx context.Context, targetType model.MentionTarget, targetID uint, userIDs []uint) error
+}
+
+// 通知
+type NotificationSQL interface {
+	InsertNotification(ctx context.Context, n *model.Notification) error
+	UpdateNotification(ctx context.Context, id uint, updates map[string]any) error
+	MarkNotificationsRead(ctx context.Context, userID uint, ids ...uint) (int64, error)
+	FindNotifications(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Notification, error)
+	CountNotifications(ctx context.Context, condition interface{}, args ...interface{}) (int64, error)
+
+	// 聚合通知的触发者
+	AddNotificationActor(ctx context.Context, notificationID, actorID uint) error
+	CountNotificationActors(ctx context.Context, notificationID uint) (int64, error)
+
+	// 通知偏好
+	FindPreferences(ctx context.Context, userID uint) ([]*model.NotificationPreference, error)
+	SavePreference(ctx context.Context, p *model.NotificationPreference) error
+}
+
+// 举报
+type ReportSQL interface {
+	InsertReport(ctx context.Context, r *model.Report) error
+	GetReportByID(ctx context.Context, id uint) (*model.Report, error)
+	// UpdatePendingReports 更新目标的全部待处理举报
+	UpdatePendingReports(ctx context.Context, targetType model.ReportTarget, targetID uint, updates map[string]any) (int64, error)
+	FindReports(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Report, error)
+	CountReports(ctx context.Context, condition interface{}, args ...interface{}) (int64, error)
+}
+
+// 媒体库
+type MediaSQL interface {
+	InsertMedia(ctx context.Context, m *model.Media) error
+	GetMediaByID(ctx context.Context, id uint) (*model.Media, error)
+	DeleteMedia(ctx context.Context, id uint) error
+	FindMedia(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Media, error)
+	// SumMediaSize 用户已上传文件的总大小
+	SumMediaSize(ctx context.Context, userID uint) (int64, error)
+
+	// 文章引用
+	FindMediaReferences(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.MediaReference, error)
+	// ReplacePostReferences 用 mediaIDs 替换文章的全部引用
+	ReplacePostReferences(ctx context.Context, postID uint, mediaIDs []uint) error
+}
+
+// 系列文章
+type SeriesSQL interface {
+	InsertSeries(ctx context.Context, series *model.Series) error
+	GetSeriesByID(ctx context.Context, id uint) (*model.Series, error)
+	GetSeriesBySlug(ctx context.Context, slug string) (*model.Series, error)
+	UpdateSeries(ctx context.Context, id uint, updates map[string]any) error
+	// DeleteSeries 删除系列及其文章列表
+	DeleteSeries(ctx context.Context, id uint) error
+	FindSeries(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Series, error)
+
+	// 系列中的文章（按顺序）
+	FindSeriesPosts(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.SeriesPost, error)
+	// ReplaceSeriesPosts 用 postIDs 的顺序替换系列的全部文章
+	ReplaceSeriesPosts(ctx context.Context, seriesID uint, postIDs []uint) error
+	// RemovePost 把文章从所在系列中移除，后面的文章依次前移
+	RemovePost(ctx context.Context, postID uint) error
+}
+
 // 用户
 type userSQL struct{ db *gorm.DB }
 
@@ -377,3 +447,260 @@ func (d *commentLikeSQL) CommentFindLikes(ctx context.Context, condition interfa
 	err := d.db.WithContext(ctx).Where(condition, args...).Find(&likes).Error
 	return likes, err
 }
+
+// 提及
+type mentionSQL struct{ db *gorm.DB }
+
+func NewMentionSQL(db *gorm.DB) MentionSQL { return &mentionSQL{db: db} }
+
+func (d *mentionSQL) InsertMention(ctx context.Context, m *model.Mention) error {
+	return d.db.WithContext(ctx).Create(m).Error
+}
+
+func (d *mentionSQL) FindMentions(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Mention, error) {
+	var mentions []*model.Mention
+	err := d.db.WithContext(ctx).Where(condition, args...).Find(&mentions).Error
+	return mentions, err
+}
+
+func (d *mentionSQL) DeleteMentions(ctx context.Context, targetType model.MentionTarget, targetID uint) error {
+	return d.db.WithContext(ctx).
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Delete(&model.Mention{}).Error
+}
+
+func (d *mentionSQL) DeleteUserMentions(ctx context.Context, targetType model.MentionTarget, targetID uint, userIDs []uint) error {
+	return d.db.WithContext(ctx).
+		Where("target_type = ? AND target_id = ? AND user_id IN ?", targetType, targetID, userIDs).
+		Delete(&model.Mention{}).Error
+}
+
+// 通知
+type notificationSQL struct{ db *gorm.DB }
+
+func NewNotificationSQL(db *gorm.DB) NotificationSQL { return &notificationSQL{db: db} }
+
+func (d *notificationSQL) InsertNotification(ctx context.Context, n *model.Notification) error {
+	return d.db.WithContext(ctx).Create(n).Error
+}
+
+func (d *notificationSQL) UpdateNotification(ctx context.Context, id uint, updates map[string]any) error {
+	return d.db.WithContext(ctx).Model(&model.Notification{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// MarkNotificationsRead 标记已读，不传ids时标记该用户全部通知
+func (d *notificationSQL) MarkNotificationsRead(ctx context.Context, userID uint, ids ...uint) (int64, error) {
+	query := d.db.WithContext(ctx).Model(&model.Notification{}).Where("user_id = ? AND is_read = ?", userID, false)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	// 使用UpdateColumn避免刷新updated_at，保持通知时间不变
+	result := query.UpdateColumn("is_read", true)
+	return result.RowsAffected, result.Error
+}
+
+func (d *notificationSQL) FindNotifications(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+	err := d.db.WithContext(ctx).Where(condition, args...).Find(&notifications).Error
+	return notifications, err
+}
+
+func (d *notificationSQL) CountNotifications(ctx context.Context, condition interface{}, args ...interface{}) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.Notification{}).Where(condition, args...).Count(&count).Error
+	return count, err
+}
+
+// AddNotificationActor 记录触发者，已记录过的忽略
+func (d *notificationSQL) AddNotificationActor(ctx context.Context, notificationID, actorID uint) error {
+	return d.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.NotificationActor{NotificationID: notificationID, ActorID: actorID}).Error
+}
+
+func (d *notificationSQL) CountNotificationActors(ctx context.Context, notificationID uint) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.NotificationActor{}).Where("notification_id = ?", notificationID).Count(&count).Error
+	return count, err
+}
+
+func (d *notificationSQL) FindPreferences(ctx context.Context, userID uint) ([]*model.NotificationPreference, error) {
+	var prefs []*model.NotificationPreference
+	err := d.db.WithContext(ctx).Where("user_id = ?", userID).Find(&prefs).Error
+	return prefs, err
+}
+
+func (d *notificationSQL) SavePreference(ctx context.Context, p *model.NotificationPreference) error {
+	return d.db.WithContext(ctx).Save(p).Error
+}
+
+// 举报
+type reportSQL struct{ db *gorm.DB }
+
+func NewReportSQL(db *gorm.DB) ReportSQL { return &reportSQL{db: db} }
+
+func (d *reportSQL) InsertReport(ctx context.Context, r *model.Report) error {
+	return d.db.WithContext(ctx).Create(r).Error
+}
+
+func (d *reportSQL) GetReportByID(ctx context.Context, id uint) (*model.Report, error) {
+	var r model.Report
+	err := d.db.WithContext(ctx).First(&r, id).Error
+	return &r, err
+}
+
+func (d *reportSQL) UpdatePendingReports(ctx context.Context, targetType model.ReportTarget, targetID uint, updates map[string]any) (int64, error) {
+	result := d.db.WithContext(ctx).Model(&model.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, model.ReportStatusPending).
+		Updates(updates)
+	return result.RowsAffected, result.Error
+}
+
+func (d *reportSQL) FindReports(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Report, error) {
+	var reports []*model.Report
+	err := d.db.WithContext(ctx).Where(condition, args...).Find(&reports).Error
+	return reports, err
+}
+
+func (d *reportSQL) CountReports(ctx context.Context, condition interface{}, args ...interface{}) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.Report{}).Where(condition, args...).Count(&count).Error
+	return count, err
+}
+
+// 媒体库
+type mediaSQL struct{ db *gorm.DB }
+
+func NewMediaSQL(db *gorm.DB) MediaSQL { return &mediaSQL{db: db} }
+
+func (d *mediaSQL) InsertMedia(ctx context.Context, m *model.Media) error {
+	return d.db.WithContext(ctx).Create(m).Error
+}
+
+func (d *mediaSQL) GetMediaByID(ctx context.Context, id uint) (*model.Media, error) {
+	var m model.Media
+	err := d.db.WithContext(ctx).First(&m, id).Error
+	return &m, err
+}
+
+func (d *mediaSQL) DeleteMedia(ctx context.Context, id uint) error {
+	return d.db.WithContext(ctx).Delete(&model.Media{}, id).Error
+}
+
+func (d *mediaSQL) FindMedia(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Media, error) {
+	var media []*model.Media
+	err := d.db.WithContext(ctx).Where(condition, args...).Find(&media).Error
+	return media, err
+}
+
+func (d *mediaSQL) SumMediaSize(ctx context.Context, userID uint) (int64, error) {
+	var total int64
+	err := d.db.WithContext(ctx).Model(&model.Media{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+func (d *mediaSQL) FindMediaReferences(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.MediaReference, error) {
+	var refs []*model.MediaReference
+	err := d.db.WithContext(ctx).Where(condition, args...).Find(&refs).Error
+	return refs, err
+}
+
+func (d *mediaSQL) ReplacePostReferences(ctx context.Context, postID uint, mediaIDs []uint) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", postID).Delete(&model.MediaReference{}).Error; err != nil {
+			return err
+		}
+		if len(mediaIDs) == 0 {
+			return nil
+		}
+		refs := make([]*model.MediaReference, 0, len(mediaIDs))
+		for _, id := range mediaIDs {
+			refs = append(refs, &model.MediaReference{MediaID: id, PostID: postID})
+		}
+		return tx.Create(&refs).Error
+	})
+}
+
+// 系列文章
+type seriesSQL struct{ db *gorm.DB }
+
+func NewSeriesSQL(db *gorm.DB) SeriesSQL { return &seriesSQL{db: db} }
+
+func (d *seriesSQL) InsertSeries(ctx context.Context, series *model.Series) error {
+	return d.db.WithContext(ctx).Create(series).Error
+}
+
+func (d *seriesSQL) GetSeriesByID(ctx context.Context, id uint) (*model.Series, error) {
+	var series model.Series
+	err := d.db.WithContext(ctx).First(&series, id).Error
+	return &series, err
+}
+
+func (d *seriesSQL) GetSeriesBySlug(ctx context.Context, slug string) (*model.Series, error) {
+	var series model.Series
+	err := d.db.WithContext(ctx).Where("slug = ?", slug).First(&series).Error
+	return &series, err
+}
+
+func (d *seriesSQL) UpdateSeries(ctx context.Context, id uint, updates map[string]any) error {
+	return d.db.WithContext(ctx).Model(&model.Series{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (d *seriesSQL) DeleteSeries(ctx context.Context, id uint) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ?", id).Delete(&model.SeriesPost{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Series{}, id).Error
+	})
+}
+
+func (d *seriesSQL) FindSeries(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.Series, error) {
+	var series []*model.Series
+	err := d.db.WithContext(ctx).Where(condition, args...).Find(&series).Error
+	return series, err
+}
+
+func (d *seriesSQL) FindSeriesPosts(ctx context.Context, condition interface{}, args ...interface{}) ([]*model.SeriesPost, error) {
+	var entries []*model.SeriesPost
+	err := d.db.WithContext(ctx).Where(condition, args...).Order("series_id, position").Find(&entries).Error
+	return entries, err
+}
+
+func (d *seriesSQL) ReplaceSeriesPosts(ctx context.Context, seriesID uint, postIDs []uint) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ?", seriesID).Delete(&model.SeriesPost{}).Error; err != nil {
+			return err
+		}
+		if len(postIDs) == 0 {
+			return nil
+		}
+		entries := make([]*model.SeriesPost, 0, len(postIDs))
+		for i, id := range postIDs {
+			entries = append(entries, &model.SeriesPost{SeriesID: seriesID, PostID: id, Position: i + 1})
+		}
+		return tx.Create(&entries).Error
+	})
+}
+
+func (d *seriesSQL) RemovePost(ctx context.Context, postID uint) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entry model.SeriesPost
+		err := tx.Where("post_id = ?", postID).First(&entry).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.SeriesPost{}).
+			Where("series_id = ? AND position > ?", entry.SeriesID, entry.Position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error
+	})
+}
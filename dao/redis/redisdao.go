@@ -3,14 +3,25 @@ package dao
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-redis/redis/v8"
 )
 
 // 接口
 type ViewCache interface {
+	// 原始浏览量（每次打开都计）
 	IncrViewCount(ctx context.Context, postID uint) error
 	GetViewCount(ctx context.Context, postID uint) (int64, error)
+
+	// 每天的去重访客（HyperLogLog，有约0.8%的误差）
+	// AddViewer 记录 at 当天的访客，返回是否为当天的新访客
+	AddViewer(ctx context.Context, postID uint, viewer string, at time.Time) (bool, error)
+	// CountViewers from 到 to 之间（按天，含两端）的去重访客数
+	CountViewers(ctx context.Context, postID uint, from, to time.Time) (int64, error)
 }
 
 type LikeCache interface {
@@ -39,15 +50,95 @@ type CommentCache interface {
 	UnlikeComment(ctx context.Context, userID, commentID uint) error
 	CountCommentLikes(ctx context.Context, commentID uint) (int64, error)
 	DeleteCommentLikeCache(ctx context.Context, commentID uint) error
+
+	// 评论热度排行（parentID为0表示帖子下的一级评论，否则为该评论下的回复）
+	SetCommentHotScore(ctx context.Context, postID, parentID, commentID uint, score float64) error
+	RemoveCommentHotScore(ctx context.Context, postID, parentID, commentID uint) error
+	RangeHotComments(ctx context.Context, postID, parentID uint, offset, limit int) ([]uint, error)
+	CountHotComments(ctx context.Context, postID, parentID uint) (int64, error)
+	ResetHotComments(ctx context.Context, postID, parentID uint, scores map[uint]float64) error
+}
+
+// Suggestion 搜索联想项
+type Suggestion struct {
+	Type   string  `json:"type"` // post / tag
+	ID     uint    `json:"id"`
+	Text   string  `json:"text"`
+	Weight float64 `json:"-"`
+}
+
+type SuggestCache interface {
+	// SetSuggestion 设置联想项及其匹配键（会替换该项原有的匹配键）
+	SetSuggestion(ctx context.Context, s *Suggestion, keys []string) error
+	RemoveSuggestion(ctx context.Context, typ string, id uint) error
+	// Suggest 按前缀查找联想项，权重高的在前
+	Suggest(ctx context.Context, prefix string, limit int) ([]*Suggestion, error)
+	// ClearSuggestions 清空联想项（重建前调用）
+	ClearSuggestions(ctx context.Context) error
+}
+
+// RelatedCache 相关文章推荐：文章的词频向量、全站文档频率和预先计算好的相关文章
+type RelatedCache interface {
+	// SetPostTerms 保存文章的词频（替换原有的），同时更新各词的文档频率
+	SetPostTerms(ctx context.Context, postID uint, terms map[string]float64) error
+	DeletePostTerms(ctx context.Context, postID uint) error
+	// ResetPostTerms 清空所有文章的词频和文档频率（重建前调用）
+	ResetPostTerms(ctx context.Context) error
+	GetPostTerms(ctx context.Context, postIDs []uint) (map[uint]map[string]float64, error)
+	// DocFreqs 各词出现在多少篇文章中，以及已记录的文章总数
+	DocFreqs(ctx context.Context, terms []string) (map[string]int64, int64, error)
+
+	// 相关文章（相似度高的在前）
+	SetRelated(ctx context.Context, postID uint, scores map[uint]float64) error
+	// AddRelated 加入一篇相关文章，只保留相似度最高的 limit 篇
+	AddRelated(ctx context.Context, postID, relatedID uint, score float64, limit int) error
+	GetRelated(ctx context.Context, postID uint, limit int) ([]uint, error)
+	DeleteRelated(ctx context.Context, postID uint) error
+}
+
+// CounterCache 文章计数的写缓冲：增量先累加在Redis中，由后台定时写入数据库
+type CounterCache interface {
+	// IncrCounter 累加文章计数的增量，并把文章加入待写入集合
+	IncrCounter(ctx context.Context, postID uint, field string, delta int64) error
+	// PendingCounters 尚未写入数据库的增量（含正在写入的）
+	PendingCounters(ctx context.Context, postID uint) (map[string]int64, error)
+	// DirtyCounterPosts 有待写入增量的文章
+	DirtyCounterPosts(ctx context.Context) ([]uint, error)
+	// ClaimCounters 取出文章的增量准备写入（移到写入中，上次未确认的增量一并取出）
+	ClaimCounters(ctx context.Context, postID uint) (map[string]int64, error)
+	// AckCounters 确认一批文章取出的增量已写入数据库（原子执行，计数不会同时包含已写入的增量）
+	AckCounters(ctx context.Context, postIDs []uint) error
+}
+
+// TrendingBucket 热度分桶：Hourly 为按小时分桶，否则按天；Index 为自 Unix 纪元起的小时数或天数
+type TrendingBucket struct {
+	Hourly bool
+	Index  int64
+	// 合并排行时的权重（随分桶的时间衰减）
+	Weight float64
+}
+
+// TrendingCache 热门文章：按小时和按天分桶累计的热度分数，浏览和互动（点赞/收藏/评论）分开保存
+type TrendingCache interface {
+	// IncrTrending 在 at 所在的小时和天分桶中给文章加分
+	IncrTrending(ctx context.Context, postID uint, at time.Time, score float64, view bool) error
+	// ResetTrendingInteractions 用给定分数替换 [from, to] 范围内的互动分桶（浏览分桶不变）
+	ResetTrendingInteractions(ctx context.Context, hourly bool, from, to int64, scores map[int64]map[uint]float64) error
+	// RangeTrending 按权重合并分桶得到排行（结果缓存 ttl），返回分数高的文章
+	RangeTrending(ctx context.Context, window string, buckets []TrendingBucket, ttl time.Duration, limit int) ([]uint, error)
 }
 
 type redisCache struct{ rdb redis.UniversalClient }
 
 var (
-	_ ViewCache    = (*redisCache)(nil)
-	_ LikeCache    = (*redisCache)(nil)
-	_ StarCache    = (*redisCache)(nil)
-	_ CommentCache = (*redisCache)(nil)
+	_ ViewCache     = (*redisCache)(nil)
+	_ LikeCache     = (*redisCache)(nil)
+	_ StarCache     = (*redisCache)(nil)
+	_ CommentCache  = (*redisCache)(nil)
+	_ SuggestCache  = (*redisCache)(nil)
+	_ RelatedCache  = (*redisCache)(nil)
+	_ CounterCache  = (*redisCache)(nil)
+	_ TrendingCache = (*redisCache)(nil)
 )
 
 func NewRedisCache(rdb redis.UniversalClient) *redisCache {
@@ -63,6 +154,35 @@ func (c *redisCache) GetViewCount(ctx context.Context, postID uint) (int64, erro
 	return c.rdb.Get(ctx, fmt.Sprintf("post:%d:views", postID)).Int64()
 }
 
+// 去重访客只保留最近几天（文章统计中展示最近7天）
+const viewersTTL = 8 * 24 * time.Hour
+
+func viewersKey(postID uint, day time.Time) string {
+	return fmt.Sprintf("post:%d:viewers:%s", postID, day.Format("20060102"))
+}
+
+func (c *redisCache) AddViewer(ctx context.Context, postID uint, viewer string, at time.Time) (bool, error) {
+	key := viewersKey(postID, at)
+	pipe := c.rdb.TxPipeline()
+	added := pipe.PFAdd(ctx, key, viewer)
+	pipe.Expire(ctx, key, viewersTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return added.Val() == 1, nil
+}
+
+func (c *redisCache) CountViewers(ctx context.Context, postID uint, from, to time.Time) (int64, error) {
+	var keys []string
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		keys = append(keys, viewersKey(postID, day))
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	return c.rdb.PFCount(ctx, keys...).Result()
+}
+
 // 帖子点赞
 func (c *redisCache) IsLiked(ctx context.Context, userID, postID uint) (bool, error) {
 	return c.rdb.SIsMember(ctx, fmt.Sprintf("post:%d:likes", postID), userID).Result()
@@ -129,3 +249,588 @@ func (c *redisCache) CountCommentLikes(ctx context.Context, commentID uint) (int
 func (c *redisCache) DeleteCommentLikeCache(ctx context.Context, commentID uint) error {
 	return c.rdb.Del(ctx, fmt.Sprintf("comment:%d:likes", commentID)).Err()
 }
+
+// 评论热度排行
+func hotCommentsKey(postID, parentID uint) string {
+	if parentID == 0 {
+		return fmt.Sprintf("post:%d:hotComments", postID)
+	}
+	return fmt.Sprintf("comment:%d:hotReplies", parentID)
+}
+
+func (c *redisCache) SetCommentHotScore(ctx context.Context, postID, parentID, commentID uint, score float64) error {
+	return c.rdb.ZAdd(ctx, hotCommentsKey(postID, parentID), &redis.Z{
+		Score:  score,
+		Member: commentID,
+	}).Err()
+}
+
+func (c *redisCache) RemoveCommentHotScore(ctx context.Context, postID, parentID, commentID uint) error {
+	return c.rdb.ZRem(ctx, hotCommentsKey(postID, parentID), commentID).Err()
+}
+
+func (c *redisCache) RangeHotComments(ctx context.Context, postID, parentID uint, offset, limit int) ([]uint, error) {
+	members, err := c.rdb.ZRevRange(ctx, hotCommentsKey(postID, parentID), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+func (c *redisCache) CountHotComments(ctx context.Context, postID, parentID uint) (int64, error) {
+	return c.rdb.ZCard(ctx, hotCommentsKey(postID, parentID)).Result()
+}
+
+// ResetHotComments 用给定分数整体重建热度排行
+func (c *redisCache) ResetHotComments(ctx context.Context, postID, parentID uint, scores map[uint]float64) error {
+	key := hotCommentsKey(postID, parentID)
+	pipe := c.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	for commentID, score := range scores {
+		pipe.ZAdd(ctx, key, &redis.Z{Score: score, Member: commentID})
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// 搜索联想
+// search:suggest:p:{前缀} 为匹配该前缀的联想项（ZSET，分数为权重），查找时直接取权重最高的几项；
+// 前缀最长 suggestMaxPrefix 个字符，更长的输入从最长前缀的候选中过滤
+// suggest:text/suggest:keys 保存展示文本和匹配键
+const (
+	suggestTextKey = "search:suggest:text"
+	suggestKeysKey = "search:suggest:keys"
+
+	// 建立前缀索引的最大长度（字符）
+	suggestMaxPrefix = 32
+	// 输入超过最大前缀长度时读取的候选数
+	suggestCandidates = 200
+)
+
+func suggestPrefixKey(prefix string) string {
+	return "search:suggest:p:" + prefix
+}
+
+func suggestionMember(typ string, id uint) string {
+	return fmt.Sprintf("%s:%d", typ, id)
+}
+
+// suggestionPrefixes 匹配键（以\x00分隔）的全部前缀，去重
+func suggestionPrefixes(keys string) []string {
+	seen := make(map[string]bool)
+	var prefixes []string
+	for _, key := range strings.Split(keys, "\x00") {
+		runes := []rune(key)
+		for i := 1; i <= len(runes) && i <= suggestMaxPrefix; i++ {
+			prefix := string(runes[:i])
+			if !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	return prefixes
+}
+
+func (c *redisCache) SetSuggestion(ctx context.Context, s *Suggestion, keys []string) error {
+	member := suggestionMember(s.Type, s.ID)
+	oldKeys, err := c.rdb.HGet(ctx, suggestKeysKey, member).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	newKeys := strings.Join(keys, "\x00")
+
+	newPrefixes := suggestionPrefixes(newKeys)
+	keep := make(map[string]bool, len(newPrefixes))
+	for _, prefix := range newPrefixes {
+		keep[prefix] = true
+	}
+
+	pipe := c.rdb.TxPipeline()
+	for _, prefix := range suggestionPrefixes(oldKeys) {
+		if !keep[prefix] {
+			pipe.ZRem(ctx, suggestPrefixKey(prefix), member)
+		}
+	}
+	for _, prefix := range newPrefixes {
+		pipe.ZAdd(ctx, suggestPrefixKey(prefix), &redis.Z{Score: s.Weight, Member: member})
+	}
+	pipe.HSet(ctx, suggestTextKey, member, s.Text)
+	pipe.HSet(ctx, suggestKeysKey, member, newKeys)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (c *redisCache) RemoveSuggestion(ctx context.Context, typ string, id uint) error {
+	member := suggestionMember(typ, id)
+	oldKeys, err := c.rdb.HGet(ctx, suggestKeysKey, member).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pipe := c.rdb.TxPipeline()
+	for _, prefix := range suggestionPrefixes(oldKeys) {
+		pipe.ZRem(ctx, suggestPrefixKey(prefix), member)
+	}
+	pipe.HDel(ctx, suggestTextKey, member)
+	pipe.HDel(ctx, suggestKeysKey, member)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (c *redisCache) Suggest(ctx context.Context, prefix string, limit int) ([]*Suggestion, error) {
+	// 超过最大前缀长度时从最长前缀中多取一些，再按完整输入过滤
+	key, count := prefix, limit
+	long := utf8.RuneCountInString(prefix) > suggestMaxPrefix
+	if long {
+		key, count = string([]rune(prefix)[:suggestMaxPrefix]), suggestCandidates
+	}
+
+	entries, err := c.rdb.ZRevRangeWithScores(ctx, suggestPrefixKey(key), 0, int64(count-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return []*Suggestion{}, nil
+	}
+
+	members := make([]string, len(entries))
+	for i, z := range entries {
+		members[i], _ = z.Member.(string)
+	}
+
+	pipe := c.rdb.Pipeline()
+	texts := pipe.HMGet(ctx, suggestTextKey, members...)
+	var matchKeys *redis.SliceCmd
+	if long {
+		matchKeys = pipe.HMGet(ctx, suggestKeysKey, members...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := texts.Val()
+	suggestions := make([]*Suggestion, 0, limit)
+	for i, m := range members {
+		if len(suggestions) >= limit {
+			break
+		}
+		text, ok := values[i].(string)
+		if !ok {
+			continue
+		}
+		if long && !matchesPrefix(matchKeys.Val()[i], prefix) {
+			continue
+		}
+		sep := strings.LastIndex(m, ":")
+		id, err := strconv.ParseUint(m[sep+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		suggestions = append(suggestions, &Suggestion{
+			Type:   m[:sep],
+			ID:     uint(id),
+			Text:   text,
+			Weight: entries[i].Score,
+		})
+	}
+	return suggestions, nil
+}
+
+// matchesPrefix 匹配键（以\x00分隔）中是否有以 prefix 开头的
+func matchesPrefix(keys interface{}, prefix string) bool {
+	str, _ := keys.(string)
+	for _, key := range strings.Split(str, "\x00") {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *redisCache) ClearSuggestions(ctx context.Context) error {
+	iter := c.rdb.Scan(ctx, 0, suggestPrefixKey("*"), 1000).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) >= 1000 {
+			if err := c.rdb.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	keys = append(keys, suggestTextKey, suggestKeysKey)
+	return c.rdb.Del(ctx, keys...).Err()
+}
+
+// 相关文章
+// related:terms:{id} 为文章的词频（HASH），related:df 为各词的文档频率，related:docs 为已记录词频的文章
+// related:post:{id} 为预先计算好的相关文章（ZSET，分数为相似度）
+const (
+	relatedDocFreqKey = "related:df"
+	relatedDocsKey    = "related:docs"
+)
+
+func relatedTermsKey(postID uint) string {
+	return fmt.Sprintf("related:terms:%d", postID)
+}
+
+func relatedPostsKey(postID uint) string {
+	return fmt.Sprintf("related:post:%d", postID)
+}
+
+// setPostTermsScript 原子地替换文章的词频并更新文档频率（读取旧词频和更新在同一脚本中，并发时不会重复增减）
+// KEYS: 词频, 文档频率, 文章集合；ARGV: 文章ID, 词1, 词频1, 词2, 词频2...
+var setPostTermsScript = redis.NewScript(`
+for _, term in ipairs(redis.call('HKEYS', KEYS[1])) do
+	if redis.call('HINCRBY', KEYS[2], term, -1) <= 0 then
+		redis.call('HDEL', KEYS[2], term)
+	end
+end
+redis.call('DEL', KEYS[1])
+if #ARGV > 1 then
+	for i = 2, #ARGV, 2 do
+		redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+		redis.call('HINCRBY', KEYS[2], ARGV[i], 1)
+	end
+	redis.call('SADD', KEYS[3], ARGV[1])
+else
+	redis.call('SREM', KEYS[3], ARGV[1])
+end
+return 1
+`)
+
+// resetPostTermsScript 删除已记录文章的词频、文档频率和文章集合
+// KEYS: 文档频率, 文章集合；ARGV: 词频键的前缀
+var resetPostTermsScript = redis.NewScript(`
+for _, id in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	redis.call('DEL', ARGV[1] .. id)
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+return 1
+`)
+
+func (c *redisCache) SetPostTerms(ctx context.Context, postID uint, terms map[string]float64) error {
+	args := make([]interface{}, 0, 1+2*len(terms))
+	args = append(args, postID)
+	for term, tf := range terms {
+		args = append(args, term, tf)
+	}
+	keys := []string{relatedTermsKey(postID), relatedDocFreqKey, relatedDocsKey}
+	return setPostTermsScript.Run(ctx, c.rdb, keys, args...).Err()
+}
+
+func (c *redisCache) DeletePostTerms(ctx context.Context, postID uint) error {
+	return c.SetPostTerms(ctx, postID, nil)
+}
+
+func (c *redisCache) ResetPostTerms(ctx context.Context) error {
+	keys := []string{relatedDocFreqKey, relatedDocsKey}
+	return resetPostTermsScript.Run(ctx, c.rdb, keys, "related:terms:").Err()
+}
+
+func (c *redisCache) GetPostTerms(ctx context.Context, postIDs []uint) (map[uint]map[string]float64, error) {
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(postIDs))
+	for i, id := range postIDs {
+		cmds[i] = pipe.HGetAll(ctx, relatedTermsKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	result := make(map[uint]map[string]float64, len(postIDs))
+	for i, cmd := range cmds {
+		values := cmd.Val()
+		if len(values) == 0 {
+			continue
+		}
+		terms := make(map[string]float64, len(values))
+		for term, v := range values {
+			if tf, err := strconv.ParseFloat(v, 64); err == nil {
+				terms[term] = tf
+			}
+		}
+		result[postIDs[i]] = terms
+	}
+	return result, nil
+}
+
+func (c *redisCache) DocFreqs(ctx context.Context, terms []string) (map[string]int64, int64, error) {
+	total, err := c.rdb.SCard(ctx, relatedDocsKey).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	freqs := make(map[string]int64, len(terms))
+	if len(terms) == 0 {
+		return freqs, total, nil
+	}
+	values, err := c.rdb.HMGet(ctx, relatedDocFreqKey, terms...).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, v := range values {
+		if str, ok := v.(string); ok {
+			if n, err := strconv.ParseInt(str, 10, 64); err == nil && n > 0 {
+				freqs[terms[i]] = n
+			}
+		}
+	}
+	return freqs, total, nil
+}
+
+func (c *redisCache) SetRelated(ctx context.Context, postID uint, scores map[uint]float64) error {
+	key := relatedPostsKey(postID)
+	pipe := c.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	for id, score := range scores {
+		pipe.ZAdd(ctx, key, &redis.Z{Score: score, Member: id})
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *redisCache) AddRelated(ctx context.Context, postID, relatedID uint, score float64, limit int) error {
+	key := relatedPostsKey(postID)
+	pipe := c.rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, &redis.Z{Score: score, Member: relatedID})
+	// 按分数从低到高删除，只留下最高的 limit 个
+	pipe.ZRemRangeByRank(ctx, key, 0, int64(-limit-1))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *redisCache) GetRelated(ctx context.Context, postID uint, limit int) ([]uint, error) {
+	members, err := c.rdb.ZRevRange(ctx, relatedPostsKey(postID), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		if id, err := strconv.ParseUint(m, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
+
+func (c *redisCache) DeleteRelated(ctx context.Context, postID uint) error {
+	return c.rdb.Del(ctx, relatedPostsKey(postID)).Err()
+}
+
+// 文章计数写缓冲
+// post:{id}:counters 为待写入的增量（HASH，字段为计数列名），post:{id}:counters:flushing 为已取出、正在写入的增量，
+// counters:dirty 为有增量的文章；重启后从这两处继续写入，写入失败的增量留在 flushing 中下次重试
+const counterDirtyKey = "counters:dirty"
+
+func counterPendingKey(postID uint) string {
+	return fmt.Sprintf("post:%d:counters", postID)
+}
+
+func counterFlushingKey(postID uint) string {
+	return fmt.Sprintf("post:%d:counters:flushing", postID)
+}
+
+// claimCountersScript 把待写入的增量并入写入中，返回写入中的全部增量
+var claimCountersScript = redis.NewScript(`
+local pending = redis.call('HGETALL', KEYS[1])
+for i = 1, #pending, 2 do
+	redis.call('HINCRBY', KEYS[2], pending[i], pending[i + 1])
+end
+redis.call('DEL', KEYS[1])
+return redis.call('HGETALL', KEYS[2])
+`)
+
+// ackCountersScript 删除各文章写入中的增量，没有新增量的移出待写入集合
+// KEYS[1] 为待写入集合，之后每篇文章依次为待写入和写入中的增量，ARGV 为文章ID
+var ackCountersScript = redis.NewScript(`
+for i = 1, #ARGV do
+	redis.call('DEL', KEYS[i * 2 + 1])
+	if redis.call('EXISTS', KEYS[i * 2]) == 0 then
+		redis.call('SREM', KEYS[1], ARGV[i])
+	end
+end
+return 1
+`)
+
+func (c *redisCache) IncrCounter(ctx context.Context, postID uint, field string, delta int64) error {
+	pipe := c.rdb.TxPipeline()
+	pipe.HIncrBy(ctx, counterPendingKey(postID), field, delta)
+	pipe.SAdd(ctx, counterDirtyKey, postID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *redisCache) PendingCounters(ctx context.Context, postID uint) (map[string]int64, error) {
+	pipe := c.rdb.Pipeline()
+	pending := pipe.HGetAll(ctx, counterPendingKey(postID))
+	flushing := pipe.HGetAll(ctx, counterFlushingKey(postID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	counters := make(map[string]int64)
+	for _, values := range []map[string]string{pending.Val(), flushing.Val()} {
+		for field, v := range values {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				counters[field] += n
+			}
+		}
+	}
+	return counters, nil
+}
+
+func (c *redisCache) DirtyCounterPosts(ctx context.Context) ([]uint, error) {
+	members, err := c.rdb.SMembers(ctx, counterDirtyKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		if id, err := strconv.ParseUint(m, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
+
+func (c *redisCache) ClaimCounters(ctx context.Context, postID uint) (map[string]int64, error) {
+	values, err := claimCountersScript.Run(ctx, c.rdb,
+		[]string{counterPendingKey(postID), counterFlushingKey(postID)}).StringSlice()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	counters := make(map[string]int64, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		if n, err := strconv.ParseInt(values[i+1], 10, 64); err == nil {
+			counters[values[i]] = n
+		}
+	}
+	return counters, nil
+}
+
+func (c *redisCache) AckCounters(ctx context.Context, postIDs []uint) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, 1+len(postIDs)*2)
+	args := make([]interface{}, 0, len(postIDs))
+	keys = append(keys, counterDirtyKey)
+	for _, id := range postIDs {
+		keys = append(keys, counterPendingKey(id), counterFlushingKey(id))
+		args = append(args, id)
+	}
+	return ackCountersScript.Run(ctx, c.rdb, keys, args...).Err()
+}
+
+// 热门文章
+// trending:{views|acts}:{h|d}:{index} 为各分桶的热度（ZSET），trending:rank:{window} 为合并后的排行
+const (
+	trendingHourlyTTL = 48 * time.Hour
+	trendingDailyTTL  = 32 * 24 * time.Hour
+)
+
+func trendingBucketKey(view, hourly bool, index int64) string {
+	kind, unit := "acts", "d"
+	if view {
+		kind = "views"
+	}
+	if hourly {
+		unit = "h"
+	}
+	return fmt.Sprintf("trending:%s:%s:%d", kind, unit, index)
+}
+
+func trendingRankKey(window string) string {
+	return fmt.Sprintf("trending:rank:%s", window)
+}
+
+func (c *redisCache) IncrTrending(ctx context.Context, postID uint, at time.Time, score float64, view bool) error {
+	hourKey := trendingBucketKey(view, true, at.Unix()/3600)
+	dayKey := trendingBucketKey(view, false, at.Unix()/86400)
+	member := strconv.FormatUint(uint64(postID), 10)
+
+	pipe := c.rdb.Pipeline()
+	pipe.ZIncrBy(ctx, hourKey, score, member)
+	pipe.Expire(ctx, hourKey, trendingHourlyTTL)
+	pipe.ZIncrBy(ctx, dayKey, score, member)
+	pipe.Expire(ctx, dayKey, trendingDailyTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *redisCache) ResetTrendingInteractions(ctx context.Context, hourly bool, from, to int64, scores map[int64]map[uint]float64) error {
+	ttl := trendingDailyTTL
+	if hourly {
+		ttl = trendingHourlyTTL
+	}
+
+	pipe := c.rdb.TxPipeline()
+	for index := from; index <= to; index++ {
+		key := trendingBucketKey(false, hourly, index)
+		pipe.Del(ctx, key)
+		if len(scores[index]) == 0 {
+			continue
+		}
+		members := make([]*redis.Z, 0, len(scores[index]))
+		for postID, score := range scores[index] {
+			members = append(members, &redis.Z{Score: score, Member: postID})
+		}
+		pipe.ZAdd(ctx, key, members...)
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *redisCache) RangeTrending(ctx context.Context, window string, buckets []TrendingBucket, ttl time.Duration, limit int) ([]uint, error) {
+	key := trendingRankKey(window)
+	exists, err := c.rdb.Exists(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if exists == 0 && len(buckets) > 0 {
+		store := &redis.ZStore{Aggregate: "SUM"}
+		for _, b := range buckets {
+			store.Keys = append(store.Keys, trendingBucketKey(true, b.Hourly, b.Index), trendingBucketKey(false, b.Hourly, b.Index))
+			store.Weights = append(store.Weights, b.Weight, b.Weight)
+		}
+		pipe := c.rdb.TxPipeline()
+		pipe.ZUnionStore(ctx, key, store)
+		pipe.Expire(ctx, key, ttl)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	members, err := c.rdb.ZRevRange(ctx, key, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		if id, err := strconv.ParseUint(m, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,425 @@
+package dao
+
+import (
+	searchpkg "blog/pkg/search"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// 字段权重，标题命中的相关度最高
+var postFieldBoosts = map[string]float64{
+	"title":       3.0,
+	"summary":     1.5,
+	"author_name": 1.0,
+	"content":     1.0,
+}
+
+// 需要返回高亮片段的字段
+var postHighlightFields = []string{"title", "summary", "content"}
+
+// highlightTag 高亮片段中命中词的标签
+const highlightTag = "<mark>"
+
+// rebuildBatchSize 重建索引时每批读取的帖子数
+const rebuildBatchSize = 200
+
+// 分面统计字段及返回的最大项数
+const (
+	FacetCategory = "category_id"
+	FacetTag      = "tag_ids"
+	facetSize     = 20
+)
+
+// 排序方式
+const (
+	SortRelevance = "relevance"
+	SortDate      = "date"
+)
+
+// PostDocument 帖子索引文档
+type PostDocument struct {
+	ID         uint      `json:"-"`
+	Title      string    `json:"title"`
+	Summary    string    `json:"summary"`
+	Content    string    `json:"content"`
+	AuthorName string    `json:"author_name"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// 过滤字段（关键词字段以字符串存储）
+	AuthorID   string   `json:"author_id"`
+	CategoryID string   `json:"category_id,omitempty"`
+	TagIDs     []string `json:"tag_ids,omitempty"`
+}
+
+// PostSearchQuery 帖子搜索条件（关键词为空时匹配全部帖子）
+type PostSearchQuery struct {
+	Keyword string
+
+	// 过滤条件，零值表示不过滤；多个标签需同时命中
+	CategoryID uint
+	TagIDs     []uint
+	AuthorID   uint
+	From       time.Time
+	To         time.Time
+
+	// 只在这些帖子中搜索
+	IDs []uint
+
+	Sort      string
+	Facets    bool
+	Highlight bool
+	Offset    int
+	Size      int
+}
+
+// PostHit 搜索命中
+type PostHit struct {
+	ID         uint
+	Score      float64
+	Highlights map[string][]string
+}
+
+// FacetTerm 分面统计项
+type FacetTerm struct {
+	ID    uint
+	Count int
+}
+
+// PostSearchResult 搜索结果
+type PostSearchResult struct {
+	Total  uint64
+	Hits   []*PostHit
+	Facets map[string][]*FacetTerm
+}
+
+// 接口
+type PostIndex interface {
+	IndexPost(ctx context.Context, doc *PostDocument) error
+	DeletePost(ctx context.Context, postID uint) error
+	SearchPosts(ctx context.Context, q *PostSearchQuery) (*PostSearchResult, error)
+
+	// Rebuild 在临时目录中重建索引后整体替换，重建期间搜索不受影响，期间的写入在替换前重放到新索引
+	Rebuild(ctx context.Context, load func(offset, limit int) ([]*PostDocument, error)) (int, error)
+	Close() error
+}
+
+// ErrRebuilding 已有重建在进行
+var ErrRebuilding = errors.New("索引正在重建")
+
+type postIndex struct {
+	path string
+	idx  bleve.Index
+	lock sync.RWMutex
+
+	// 重建期间的写入（文档为nil表示删除），替换前重放到新索引上
+	pending     map[string]*PostDocument
+	pendingLock sync.Mutex
+}
+
+func NewPostIndex(path string, idx bleve.Index) PostIndex {
+	return &postIndex{path: path, idx: idx}
+}
+
+func docID(postID uint) string {
+	return strconv.FormatUint(uint64(postID), 10)
+}
+
+// KeywordID 过滤字段中的ID以字符串形式索引
+func KeywordID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return docID(id)
+}
+
+func (p *postIndex) IndexPost(ctx context.Context, doc *PostDocument) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	p.record(docID(doc.ID), doc)
+	return p.idx.Index(docID(doc.ID), doc)
+}
+
+func (p *postIndex) DeletePost(ctx context.Context, postID uint) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	p.record(docID(postID), nil)
+	return p.idx.Delete(docID(postID))
+}
+
+// record 重建期间记录写入，同一帖子只保留最后一次
+func (p *postIndex) record(id string, doc *PostDocument) {
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+	if p.pending != nil {
+		p.pending[id] = doc
+	}
+}
+
+// buildPostQuery 按字段权重组合关键词查询，并附加过滤条件
+func buildPostQuery(q *PostSearchQuery) query.Query {
+	var keywordQuery query.Query
+	if strings.TrimSpace(q.Keyword) == "" {
+		keywordQuery = bleve.NewMatchAllQuery()
+	} else {
+		fieldQueries := make([]query.Query, 0, len(postFieldBoosts))
+		for field, boost := range postFieldBoosts {
+			mq := bleve.NewMatchQuery(q.Keyword)
+			mq.SetField(field)
+			mq.SetBoost(boost)
+			fieldQueries = append(fieldQueries, mq)
+		}
+		keywordQuery = bleve.NewDisjunctionQuery(fieldQueries...)
+	}
+
+	filters := []query.Query{keywordQuery}
+	termFilter := func(field string, id uint) {
+		tq := bleve.NewTermQuery(docID(id))
+		tq.SetField(field)
+		filters = append(filters, tq)
+	}
+	if q.CategoryID > 0 {
+		termFilter(FacetCategory, q.CategoryID)
+	}
+	for _, tagID := range q.TagIDs {
+		termFilter(FacetTag, tagID)
+	}
+	if q.AuthorID > 0 {
+		termFilter("author_id", q.AuthorID)
+	}
+	if !q.From.IsZero() || !q.To.IsZero() {
+		inclusive := true
+		dq := bleve.NewDateRangeInclusiveQuery(q.From, q.To, &inclusive, &inclusive)
+		dq.SetField("created_at")
+		filters = append(filters, dq)
+	}
+	if len(q.IDs) > 0 {
+		ids := make([]string, 0, len(q.IDs))
+		for _, id := range q.IDs {
+			ids = append(ids, docID(id))
+		}
+		filters = append(filters, bleve.NewDocIDQuery(ids))
+	}
+
+	if len(filters) == 1 {
+		return keywordQuery
+	}
+	return bleve.NewConjunctionQuery(filters...)
+}
+
+// matchedFragments 只保留包含命中词的高亮片段
+func matchedFragments(fragments map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(fragments))
+	for field, frags := range fragments {
+		for _, frag := range frags {
+			if strings.Contains(frag, highlightTag) {
+				result[field] = append(result[field], frag)
+			}
+		}
+	}
+	return result
+}
+
+func (p *postIndex) SearchPosts(ctx context.Context, q *PostSearchQuery) (*PostSearchResult, error) {
+	req := bleve.NewSearchRequestOptions(buildPostQuery(q), q.Size, q.Offset, false)
+	if q.Sort == SortDate || strings.TrimSpace(q.Keyword) == "" {
+		req.SortBy([]string{"-created_at", "-_id"})
+	}
+	if q.Highlight {
+		req.Highlight = bleve.NewHighlightWithStyle(html.Name)
+		req.Highlight.Fields = postHighlightFields
+	}
+	if q.Facets {
+		req.AddFacet(FacetCategory, bleve.NewFacetRequest(FacetCategory, facetSize))
+		req.AddFacet(FacetTag, bleve.NewFacetRequest(FacetTag, facetSize))
+	}
+
+	p.lock.RLock()
+	res, err := p.idx.SearchInContext(ctx, req)
+	p.lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PostSearchResult{
+		Total:  res.Total,
+		Hits:   make([]*PostHit, 0, len(res.Hits)),
+		Facets: make(map[string][]*FacetTerm, len(res.Facets)),
+	}
+	for _, hit := range res.Hits {
+		id, err := strconv.ParseUint(hit.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		result.Hits = append(result.Hits, &PostHit{
+			ID:         uint(id),
+			Score:      hit.Score,
+			Highlights: matchedFragments(hit.Fragments),
+		})
+	}
+	for name, facet := range res.Facets {
+		terms := make([]*FacetTerm, 0)
+		if facet.Terms != nil {
+			for _, t := range facet.Terms.Terms() {
+				id, err := strconv.ParseUint(t.Term, 10, 64)
+				if err != nil || id == 0 {
+					continue
+				}
+				terms = append(terms, &FacetTerm{ID: uint(id), Count: t.Count})
+			}
+		}
+		result.Facets[name] = terms
+	}
+	return result, nil
+}
+
+func (p *postIndex) Rebuild(ctx context.Context, load func(offset, limit int) ([]*PostDocument, error)) (int, error) {
+	p.pendingLock.Lock()
+	if p.pending != nil {
+		p.pendingLock.Unlock()
+		return 0, ErrRebuilding
+	}
+	p.pending = make(map[string]*PostDocument)
+	p.pendingLock.Unlock()
+
+	defer func() {
+		p.pendingLock.Lock()
+		p.pending = nil
+		p.pendingLock.Unlock()
+	}()
+
+	tmpPath := p.path + ".rebuild"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return 0, fmt.Errorf("清理临时索引失败: %w", err)
+	}
+
+	newIdx, err := bleve.New(tmpPath, searchpkg.NewPostIndexMapping())
+	if err != nil {
+		return 0, fmt.Errorf("创建临时索引失败: %w", err)
+	}
+
+	total := 0
+	for offset := 0; ; offset += rebuildBatchSize {
+		if err := ctx.Err(); err != nil {
+			newIdx.Close()
+			return total, err
+		}
+
+		docs, err := load(offset, rebuildBatchSize)
+		if err != nil {
+			newIdx.Close()
+			return total, fmt.Errorf("读取帖子失败: %w", err)
+		}
+
+		batch := newIdx.NewBatch()
+		for _, doc := range docs {
+			if err := batch.Index(docID(doc.ID), doc); err != nil {
+				newIdx.Close()
+				return total, fmt.Errorf("索引帖子失败: %w", err)
+			}
+		}
+		if err := newIdx.Batch(batch); err != nil {
+			newIdx.Close()
+			return total, fmt.Errorf("写入索引失败: %w", err)
+		}
+		total += len(docs)
+
+		if len(docs) < rebuildBatchSize {
+			break
+		}
+	}
+
+	// 替换前停止写入，把重建期间的新增、修改和删除重放到新索引上（覆盖读取时的旧数据）
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if err := p.replay(newIdx); err != nil {
+		newIdx.Close()
+		return total, fmt.Errorf("重放重建期间的写入失败: %w", err)
+	}
+	if err := newIdx.Close(); err != nil {
+		return total, fmt.Errorf("关闭临时索引失败: %w", err)
+	}
+
+	if err := p.swap(tmpPath); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// replay 重放重建期间记录的写入（调用方持有写锁）
+func (p *postIndex) replay(idx bleve.Index) error {
+	p.pendingLock.Lock()
+	defer p.pendingLock.Unlock()
+
+	batch := idx.NewBatch()
+	for id, doc := range p.pending {
+		if doc == nil {
+			batch.Delete(id)
+			continue
+		}
+		if err := batch.Index(id, doc); err != nil {
+			return err
+		}
+	}
+	return idx.Batch(batch)
+}
+
+// swap 通过重命名用 tmpPath 替换旧索引，任何一步失败都恢复并重新打开旧索引（调用方持有写锁）
+func (p *postIndex) swap(tmpPath string) error {
+	oldPath := p.path + ".old"
+	if err := os.RemoveAll(oldPath); err != nil {
+		return fmt.Errorf("清理旧索引备份失败: %w", err)
+	}
+
+	if err := p.idx.Close(); err != nil {
+		return fmt.Errorf("关闭旧索引失败: %w", err)
+	}
+	// restore 恢复旧索引目录并重新打开
+	restore := func(cause error) error {
+		if _, err := os.Stat(oldPath); err == nil {
+			os.RemoveAll(p.path)
+			if err := os.Rename(oldPath, p.path); err != nil {
+				return fmt.Errorf("%w；恢复旧索引失败: %v", cause, err)
+			}
+		}
+		idx, err := bleve.Open(p.path)
+		if err != nil {
+			return fmt.Errorf("%w；重新打开旧索引失败: %v", cause, err)
+		}
+		p.idx = idx
+		return cause
+	}
+
+	if err := os.Rename(p.path, oldPath); err != nil {
+		return restore(fmt.Errorf("备份旧索引失败: %w", err))
+	}
+	if err := os.Rename(tmpPath, p.path); err != nil {
+		return restore(fmt.Errorf("替换索引失败: %w", err))
+	}
+	idx, err := bleve.Open(p.path)
+	if err != nil {
+		return restore(fmt.Errorf("打开新索引失败: %w", err))
+	}
+	p.idx = idx
+
+	if err := os.RemoveAll(oldPath); err != nil {
+		fmt.Printf("删除旧索引备份失败: %v\n", err)
+	}
+	return nil
+}
+
+func (p *postIndex) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.idx.Close()
+}
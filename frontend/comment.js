@@ -123,7 +123,7 @@ function renderComment(comment, depth = 0) {
                 </div>
             </div>
             <div class="comment-content mt-2">
-                ${escapeHtml(comment.content || '')}
+                ${comment.rendered || escapeHtml(comment.content || '')}
             </div>
             <div id="replyForm${comment.id}" class="mt-3 d-none">
                 <form onsubmit="handleReply(${comment.id}, event)">
@@ -126,8 +126,13 @@ func (h *CommentHandler) ListCommentsByPost(c *gin.Context) {
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+	sortBy, err := commentservice.ParseCommentSort(c.Query("sort"), commentservice.CommentSortNew)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
 
-	comments, total, err := h.commentService.ListCommentsByPost(c.Request.Context(), uint(postID), page, size)
+	comments, total, err := h.commentService.ListCommentsByPost(c.Request.Context(), uint(postID), sortBy, page, size)
 	if err != nil {
 		status := http.StatusInternalServerError
 		errorMsg := "获取评论失败"
@@ -293,7 +298,7 @@ func (h *CommentHandler) CreateReply(c *gin.Context) {
 
 // ListReplies 获取评论回复列表
 func (h *CommentHandler) ListReplies(c *gin.Context) {
-	commentIDStr := c.Param("comment_id")
+	commentIDStr := c.Param("id")
 	commentID, err := strconv.ParseUint(commentIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的评论ID"})
@@ -302,8 +307,13 @@ func (h *CommentHandler) ListReplies(c *gin.Context) {
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	sortBy, err := commentservice.ParseCommentSort(c.Query("sort"), commentservice.CommentSortOld)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
 
-	replies, total, err := h.commentService.ListReplies(c.Request.Context(), uint(commentID), page, size)
+	replies, total, err := h.commentService.ListReplies(c.Request.Context(), uint(commentID), sortBy, page, size)
 	if err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "评论不存在"})
 		return
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	feedpkg "blog/pkg/feed"
+	feedservice "blog/service/FeedService"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// FeedHandler 订阅源处理器
+type FeedHandler struct {
+	feedService feedservice.FeedService
+}
+
+// NewFeedHandler 创建订阅源处理器
+func NewFeedHandler(feedService feedservice.FeedService) *FeedHandler {
+	return &FeedHandler{feedService: feedService}
+}
+
+// SiteFeed 全站订阅
+func (h *FeedHandler) SiteFeed(format string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.serve(c, format, func(ctx context.Context) (*feedpkg.Feed, error) {
+			return h.feedService.SiteFeed(ctx, format)
+		})
+	}
+}
+
+// CategoryFeed 分类订阅
+func (h *FeedHandler) CategoryFeed(format string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.serve(c, format, func(ctx context.Context) (*feedpkg.Feed, error) {
+			return h.feedService.CategoryFeed(ctx, format, c.Param("slug"))
+		})
+	}
+}
+
+// TagFeed 标签订阅
+func (h *FeedHandler) TagFeed(format string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.serve(c, format, func(ctx context.Context) (*feedpkg.Feed, error) {
+			return h.feedService.TagFeed(ctx, format, c.Param("slug"))
+		})
+	}
+}
+
+// AuthorFeed 作者订阅
+func (h *FeedHandler) AuthorFeed(format string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.serve(c, format, func(ctx context.Context) (*feedpkg.Feed, error) {
+			return h.feedService.AuthorFeed(ctx, format, c.Param("username"))
+		})
+	}
+}
+
+// serve 输出订阅源，支持 ETag / Last-Modified 条件请求
+func (h *FeedHandler) serve(c *gin.Context, format string, load func(ctx context.Context) (*feedpkg.Feed, error)) {
+	feed, err := load(c.Request.Context())
+	if err != nil {
+		switch err {
+		case feedservice.ErrFeedNotFound:
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case feedservice.ErrRateLimited:
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: err.Error()})
+		default:
+			slog.Error("生成订阅源失败", "path", c.Request.URL.Path, "error", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "生成订阅源失败"})
+		}
+		return
+	}
+
+	body, err := feedpkg.Render(format, feed)
+	if err != nil {
+		slog.Error("渲染订阅源失败", "path", c.Request.URL.Path, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "生成订阅源失败"})
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum(body))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=300")
+	if !feed.Updated.IsZero() {
+		c.Header("Last-Modified", feed.Updated.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, feed.Updated) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, feedpkg.ContentTypes[format], body)
+}
+
+// notModified 检查条件请求（If-None-Match 优先于 If-Modified-Since）
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		if err == nil && !lastModified.Truncate(time.Second).After(t) {
+			return true
+		}
+	}
+	return false
+}
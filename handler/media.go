@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"blog/model"
+	mediaservice "blog/service/MediaService"
+	"blog/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// MediaHandler 媒体库处理器
+type MediaHandler struct {
+	mediaService mediaservice.MediaService
+}
+
+// NewMediaHandler 创建媒体库处理器
+func NewMediaHandler(mediaService mediaservice.MediaService) *MediaHandler {
+	return &MediaHandler{mediaService: mediaService}
+}
+
+// ListMediaResponse 媒体库列表响应结构体
+type ListMediaResponse struct {
+	Media []*model.Media `json:"media"`
+	Total int64          `json:"total"`
+	Page  int            `json:"page"`
+	Size  int            `json:"size"`
+}
+
+// mediaErrorStatus 媒体库相关错误对应的HTTP状态码
+func mediaErrorStatus(err error) int {
+	switch err {
+	case mediaservice.ErrMediaNotFound:
+		return http.StatusNotFound
+	case mediaservice.ErrEmptyFile, mediaservice.ErrUnsupportedFile:
+		return http.StatusBadRequest
+	case mediaservice.ErrFileTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case mediaservice.ErrMediaInUse, mediaservice.ErrQuotaExceeded:
+		return http.StatusConflict
+	case mediaservice.ErrRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// mediaError 返回错误响应，服务器错误只记录日志
+func mediaError(c *gin.Context, err error, msg string, args ...any) {
+	status := mediaErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		slog.Error(msg, append(args, "error", err)...)
+		c.JSON(status, ErrorResponse{Error: msg})
+		return
+	}
+	c.JSON(status, ErrorResponse{Error: err.Error()})
+}
+
+// Upload 上传图片或附件到媒体库
+func (h *MediaHandler) Upload(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "请选择要上传的文件"})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "读取文件失败"})
+		return
+	}
+	defer src.Close()
+
+	ctx := context.WithValue(c.Request.Context(), "user_id", userID)
+	media, err := h.mediaService.Upload(ctx, userID, file.Filename, src, file.Size)
+	if err != nil {
+		mediaError(c, err, "上传文件失败", "user_id", userID)
+		return
+	}
+
+	c.JSON(http.StatusCreated, media)
+}
+
+// ListMedia 获取当前用户的媒体库
+func (h *MediaHandler) ListMedia(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+
+	media, total, err := h.mediaService.ListMedia(c.Request.Context(), userID, page, size)
+	if err != nil {
+		mediaError(c, err, "获取媒体库失败", "user_id", userID)
+		return
+	}
+
+	c.JSON(http.StatusOK, ListMediaResponse{
+		Media: media,
+		Total: total,
+		Page:  page,
+		Size:  size,
+	})
+}
+
+// GetMedia 获取文件详情（含引用该文件的文章）
+func (h *MediaHandler) GetMedia(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的文件ID"})
+		return
+	}
+
+	media, err := h.mediaService.GetMedia(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		mediaError(c, err, "获取文件失败", "media_id", id)
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// DeleteMedia 删除文件，被文章引用时返回409
+func (h *MediaHandler) DeleteMedia(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的文件ID"})
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), "user_id", userID)
+	if err := h.mediaService.DeleteMedia(ctx, userID, uint(id)); err != nil {
+		mediaError(c, err, "删除文件失败", "media_id", id)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "文件已删除"})
+}
+
+// GetUsage 获取当前用户的存储用量和配额
+func (h *MediaHandler) GetUsage(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	usage, err := h.mediaService.GetUsage(c.Request.Context(), userID)
+	if err != nil {
+		mediaError(c, err, "获取存储用量失败", "user_id", userID)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"blog/model"
+	notificationservice "blog/service/NotificationService"
+	"blog/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// NotificationHandler 通知处理器
+type NotificationHandler struct {
+	notificationService notificationservice.NotificationService
+}
+
+// NewNotificationHandler 创建通知处理器
+func NewNotificationHandler(notificationService notificationservice.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// ListNotificationsResponse 通知列表响应结构体
+type ListNotificationsResponse struct {
+	Notifications []*model.Notification `json:"notifications"`
+	Total         int64                 `json:"total"`
+	Unread        int64                 `json:"unread"`
+	Page          int                   `json:"page"`
+	Size          int                   `json:"size"`
+}
+
+// UpdatePreferencesRequest 更新通知偏好请求结构体
+type UpdatePreferencesRequest struct {
+	Preferences map[model.NotificationType]bool `json:"preferences" binding:"required"`
+}
+
+// ListNotifications 获取当前用户的通知列表
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	unreadOnly := c.Query("unread") == "true"
+
+	ctx := context.WithValue(c.Request.Context(), "user_id", userID)
+	notifications, total, err := h.notificationService.ListNotifications(ctx, userID, unreadOnly, page, size)
+	if err != nil {
+		if err == notificationservice.ErrRateLimited {
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: err.Error()})
+			return
+		}
+		slog.Error("获取通知列表失败", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "获取通知列表失败", Details: err.Error()})
+		return
+	}
+
+	unread, err := h.notificationService.CountUnread(ctx, userID)
+	if err != nil {
+		slog.Error("获取未读通知数失败", "user_id", userID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, ListNotificationsResponse{
+		Notifications: notifications,
+		Total:         total,
+		Unread:        unread,
+		Page:          page,
+		Size:          size,
+	})
+}
+
+// UnreadCount 获取未读通知数
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	unread, err := h.notificationService.CountUnread(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "获取未读通知数失败", Details: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": unread})
+}
+
+// MarkRead 标记单条通知为已读
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的通知ID"})
+		return
+	}
+
+	if err := h.notificationService.MarkRead(c.Request.Context(), userID, uint(id)); err != nil {
+		if err == notificationservice.ErrNotificationNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "标记已读失败", Details: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已标记为已读"})
+}
+
+// MarkAllRead 标记全部通知为已读
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	count, err := h.notificationService.MarkAllRead(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "标记已读失败", Details: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已全部标记为已读", "count": count})
+}
+
+// GetPreferences 获取通知偏好
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	prefs, err := h.notificationService.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "获取通知偏好失败", Details: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+// UpdatePreferences 更新通知偏好
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "请求参数错误", Details: err.Error()})
+		return
+	}
+
+	prefs, err := h.notificationService.UpdatePreferences(c.Request.Context(), userID, req.Preferences)
+	if err != nil {
+		if err == notificationservice.ErrInvalidNotificationType {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "更新通知偏好失败", Details: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pageservice "blog/service/PageService"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// PageHandler 服务端渲染页面处理器
+type PageHandler struct {
+	pageService pageservice.PageService
+}
+
+// NewPageHandler 创建页面处理器
+func NewPageHandler(pageService pageservice.PageService) *PageHandler {
+	return &PageHandler{pageService: pageService}
+}
+
+// Home 首页
+func (h *PageHandler) Home(c *gin.Context) {
+	h.serve(c, func(ctx context.Context) (*pageservice.Page, error) {
+		return h.pageService.Home(ctx, pageParam(c))
+	})
+}
+
+// Post 文章详情页
+func (h *PageHandler) Post(c *gin.Context) {
+	h.serve(c, func(ctx context.Context) (*pageservice.Page, error) {
+		return h.pageService.Post(ctx, c.Param("slug"))
+	})
+}
+
+// Category 分类页
+func (h *PageHandler) Category(c *gin.Context) {
+	h.serve(c, func(ctx context.Context) (*pageservice.Page, error) {
+		return h.pageService.Category(ctx, c.Param("slug"), pageParam(c))
+	})
+}
+
+// Tag 标签页
+func (h *PageHandler) Tag(c *gin.Context) {
+	h.serve(c, func(ctx context.Context) (*pageservice.Page, error) {
+		return h.pageService.Tag(ctx, c.Param("slug"), pageParam(c))
+	})
+}
+
+// Author 作者主页
+func (h *PageHandler) Author(c *gin.Context) {
+	h.serve(c, func(ctx context.Context) (*pageservice.Page, error) {
+		return h.pageService.Author(ctx, c.Param("username"), pageParam(c))
+	})
+}
+
+// NotFound 未匹配路由：API请求返回JSON，旧链接永久跳转，其余返回404页面
+func (h *PageHandler) NotFound(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "接口不存在"})
+		return
+	}
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		target, err := h.pageService.Redirect(c.Request.Context(), c.Request.URL.Path)
+		if err != nil {
+			slog.Error("查询旧链接跳转失败", "path", c.Request.URL.Path, "error", err)
+		} else if target != "" {
+			c.Redirect(http.StatusMovedPermanently, target)
+			return
+		}
+	}
+	h.render(c, http.StatusNotFound, h.pageService.NotFound())
+}
+
+// pageParam 列表页码，缺省为第一页
+func pageParam(c *gin.Context) int {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+func (h *PageHandler) serve(c *gin.Context, load func(ctx context.Context) (*pageservice.Page, error)) {
+	// 带上请求，用于按IP限流和识别访客
+	page, err := load(context.WithValue(c.Request.Context(), "httpRequest", c.Request))
+	if err != nil {
+		switch err {
+		case pageservice.ErrPageNotFound:
+			h.NotFound(c)
+		case pageservice.ErrRateLimited:
+			c.String(http.StatusTooManyRequests, err.Error())
+		default:
+			slog.Error("加载页面失败", "path", c.Request.URL.Path, "error", err)
+			c.String(http.StatusInternalServerError, "服务器内部错误")
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	h.render(c, http.StatusOK, page)
+}
+
+// render 先渲染到内存，模板出错时不会输出半个页面
+func (h *PageHandler) render(c *gin.Context, status int, page *pageservice.Page) {
+	body, err := h.pageService.Render(page)
+	if err != nil {
+		slog.Error("渲染页面失败", "path", c.Request.URL.Path, "template", page.Template, "error", err)
+		c.String(http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", body)
+}
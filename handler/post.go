@@ -7,6 +7,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/exp/slog"
@@ -74,7 +75,9 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.GetPost(c.Request.Context(), uint(id))
+	// 带上请求，用于按IP限流和识别访客
+	ctx := context.WithValue(c.Request.Context(), "httpRequest", c.Request)
+	post, err := h.postService.GetPost(ctx, uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
 		return
@@ -91,7 +94,8 @@ func (h *PostHandler) GetPostBySlug(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.GetPostBySlug(c.Request.Context(), slug)
+	ctx := context.WithValue(c.Request.Context(), "httpRequest", c.Request)
+	post, err := h.postService.GetPostBySlug(ctx, slug)
 	if err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
 		return
@@ -247,32 +251,103 @@ func (h *PostHandler) ListPostsByTag(c *gin.Context) {
 	})
 }
 
-// SearchPosts 搜索文章
-func (h *PostHandler) SearchPosts(c *gin.Context) {
-	keyword := c.Query("keyword")
-	if keyword == "" {
-		h.ListPosts(c)
-		return
+// SearchPostsResponse 搜索结果响应结构体
+type SearchPostsResponse struct {
+	Posts  []*model.Post             `json:"posts"`
+	Total  int64                     `json:"total"`
+	Page   int                       `json:"page"`
+	Size   int                       `json:"size"`
+	Facets *postservice.SearchFacets `json:"facets"`
+}
+
+// parseDateParam 解析日期参数（2006-01-02 或 RFC3339），endOfDay为true时日期取当天结束时间
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
 	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
 
+// SearchPosts 搜索文章（支持分类/标签/作者/日期过滤和排序）
+func (h *PostHandler) SearchPosts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	categoryID, _ := strconv.ParseUint(c.Query("category_id"), 10, 32)
+	authorID, _ := strconv.ParseUint(c.Query("author_id"), 10, 32)
+
+	from, err := parseDateParam(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的起始日期", Details: err.Error()})
+		return
+	}
+	to, err := parseDateParam(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的结束日期", Details: err.Error()})
+		return
+	}
 
-	posts, total, err := h.postService.SearchPosts(c.Request.Context(), keyword, page, size)
+	req := &postservice.SearchPostsRequest{
+		Keyword:    c.Query("keyword"),
+		CategoryID: uint(categoryID),
+		TagIDs:     parsePostIDs(c.Query("tag_ids")),
+		AuthorID:   uint(authorID),
+		From:       from,
+		To:         to,
+		Sort:       c.Query("sort"),
+		Page:       page,
+		Size:       size,
+	}
+	result, err := h.postService.SearchPosts(c.Request.Context(), req)
 	if err != nil {
-		slog.Error("搜索文章失败", "error", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "搜索文章失败"})
+		switch err {
+		case postservice.ErrInvalidSearchSort:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case postservice.ErrRateLimited:
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: err.Error()})
+		default:
+			slog.Error("搜索文章失败", "error", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "搜索文章失败"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, ListPostsResponse{
-		Posts: posts,
-		Total: total,
-		Page:  page,
-		Size:  size,
+	c.JSON(http.StatusOK, SearchPostsResponse{
+		Posts:  result.Posts,
+		Total:  result.Total,
+		Page:   req.Page,
+		Size:   req.Size,
+		Facets: result.Facets,
 	})
 }
 
+// SuggestSearch 搜索联想（帖子标题和标签的前缀匹配）
+func (h *PostHandler) SuggestSearch(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	suggestions, err := h.postService.SuggestSearch(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		if err == postservice.ErrRateLimited {
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: err.Error()})
+			return
+		}
+		slog.Error("获取搜索联想失败", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "获取搜索联想失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
 // LikePost 点赞文章
 func (h *PostHandler) LikePost(c *gin.Context) {
 	idStr := c.Param("id")
@@ -421,3 +496,29 @@ func (h *PostHandler) GetPostStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// RebuildSearchIndex 重建全文索引（管理员）
+func (h *PostHandler) RebuildSearchIndex(c *gin.Context) {
+	currentUserID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+	ctx := context.WithValue(c.Request.Context(), "user_id", currentUserID)
+
+	count, err := h.postService.RebuildSearchIndex(ctx)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case postservice.ErrNotAdmin:
+			status = http.StatusForbidden
+		case postservice.ErrUnauthorized:
+			status = http.StatusUnauthorized
+		}
+		slog.Error("重建全文索引失败", "error", err)
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "全文索引重建完成", "indexed": count})
+}
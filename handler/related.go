@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog/model"
+	relatedservice "blog/service/RelatedService"
+	"blog/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// RelatedHandler 相关文章推荐处理器
+type RelatedHandler struct {
+	relatedService relatedservice.RelatedService
+}
+
+// NewRelatedHandler 创建相关文章推荐处理器
+func NewRelatedHandler(relatedService relatedservice.RelatedService) *RelatedHandler {
+	return &RelatedHandler{relatedService: relatedService}
+}
+
+// RelatedPostsResponse 相关文章响应结构体
+type RelatedPostsResponse struct {
+	Posts []*model.Post `json:"posts"`
+}
+
+// relatedErrorStatus 相关推荐错误对应的HTTP状态码
+func relatedErrorStatus(err error) int {
+	switch err {
+	case relatedservice.ErrPostNotFound:
+		return http.StatusNotFound
+	case relatedservice.ErrNotAdmin:
+		return http.StatusForbidden
+	case relatedservice.ErrRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// relatedError 返回错误响应，服务器错误只记录日志
+func relatedError(c *gin.Context, err error, msg string, args ...any) {
+	status := relatedErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		slog.Error(msg, append(args, "error", err)...)
+		c.JSON(status, ErrorResponse{Error: msg})
+		return
+	}
+	c.JSON(status, ErrorResponse{Error: err.Error()})
+}
+
+// ListRelated 获取文章的相关推荐
+func (h *RelatedHandler) ListRelated(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的文章ID"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+
+	posts, err := h.relatedService.ListRelated(c.Request.Context(), uint(id), limit)
+	if err != nil {
+		relatedError(c, err, "获取相关文章失败", "post_id", id)
+		return
+	}
+
+	c.JSON(http.StatusOK, RelatedPostsResponse{Posts: posts})
+}
+
+// Rebuild 重新计算所有文章的相关推荐（管理员）
+func (h *RelatedHandler) Rebuild(c *gin.Context) {
+	currentUserID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	count, err := h.relatedService.Rebuild(c.Request.Context(), currentUserID)
+	if err != nil {
+		relatedError(c, err, "重建相关文章推荐失败", "user_id", currentUserID)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "相关文章推荐重建完成", "computed": count})
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"blog/model"
+	reportservice "blog/service/ReportService"
+	"blog/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// ReportHandler 举报处理器
+type ReportHandler struct {
+	reportService reportservice.ReportService
+}
+
+// NewReportHandler 创建举报处理器
+func NewReportHandler(reportService reportservice.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+
+// ListReportsResponse 举报列表响应结构体
+type ListReportsResponse struct {
+	Reports []*model.Report `json:"reports"`
+	Total   int64           `json:"total"`
+	Page    int             `json:"page"`
+	Size    int             `json:"size"`
+}
+
+// reportErrorStatus 举报相关错误对应的HTTP状态码
+func reportErrorStatus(err error) int {
+	switch err {
+	case reportservice.ErrReportNotFound, reportservice.ErrReportTargetMissing:
+		return http.StatusNotFound
+	case reportservice.ErrInvalidReportTarget, reportservice.ErrInvalidReportReason,
+		reportservice.ErrInvalidReportAction, reportservice.ErrReportDetailsLong,
+		reportservice.ErrCannotReportSelf:
+		return http.StatusBadRequest
+	case reportservice.ErrAlreadyReported, reportservice.ErrReportClosed:
+		return http.StatusConflict
+	case reportservice.ErrNotAdmin:
+		return http.StatusForbidden
+	case reportservice.ErrRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CreateReport 举报帖子、评论或用户
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	var req reportservice.CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "请求参数错误", Details: err.Error()})
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), "user_id", userID)
+	report, err := h.reportService.CreateReport(ctx, userID, &req)
+	if err != nil {
+		c.JSON(reportErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// ListReports 管理员获取举报审核队列
+func (h *ReportHandler) ListReports(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	status := model.ReportStatus(c.DefaultQuery("status", string(model.ReportStatusPending)))
+	targetType := model.ReportTarget(c.Query("target_type"))
+
+	ctx := context.WithValue(c.Request.Context(), "user_id", userID)
+	reports, total, err := h.reportService.ListReports(ctx, userID, status, targetType, page, size)
+	if err != nil {
+		c.JSON(reportErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListReportsResponse{
+		Reports: reports,
+		Total:   total,
+		Page:    page,
+		Size:    size,
+	})
+}
+
+// ResolveReport 管理员处理举报
+func (h *ReportHandler) ResolveReport(c *gin.Context) {
+	h.review(c, h.reportService.ResolveReport)
+}
+
+// DismissReport 管理员驳回举报
+func (h *ReportHandler) DismissReport(c *gin.Context) {
+	h.review(c, h.reportService.DismissReport)
+}
+
+// review 处理/驳回举报的公共逻辑
+func (h *ReportHandler) review(c *gin.Context, fn func(ctx context.Context, adminID, reportID uint, req *reportservice.ReviewReportRequest) (int64, error)) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的举报ID"})
+		return
+	}
+
+	var req reportservice.ReviewReportRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "请求参数错误", Details: err.Error()})
+			return
+		}
+	}
+
+	ctx := context.WithValue(c.Request.Context(), "user_id", userID)
+	closed, err := fn(ctx, userID, uint(id), &req)
+	if err != nil {
+		slog.Error("审核举报失败", "report_id", id, "admin_id", userID, "error", err)
+		c.JSON(reportErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "举报已处理", "closed": closed})
+}
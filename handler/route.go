@@ -4,9 +4,20 @@ import (
 	"net/http"
 	"time"
 
+	feedpkg "blog/pkg/feed"
 	categoryservice "blog/service/CategoryService"
 	commentservice "blog/service/CommentService"
+	feedservice "blog/service/FeedService"
+	mediaservice "blog/service/MediaService"
+	notificationservice "blog/service/NotificationService"
+	pageservice "blog/service/PageService"
 	postservice "blog/service/PostService"
+	relatedservice "blog/service/RelatedService"
+	reportservice "blog/service/ReportService"
+	seriesservice "blog/service/SeriesService"
+	sitemapservice "blog/service/SitemapService"
+	streamservice "blog/service/StreamService"
+	trendingservice "blog/service/TrendingService"
 	userservice "blog/service/UserService"
 	"blog/utils"
 
@@ -19,6 +30,16 @@ func SetupRouter(
 	postService postservice.PostService,
 	categoryService categoryservice.CategoryService,
 	commentService commentservice.CommentService,
+	notificationService notificationservice.NotificationService,
+	streamService streamservice.StreamService,
+	reportService reportservice.ReportService,
+	feedService feedservice.FeedService,
+	sitemapService sitemapservice.SitemapService,
+	pageService pageservice.PageService,
+	mediaService mediaservice.MediaService,
+	seriesService seriesservice.SeriesService,
+	relatedService relatedservice.RelatedService,
+	trendingService trendingservice.TrendingService,
 	lockManager *utils.LockManager,
 	rateLimiter *utils.RateLimiter,
 ) *gin.Engine {
@@ -29,8 +50,8 @@ func SetupRouter(
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
-	// 添加根路径和favicon处理
-	router.GET("/", func(c *gin.Context) {
+	// API根路径
+	router.GET("/api", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "欢迎使用博客API服务",
 			"version": "1.0.0",
@@ -68,6 +89,37 @@ func SetupRouter(
 	postHandler := NewPostHandler(postService)
 	categoryHandler := NewCategoryHandler(categoryService)
 	commentHandler := NewCommentHandler(commentService)
+	notificationHandler := NewNotificationHandler(notificationService)
+	streamHandler := NewStreamHandler(streamService)
+	reportHandler := NewReportHandler(reportService)
+	feedHandler := NewFeedHandler(feedService)
+	sitemapHandler := NewSitemapHandler(sitemapService)
+	pageHandler := NewPageHandler(pageService)
+	mediaHandler := NewMediaHandler(mediaService)
+	seriesHandler := NewSeriesHandler(seriesService)
+	relatedHandler := NewRelatedHandler(relatedService)
+	trendingHandler := NewTrendingHandler(trendingService)
+
+	// 服务端渲染页面
+	router.GET("/", pageHandler.Home)
+	router.GET("/posts/:slug", pageHandler.Post)
+	router.GET("/categories/:slug", pageHandler.Category)
+	router.GET("/tags/:slug", pageHandler.Tag)
+	router.GET("/users/:username", pageHandler.Author)
+	router.NoRoute(pageHandler.NotFound)
+
+	// 站点地图和robots.txt
+	router.GET("/sitemap.xml", sitemapHandler.Sitemap)
+	router.GET("/sitemaps/:name", sitemapHandler.SitemapPage)
+	router.GET("/robots.txt", sitemapHandler.Robots)
+
+	// 订阅源：全站及按分类/标签/作者
+	for format, path := range feedpkg.FileNames {
+		router.GET("/"+path, feedHandler.SiteFeed(format))
+		router.GET("/categories/:slug/"+path, feedHandler.CategoryFeed(format))
+		router.GET("/tags/:slug/"+path, feedHandler.TagFeed(format))
+		router.GET("/users/:username/"+path, feedHandler.AuthorFeed(format))
+	}
 
 	// 公共路由（无需认证）
 	public := router.Group("/api")
@@ -99,6 +151,7 @@ func SetupRouter(
 			postGroup.GET("", postHandler.ListPosts)
 			postGroup.GET("/slug/:slug", postHandler.GetPostBySlug)
 			postGroup.GET("/search", postHandler.SearchPosts)
+			postGroup.GET("/trending", trendingHandler.ListTrending)
 			postGroup.GET("/category/:category_id", postHandler.ListPostsByCategory)
 			postGroup.GET("/tag/:tag_id", postHandler.ListPostsByTag)
 
@@ -108,9 +161,23 @@ func SetupRouter(
 				postDetailGroup.GET("", postHandler.GetPost)
 				postDetailGroup.GET("/stats", postHandler.GetPostStats)
 				postDetailGroup.GET("/comments", commentHandler.ListCommentsByPost)
+				postDetailGroup.GET("/related", relatedHandler.ListRelated)
 			}
 		}
 
+		// 搜索联想
+		searchGroup := public.Group("/search")
+		{
+			searchGroup.GET("/suggest", postHandler.SuggestSearch)
+		}
+
+		// 系列文章
+		seriesGroup := public.Group("/series")
+		{
+			seriesGroup.GET("", seriesHandler.ListSeries)
+			seriesGroup.GET("/:slug", seriesHandler.GetSeries)
+		}
+
 		// 分类相关路由
 		categoryGroup := public.Group("/categories")
 		{
@@ -143,6 +210,9 @@ func SetupRouter(
 				commentDetailGroup.GET("/replies", commentHandler.ListReplies)
 			}
 		}
+
+		// 实时推送（SSE），使用连接凭证或 Authorization 请求头认证
+		public.GET("/stream", streamHandler.Stream)
 	}
 
 	// 需要认证的路由
@@ -158,6 +228,51 @@ func SetupRouter(
 			userAuthGroup.DELETE("/avatar", userHandler.DeleteAvatar) // 删除头像
 		}
 
+		// 媒体库（文章图片和附件）
+		mediaAuthGroup := auth.Group("/media")
+		{
+			mediaAuthGroup.POST("", mediaHandler.Upload)
+			mediaAuthGroup.GET("", mediaHandler.ListMedia)
+			mediaAuthGroup.GET("/usage", mediaHandler.GetUsage)
+			mediaAuthGroup.GET("/:id", mediaHandler.GetMedia)
+			mediaAuthGroup.DELETE("/:id", mediaHandler.DeleteMedia)
+		}
+
+		// 关注相关
+		followAuthGroup := auth.Group("/users/:username")
+		{
+			followAuthGroup.POST("/follow", userHandler.FollowUser)
+			followAuthGroup.DELETE("/follow", userHandler.UnfollowUser)
+		}
+
+		// 通知相关
+		notificationAuthGroup := auth.Group("/notifications")
+		{
+			notificationAuthGroup.GET("", notificationHandler.ListNotifications)
+			notificationAuthGroup.GET("/unread-count", notificationHandler.UnreadCount)
+			notificationAuthGroup.PUT("/read-all", notificationHandler.MarkAllRead)
+			notificationAuthGroup.PUT("/:id/read", notificationHandler.MarkRead)
+			notificationAuthGroup.GET("/preferences", notificationHandler.GetPreferences)
+			notificationAuthGroup.PUT("/preferences", notificationHandler.UpdatePreferences)
+		}
+
+		// 实时推送（SSE）连接凭证
+		auth.POST("/stream/ticket", streamHandler.Ticket)
+
+		// 举报
+		auth.POST("/reports", reportHandler.CreateReport)
+
+		// 管理员审核（权限在Service中校验）
+		adminAuthGroup := auth.Group("/admin")
+		{
+			adminAuthGroup.GET("/reports", reportHandler.ListReports)
+			adminAuthGroup.PUT("/reports/:id/resolve", reportHandler.ResolveReport)
+			adminAuthGroup.PUT("/reports/:id/dismiss", reportHandler.DismissReport)
+			adminAuthGroup.POST("/search/rebuild", postHandler.RebuildSearchIndex)
+			adminAuthGroup.POST("/related/rebuild", relatedHandler.Rebuild)
+			adminAuthGroup.POST("/trending/rebuild", trendingHandler.Rebuild)
+		}
+
 		// 文章相关
 		postAuthGroup := auth.Group("/posts")
 		{
@@ -186,6 +301,15 @@ func SetupRouter(
 			}
 		}
 
+		// 系列文章（只有作者可以修改）
+		seriesAuthGroup := auth.Group("/series")
+		{
+			seriesAuthGroup.POST("", seriesHandler.CreateSeries)
+			seriesAuthGroup.PUT("/:slug", seriesHandler.UpdateSeries)
+			seriesAuthGroup.PUT("/:slug/posts", seriesHandler.SetSeriesPosts)
+			seriesAuthGroup.DELETE("/:slug", seriesHandler.DeleteSeries)
+		}
+
 		// 评论相关
 		commentAuthGroup := auth.Group("/comments")
 		{
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog/model"
+	seriesservice "blog/service/SeriesService"
+	"blog/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// SeriesHandler 系列文章处理器
+type SeriesHandler struct {
+	seriesService seriesservice.SeriesService
+}
+
+// NewSeriesHandler 创建系列文章处理器
+func NewSeriesHandler(seriesService seriesservice.SeriesService) *SeriesHandler {
+	return &SeriesHandler{seriesService: seriesService}
+}
+
+// ListSeriesResponse 系列列表响应结构体
+type ListSeriesResponse struct {
+	Series []*model.Series `json:"series"`
+	Total  int64           `json:"total"`
+	Page   int             `json:"page"`
+	Size   int             `json:"size"`
+}
+
+// SetSeriesPostsRequest 设置系列文章请求
+type SetSeriesPostsRequest struct {
+	PostIDs []uint `json:"post_ids"` // 按顺序排列，为空表示清空
+}
+
+// seriesErrorStatus 系列相关错误对应的HTTP状态码
+func seriesErrorStatus(err error) int {
+	switch err {
+	case seriesservice.ErrSeriesNotFound:
+		return http.StatusNotFound
+	case seriesservice.ErrNotSeriesAuthor:
+		return http.StatusForbidden
+	case seriesservice.ErrSeriesSlugExists, seriesservice.ErrPostInOtherSeries:
+		return http.StatusConflict
+	case seriesservice.ErrInvalidSeriesTitle, seriesservice.ErrInvalidSeriesPost, seriesservice.ErrTooManySeriesPosts:
+		return http.StatusBadRequest
+	case seriesservice.ErrRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// seriesError 返回错误响应，服务器错误只记录日志
+func seriesError(c *gin.Context, err error, msg string, args ...any) {
+	status := seriesErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		slog.Error(msg, append(args, "error", err)...)
+		c.JSON(status, ErrorResponse{Error: msg})
+		return
+	}
+	c.JSON(status, ErrorResponse{Error: err.Error()})
+}
+
+// CreateSeries 创建系列
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var req seriesservice.CreateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "请求参数错误", Details: err.Error()})
+		return
+	}
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	series, err := h.seriesService.CreateSeries(c.Request.Context(), userID, &req)
+	if err != nil {
+		seriesError(c, err, "创建系列失败", "user_id", userID)
+		return
+	}
+
+	c.JSON(http.StatusCreated, series)
+}
+
+// GetSeries 获取系列及按顺序排列的文章
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	// 公开接口不经过认证中间件，此时只返回公开文章
+	viewerID, _ := utils.GetUserIDFromGin(c)
+
+	series, err := h.seriesService.GetSeriesBySlug(c.Request.Context(), viewerID, c.Param("slug"))
+	if err != nil {
+		seriesError(c, err, "获取系列失败", "slug", c.Param("slug"))
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// ListSeries 系列列表，可按作者过滤
+func (h *SeriesHandler) ListSeries(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	authorID, _ := strconv.ParseUint(c.DefaultQuery("user_id", "0"), 10, 32)
+
+	series, total, err := h.seriesService.ListSeries(c.Request.Context(), uint(authorID), page, size)
+	if err != nil {
+		seriesError(c, err, "获取系列列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, ListSeriesResponse{
+		Series: series,
+		Total:  total,
+		Page:   page,
+		Size:   size,
+	})
+}
+
+// UpdateSeries 修改系列信息
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	var req seriesservice.UpdateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "请求参数错误", Details: err.Error()})
+		return
+	}
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	series, err := h.seriesService.UpdateSeries(c.Request.Context(), userID, c.Param("slug"), &req)
+	if err != nil {
+		seriesError(c, err, "更新系列失败", "slug", c.Param("slug"))
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// SetSeriesPosts 设置系列中的文章及顺序
+func (h *SeriesHandler) SetSeriesPosts(c *gin.Context) {
+	var req SetSeriesPostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "请求参数错误", Details: err.Error()})
+		return
+	}
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	series, err := h.seriesService.SetSeriesPosts(c.Request.Context(), userID, c.Param("slug"), req.PostIDs)
+	if err != nil {
+		seriesError(c, err, "设置系列文章失败", "slug", c.Param("slug"))
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// DeleteSeries 删除系列（文章保留）
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	if err := h.seriesService.DeleteSeries(c.Request.Context(), userID, c.Param("slug")); err != nil {
+		seriesError(c, err, "删除系列失败", "slug", c.Param("slug"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "系列已删除"})
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+
+	sitemapservice "blog/service/SitemapService"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// SitemapHandler 站点地图处理器
+type SitemapHandler struct {
+	sitemapService sitemapservice.SitemapService
+}
+
+// NewSitemapHandler 创建站点地图处理器
+func NewSitemapHandler(sitemapService sitemapservice.SitemapService) *SitemapHandler {
+	return &SitemapHandler{sitemapService: sitemapService}
+}
+
+// Sitemap 站点地图入口
+func (h *SitemapHandler) Sitemap(c *gin.Context) {
+	body, err := h.sitemapService.Sitemap(c.Request.Context())
+	if err != nil {
+		slog.Error("生成站点地图失败", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "生成站点地图失败"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+// SitemapPage 分页站点地图
+func (h *SitemapHandler) SitemapPage(c *gin.Context) {
+	body, err := h.sitemapService.SitemapPage(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		if err == sitemapservice.ErrSitemapNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		slog.Error("生成站点地图失败", "name", c.Param("name"), "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "生成站点地图失败"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+// Robots robots.txt
+func (h *SitemapHandler) Robots(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", h.sitemapService.Robots())
+}
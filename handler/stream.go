@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	streamservice "blog/service/StreamService"
+	"blog/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// streamHeartbeat SSE心跳间隔，防止代理断开空闲连接
+const streamHeartbeat = 25 * time.Second
+
+// StreamHandler 实时推送处理器
+type StreamHandler struct {
+	streamService streamservice.StreamService
+}
+
+// NewStreamHandler 创建实时推送处理器
+func NewStreamHandler(streamService streamservice.StreamService) *StreamHandler {
+	return &StreamHandler{streamService: streamService}
+}
+
+// parsePostIDs 解析逗号分隔的帖子ID列表
+func parsePostIDs(value string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil || id == 0 {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// Ticket 签发一次性的连接凭证，浏览器用 /api/stream?ticket= 建立连接，避免 token 出现在URL和访问日志中
+func (h *StreamHandler) Ticket(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	ticket, err := h.streamService.IssueTicket(c.Request.Context(), userID)
+	if err != nil {
+		slog.Error("签发连接凭证失败", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "签发连接凭证失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket})
+}
+
+// streamUserID 连接凭证或 Authorization 请求头对应的用户
+func (h *StreamHandler) streamUserID(c *gin.Context) (uint, error) {
+	if ticket := c.Query("ticket"); ticket != "" {
+		return h.streamService.RedeemTicket(c.Request.Context(), ticket)
+	}
+
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return 0, streamservice.ErrInvalidTicket
+	}
+	claims, err := utils.ParseToken(parts[1])
+	if err != nil {
+		return 0, streamservice.ErrInvalidTicket
+	}
+	return claims.UserID, nil
+}
+
+// Stream SSE实时推送：关注帖子的新评论、点赞/收藏数，以及当前用户的通知
+func (h *StreamHandler) Stream(c *gin.Context) {
+	userID, err := h.streamUserID(c)
+	if err != nil {
+		if err != streamservice.ErrInvalidTicket {
+			slog.Error("验证连接凭证失败", "error", err)
+		}
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	postIDs := parsePostIDs(c.Query("posts"))
+	sub, err := h.streamService.Subscribe(c.Request.Context(), userID, postIDs)
+	if err != nil {
+		slog.Error("注册实时推送失败", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "建立实时推送失败"})
+		return
+	}
+	defer h.streamService.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("ready", gin.H{"user_id": userID, "posts": sub.Watched})
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-sub.Events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return true
+		case <-ticker.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
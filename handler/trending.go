@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog/model"
+	trendingservice "blog/service/TrendingService"
+	"blog/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// TrendingHandler 热门文章处理器
+type TrendingHandler struct {
+	trendingService trendingservice.TrendingService
+}
+
+// NewTrendingHandler 创建热门文章处理器
+func NewTrendingHandler(trendingService trendingservice.TrendingService) *TrendingHandler {
+	return &TrendingHandler{trendingService: trendingService}
+}
+
+// TrendingPostsResponse 热门文章响应结构体
+type TrendingPostsResponse struct {
+	Window string        `json:"window"`
+	Posts  []*model.Post `json:"posts"`
+}
+
+// trendingErrorStatus 热门文章错误对应的HTTP状态码
+func trendingErrorStatus(err error) int {
+	switch err {
+	case trendingservice.ErrInvalidWindow:
+		return http.StatusBadRequest
+	case trendingservice.ErrNotAdmin:
+		return http.StatusForbidden
+	case trendingservice.ErrRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// trendingError 返回错误响应，服务器错误只记录日志
+func trendingError(c *gin.Context, err error, msg string, args ...any) {
+	status := trendingErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		slog.Error(msg, append(args, "error", err)...)
+		c.JSON(status, ErrorResponse{Error: msg})
+		return
+	}
+	c.JSON(status, ErrorResponse{Error: err.Error()})
+}
+
+// ListTrending 热门文章，window 为 24h、7d 或 30d
+func (h *TrendingHandler) ListTrending(c *gin.Context) {
+	window := c.DefaultQuery("window", trendingservice.WindowDay)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	posts, err := h.trendingService.ListTrending(c.Request.Context(), window, limit)
+	if err != nil {
+		trendingError(c, err, "获取热门文章失败", "window", window)
+		return
+	}
+
+	c.JSON(http.StatusOK, TrendingPostsResponse{Window: window, Posts: posts})
+}
+
+// Rebuild 从数据库重建热门文章统计（管理员）
+func (h *TrendingHandler) Rebuild(c *gin.Context) {
+	currentUserID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	count, err := h.trendingService.Rebuild(c.Request.Context(), currentUserID)
+	if err != nil {
+		trendingError(c, err, "重建热门文章失败", "user_id", currentUserID)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "热门文章统计重建完成", "posts": count})
+}
@@ -4,7 +4,7 @@ import (
 	userservice "blog/service/UserService"
 	"context"
 	"io"
-	"strings"
+	"strconv"
 
 	"blog/utils"
 	"net/http"
@@ -240,8 +240,8 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
 		status := http.StatusBadRequest
 		errorMsg := err.Error()
 
-		// 如果是文件格式错误，返回400
-		if strings.Contains(err.Error(), "不支持的图片格式") {
+		// 如果是文件格式或尺寸错误，返回400
+		if err == userservice.ErrUnsupportedImage || err == userservice.ErrImageTooLarge {
 			status = http.StatusBadRequest
 		} else {
 			// 其他错误返回500
@@ -285,7 +285,7 @@ func (h *UserHandler) DeleteAvatar(c *gin.Context) {
 	})
 }
 
-// GetAvatar 获取用户头像URL
+// GetAvatar 获取用户头像URL，可用 size 参数指定尺寸（像素）
 func (h *UserHandler) GetAvatar(c *gin.Context) {
 	username := c.Param("username")
 	if username == "" {
@@ -293,6 +293,16 @@ func (h *UserHandler) GetAvatar(c *gin.Context) {
 		return
 	}
 
+	size := 0
+	if s := c.Query("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的头像尺寸"})
+			return
+		}
+		size = n
+	}
+
 	// 获取用户公开资料以获取头像URL
 	resp, err := h.userService.GetUserPublicProfile(c.Request.Context(), username)
 	if err != nil {
@@ -300,8 +310,71 @@ func (h *UserHandler) GetAvatar(c *gin.Context) {
 		return
 	}
 
+	// 没有上传头像时返回生成的默认头像
+	avatarURL := h.userService.AvatarURLForSize(resp.AvatarURL, size)
+	if avatarURL == "" {
+		avatarURL, err = h.userService.DefaultAvatarURL(c.Request.Context(), resp.ID, resp.Name)
+		if err != nil {
+			slog.Error("生成默认头像失败", "username", username, "error", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "获取头像失败"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"avatar_url": resp.AvatarURL,
+		"avatar_url": avatarURL,
 		"username":   username,
 	})
 }
+
+// FollowUser 关注用户
+func (h *UserHandler) FollowUser(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	username := c.Param("username")
+	ctx := context.WithValue(c.Request.Context(), "user_id", userID)
+	if err := h.userService.FollowUser(ctx, userID, username); err != nil {
+		c.JSON(followErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "关注成功"})
+}
+
+// UnfollowUser 取消关注用户
+func (h *UserHandler) UnfollowUser(c *gin.Context) {
+	userID, err := utils.GetUserIDFromGin(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "用户未认证"})
+		return
+	}
+
+	username := c.Param("username")
+	ctx := context.WithValue(c.Request.Context(), "user_id", userID)
+	if err := h.userService.UnfollowUser(ctx, userID, username); err != nil {
+		c.JSON(followErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已取消关注"})
+}
+
+// followErrorStatus 关注相关错误对应的HTTP状态码
+func followErrorStatus(err error) int {
+	switch err {
+	case userservice.ErrUserNotFound:
+		return http.StatusNotFound
+	case userservice.ErrCannotFollowSelf, userservice.ErrNotFollowing:
+		return http.StatusBadRequest
+	case userservice.ErrAlreadyFollowing:
+		return http.StatusConflict
+	case userservice.ErrRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -4,16 +4,43 @@ import (
 	"blog/config"
 	mysqldao "blog/dao/mysql"
 	redisdao "blog/dao/redis"
+	searchdao "blog/dao/search"
 	"blog/handler"
+	blobpkg "blog/pkg/blob"
 	mysqlpkg "blog/pkg/mysql"
 	redispkg "blog/pkg/redis"
+	searchpkg "blog/pkg/search"
+	themepkg "blog/pkg/theme"
+	BackupService "blog/service/BackupService"
 	CategoryService "blog/service/CategoryService"
 	CommentService "blog/service/CommentService"
+	CounterService "blog/service/CounterService"
+	FeedService "blog/service/FeedService"
+	ImportService "blog/service/ImportService"
+	MediaService "blog/service/MediaService"
+	MentionService "blog/service/MentionService"
+	NotificationService "blog/service/NotificationService"
+	PageService "blog/service/PageService"
 	PostService "blog/service/PostService"
+	RelatedService "blog/service/RelatedService"
+	ReportService "blog/service/ReportService"
+	SeriesService "blog/service/SeriesService"
+	SitemapService "blog/service/SitemapService"
+	StaticService "blog/service/StaticService"
+	StreamService "blog/service/StreamService"
+	TrendingService "blog/service/TrendingService"
 	UserService "blog/service/UserService"
 	"blog/utils"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -23,6 +50,11 @@ func main() {
 		log.Fatal("加载配置失败:", err)
 	}
 
+	utils.DefaultSlugMode = utils.SlugMode(cfg.Slug.Mode)
+	if err := utils.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("加载可信代理配置失败:", err)
+	}
+
 	// 2. 初始化数据库
 	db, err := mysqlpkg.InitMysql_or_sqlite(&cfg.Database)
 	if err != nil {
@@ -32,6 +64,14 @@ func main() {
 	// 3. 初始化Redis
 	redisClient := redispkg.NewRedisClient(&cfg.Redis)
 
+	// 初始化全文索引
+	searchIndex, err := searchpkg.InitSearchIndex(&cfg.Search)
+	if err != nil {
+		log.Fatal("初始化全文索引失败:", err)
+	}
+	postIndex := searchdao.NewPostIndex(cfg.Search.IndexPath, searchIndex)
+	defer postIndex.Close()
+
 	// 4. 初始化锁管理器和限流器
 	lockManager := utils.NewLockManager(redisClient.Client)
 	rateLimiter := utils.NewRateLimiter(redisClient.Client, "blog:rate_limit:")
@@ -45,13 +85,219 @@ func main() {
 	likeSQL := mysqldao.NewLikeSQL(db.DB)
 	starSQL := mysqldao.NewStarSQL(db.DB)
 	commentLikeSQL := mysqldao.NewCommentLikeSQL(db.DB)
+	mentionSQL := mysqldao.NewMentionSQL(db.DB)
+	notificationSQL := mysqldao.NewNotificationSQL(db.DB)
+	followSQL := mysqldao.NewFollowSQL(db.DB)
+	reportSQL := mysqldao.NewReportSQL(db.DB)
+	mediaSQL := mysqldao.NewMediaSQL(db.DB)
+	seriesSQL := mysqldao.NewSeriesSQL(db.DB)
 
 	// 6. 初始化Redis Cache
 	redisCache := redisdao.NewRedisCache(redisClient.Client)
 
+	// 上传文件存储（本地目录或 S3 兼容的对象存储）
+	uploads, err := blobpkg.New(&cfg.Storage)
+	if err != nil {
+		log.Fatal("初始化上传文件存储失败:", err)
+	}
+
+	// 命令行：重建全文索引（需先停止服务，索引文件不能被多个进程同时打开）
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-index" {
+		count, err := PostService.RebuildPostIndex(context.Background(), db.DB, postIndex, redisCache)
+		if err != nil {
+			log.Fatal("重建全文索引失败:", err)
+		}
+		log.Printf("全文索引重建完成，共索引%d篇帖子", count)
+		return
+	}
+
+	// 命令行：从 Hexo/Jekyll/Hugo 的 Markdown 目录导入文章，默认只输出导入计划，加 -commit 才写入
+	if len(os.Args) > 1 && os.Args[1] == "import-markdown" {
+		flags := flag.NewFlagSet("import-markdown", flag.ExitOnError)
+		dir := flags.String("dir", "", "Markdown文件目录（如 Hexo 的 source/_posts）")
+		assets := flags.String("assets", "", "以 / 开头的图片链接相对的资源目录（如 Hexo 的 source），默认同 -dir")
+		author := flags.String("author", "", "导入文章的作者用户名")
+		category := flags.String("category", "未分类", "没有分类的文章归入的分类")
+		onConflict := flags.String("on-conflict", ImportService.ConflictSkip, "slug已存在时：skip 跳过，rename 追加序号")
+		commit := flags.Bool("commit", false, "写入数据库（默认只试运行并输出报告）")
+		flags.Parse(os.Args[2:])
+		if *dir == "" || *author == "" {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		importService := ImportService.NewImportService(db.DB, ImportService.Options{Uploads: uploads})
+		report, err := importService.ImportMarkdown(context.Background(), &ImportService.MarkdownImportRequest{
+			ImportRequest: ImportService.ImportRequest{
+				Author:          *author,
+				DefaultCategory: *category,
+				OnConflict:      *onConflict,
+				DryRun:          !*commit,
+			},
+			Dir:       *dir,
+			AssetsDir: *assets,
+		})
+		if err != nil {
+			log.Fatal("导入失败:", err)
+		}
+		report.Print(os.Stdout)
+
+		// 导入的文章直接写入数据库，需要重建全文索引
+		if *commit && report.Created > 0 {
+			count, err := PostService.RebuildPostIndex(context.Background(), db.DB, postIndex, redisCache)
+			if err != nil {
+				log.Fatal("重建全文索引失败:", err)
+			}
+			log.Printf("全文索引重建完成，共索引%d篇帖子", count)
+		}
+		return
+	}
+
+	// 命令行：从 WordPress 导出的 WXR 文件导入，默认只输出导入计划，加 -commit 才写入；重复导入只补充新内容
+	if len(os.Args) > 1 && os.Args[1] == "import-wordpress" {
+		flags := flag.NewFlagSet("import-wordpress", flag.ExitOnError)
+		file := flags.String("file", "", "WordPress 导出的 WXR 文件")
+		author := flags.String("author", "", "作者不在导出文件中时使用的用户名")
+		category := flags.String("category", "未分类", "没有分类的文章归入的分类")
+		onConflict := flags.String("on-conflict", ImportService.ConflictSkip, "slug已存在时：skip 跳过，rename 追加序号")
+		redirects := flags.String("redirects", "", "把旧链接与新地址的对应写入此文件（每行：旧路径<TAB>新路径）")
+		commit := flags.Bool("commit", false, "写入数据库（默认只试运行并输出报告）")
+		flags.Parse(os.Args[2:])
+		if *file == "" {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		importService := ImportService.NewImportService(db.DB, ImportService.Options{Uploads: uploads})
+		report, err := importService.ImportWordPress(context.Background(), &ImportService.WordPressImportRequest{
+			ImportRequest: ImportService.ImportRequest{
+				Author:          *author,
+				DefaultCategory: *category,
+				OnConflict:      *onConflict,
+				DryRun:          !*commit,
+			},
+			File: *file,
+		})
+		if err != nil {
+			log.Fatal("导入失败:", err)
+		}
+		report.Print(os.Stdout)
+
+		if *redirects != "" {
+			out, err := os.Create(*redirects)
+			if err != nil {
+				log.Fatal("写入旧链接对应失败:", err)
+			}
+			if err := report.WriteRedirects(out); err != nil {
+				log.Fatal("写入旧链接对应失败:", err)
+			}
+			if err := out.Close(); err != nil {
+				log.Fatal("写入旧链接对应失败:", err)
+			}
+		}
+
+		if *commit && report.Created > 0 {
+			count, err := PostService.RebuildPostIndex(context.Background(), db.DB, postIndex, redisCache)
+			if err != nil {
+				log.Fatal("重建全文索引失败:", err)
+			}
+			log.Printf("全文索引重建完成，共索引%d篇帖子", count)
+		}
+		return
+	}
+
+	// 命令行：导出整个博客（数据库和上传的文件）为 ZIP 归档
+	if len(os.Args) > 1 && os.Args[1] == "export-site" {
+		flags := flag.NewFlagSet("export-site", flag.ExitOnError)
+		out := flags.String("out", "blog-backup.zip", "归档文件")
+		withoutPasswords := flags.Bool("no-passwords", false, "不导出用户的密码哈希（恢复后需要重置密码）")
+		flags.Parse(os.Args[2:])
+
+		// 先写临时文件，导出失败时不会覆盖已有的归档
+		tmp := *out + ".tmp"
+		f, err := os.Create(tmp)
+		if err != nil {
+			log.Fatal("创建归档文件失败:", err)
+		}
+		backupService := BackupService.NewBackupService(db.DB, BackupService.Options{Uploads: uploads})
+		manifest, err := backupService.Export(context.Background(), f, BackupService.ExportOptions{WithoutPasswords: *withoutPasswords})
+		if err == nil {
+			err = f.Close()
+		} else {
+			f.Close()
+		}
+		if err == nil {
+			err = os.Rename(tmp, *out)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			log.Fatal("导出失败:", err)
+		}
+		log.Printf("导出完成: %s（%d个用户，%d篇帖子，%d条评论，%d个文件）", *out,
+			manifest.Tables["users"], manifest.Tables["posts"], manifest.Tables["comments"], manifest.Files)
+		return
+	}
+
+	// 命令行：把 export-site 的归档恢复到空数据库（MySQL 或 SQLite 均可）
+	if len(os.Args) > 1 && os.Args[1] == "restore-site" {
+		flags := flag.NewFlagSet("restore-site", flag.ExitOnError)
+		in := flags.String("in", "", "归档文件")
+		flags.Parse(os.Args[2:])
+		if *in == "" {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatal("打开归档文件失败:", err)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			log.Fatal("打开归档文件失败:", err)
+		}
+		backupService := BackupService.NewBackupService(db.DB, BackupService.Options{Uploads: uploads})
+		manifest, err := backupService.Restore(context.Background(), f, info.Size())
+		if err != nil {
+			log.Fatal("恢复失败:", err)
+		}
+		log.Printf("恢复完成: 归档导出于%s（%s），%d个用户，%d篇帖子，%d条评论，%d个文件",
+			manifest.CreatedAt.Format("2006-01-02 15:04:05"), manifest.Driver,
+			manifest.Tables["users"], manifest.Tables["posts"], manifest.Tables["comments"], manifest.Files)
+		if !manifest.Passwords {
+			log.Println("归档不含密码哈希，用户需要重置密码后才能登录")
+		}
+
+		count, err := PostService.RebuildPostIndex(context.Background(), db.DB, postIndex, redisCache)
+		if err != nil {
+			log.Fatal("重建全文索引失败:", err)
+		}
+		log.Printf("全文索引重建完成，共索引%d篇帖子", count)
+		return
+	}
+
+	// 预加载分词词典
+	go utils.WarmUpSegmenter()
+
 	// 7. 初始化Service
-	userService := UserService.NewUserService(userSQL, lockManager, rateLimiter)
+	streamService := StreamService.NewStreamService(redisClient.Client, userSQL, db.DB)
+	notificationService := NotificationService.NewNotificationService(notificationSQL, db.DB, lockManager, rateLimiter, streamService)
+	userService := UserService.NewUserService(userSQL, followSQL, notificationService, lockManager, rateLimiter, uploads, UserService.AvatarOptions{
+		Sizes:        cfg.Avatar.Sizes,
+		DefaultStyle: cfg.Avatar.DefaultStyle,
+	})
 	categoryService := CategoryService.NewCategoryService(categorySQL, lockManager, rateLimiter)
+	mentionService := MentionService.NewMentionService(mentionSQL, userSQL, notificationService, lockManager)
+	mediaService := MediaService.NewMediaService(mediaSQL, postSQL, userSQL, db.DB, uploads, lockManager, rateLimiter, MediaService.Options{
+		MaxFileSize: cfg.Media.MaxFileSizeMB << 20,
+		Quota:       cfg.Media.QuotaMB << 20,
+		ImageWidths: cfg.Media.ImageWidths,
+	})
+	seriesService := SeriesService.NewSeriesService(seriesSQL, postSQL, userSQL, db.DB, lockManager, rateLimiter)
+	relatedService := RelatedService.NewRelatedService(postSQL, userSQL, db.DB, redisCache, lockManager, rateLimiter)
+	trendingService := TrendingService.NewTrendingService(userSQL, db.DB, redisCache, lockManager, rateLimiter)
+	counterService := CounterService.NewCounterService(db.DB, redisCache, lockManager)
 
 	commentService := CommentService.NewCommentService(
 		commentSQL,
@@ -62,6 +308,11 @@ func main() {
 		db.DB,
 		lockManager,
 		rateLimiter,
+		mentionService,
+		notificationService,
+		streamService,
+		trendingService,
+		counterService,
 	)
 
 	// 创建PostService
@@ -78,40 +329,230 @@ func main() {
 		redisCache,
 		redisCache,
 		redisCache,
+		redisCache,
+		lockManager,
+		rateLimiter,
+		mentionService,
+		mediaService,
+		seriesService,
+		relatedService,
+		trendingService,
+		counterService,
+		notificationService,
+		streamService,
+		postIndex,
+	)
+
+	// 创建ReportService
+	reportService := ReportService.NewReportService(
+		reportSQL,
+		postSQL,
+		commentSQL,
+		userSQL,
+		postService,
+		commentService,
+		userService,
+		db.DB,
 		lockManager,
 		rateLimiter,
+		cfg.Moderation.AutoHideThreshold,
+	)
+
+	// 创建FeedService
+	feedService := FeedService.NewFeedService(
+		categorySQL,
+		tagSQL,
+		userSQL,
+		db.DB,
+		rateLimiter,
+		FeedService.Options{
+			Title:       cfg.Site.Title,
+			Description: cfg.Site.Description,
+			BaseURL:     cfg.Site.URL,
+			Language:    cfg.Site.Language,
+			Size:        cfg.Feed.Size,
+			FullContent: cfg.Feed.FullContent,
+		},
 	)
 
+	// 监听Redis频道，把其他实例发布的实时事件推送给本实例的SSE连接
+	go func() {
+		if err := streamService.Run(context.Background()); err != nil {
+			log.Printf("实时推送服务退出: %v", err)
+		}
+	}()
+
+	// 定时从数据库重建热门文章的互动统计
+	go func() {
+		if err := trendingService.Run(context.Background(), cfg.Trending.RebuildInterval); err != nil {
+			log.Printf("热门文章统计服务退出: %v", err)
+		}
+	}()
+
 	// 8. 设置路由
+	// 创建SitemapService
+	sitemapService := SitemapService.NewSitemapService(db.DB, SitemapService.Options{
+		BaseURL:        cfg.Site.URL,
+		CacheTTL:       cfg.Sitemap.CacheTTL,
+		RobotsDisallow: cfg.Sitemap.RobotsDisallow,
+		RobotsExtra:    cfg.Sitemap.RobotsExtra,
+	})
+
+	// 创建PageService（服务端渲染页面）
+	themeDir := filepath.Join(cfg.Theme.Dir, cfg.Theme.Name)
+	theme, err := themepkg.Load(themeDir)
+	if err != nil {
+		log.Fatal("加载主题失败:", err)
+	}
+	pageService := PageService.NewPageService(
+		categorySQL,
+		tagSQL,
+		userSQL,
+		postService,
+		db.DB,
+		rateLimiter,
+		theme,
+		PageService.Options{
+			Title:       cfg.Site.Title,
+			Description: cfg.Site.Description,
+			BaseURL:     cfg.Site.URL,
+			Language:    cfg.Site.Language,
+			PageSize:    cfg.Theme.PageSize,
+		},
+	)
+
+	// 命令行：导出静态站点，例如 `blog export-static -out ./public`
+	if len(os.Args) > 1 && os.Args[1] == "export-static" {
+		flags := flag.NewFlagSet("export-static", flag.ExitOnError)
+		out := flags.String("out", "./public", "导出目录")
+		full := flags.Bool("full", false, "忽略上次导出记录，全部重新生成")
+		flags.Parse(os.Args[2:])
+
+		// 静态站点的分页地址为 /page/2/ 形式
+		staticPageService := PageService.NewPageService(
+			categorySQL,
+			tagSQL,
+			userSQL,
+			postService,
+			db.DB,
+			rateLimiter,
+			theme,
+			PageService.Options{
+				Title:        cfg.Site.Title,
+				Description:  cfg.Site.Description,
+				BaseURL:      cfg.Site.URL,
+				Language:     cfg.Site.Language,
+				PageSize:     cfg.Theme.PageSize,
+				StaticPaging: true,
+			},
+		)
+		// 对象存储中的文件直接从存储地址访问，不需要复制到静态站点
+		var staticUploads blobpkg.Store
+		if _, ok := uploads.(*blobpkg.LocalStore); ok {
+			staticUploads = uploads
+		}
+		staticService := StaticService.NewStaticService(
+			postService,
+			staticPageService,
+			feedService,
+			sitemapService,
+			StaticService.Options{
+				ThemeDir: themeDir,
+				Uploads:  staticUploads,
+			},
+		)
+
+		result, err := staticService.Export(context.Background(), *out, *full)
+		if err != nil {
+			log.Fatal("导出静态站点失败:", err)
+		}
+		log.Printf("静态站点已导出到%s：共%d篇文章，重新生成%d篇，写入%d个文件，删除%d个过期文件",
+			*out, result.Posts, result.Rendered, result.Written, result.Removed)
+		return
+	}
+
 	router := handler.SetupRouter(
 		userService,
 		postService,
 		categoryService,
 		commentService,
+		notificationService,
+		streamService,
+		reportService,
+		feedService,
+		sitemapService,
+		pageService,
+		mediaService,
+		seriesService,
+		relatedService,
+		trendingService,
 		lockManager,
 		rateLimiter,
 	)
 
+	// 与 utils.GetClientIP 使用相同的可信代理
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("设置可信代理失败:", err)
+	}
+
 	// 9. 添加静态文件服务
 	// 如果存在frontend文件夹，则提供静态文件服务
 	router.Static("/frontend", "./frontend")
 
-	// 添加头像上传目录的静态文件服务
-	router.Static("/uploads", "./uploads")
+	// 主题静态资源
+	router.Static("/theme", filepath.Join(themeDir, "static"))
 
-	// 创建上传目录（如果不存在）
-	createUploadDirs()
+	// 本地存储的上传文件由站点提供，对象存储的文件直接从存储地址访问
+	if local, ok := uploads.(*blobpkg.LocalStore); ok {
+		router.Static(cfg.Storage.Local.URL, local.Dir())
+
+		// 创建上传目录（如果不存在）
+		createUploadDirs(local.Dir())
+	}
 
 	// 10. 启动服务器
-	router.Run(":8080")
+	// 点赞/收藏/评论/浏览数先累加在Redis中，定时批量写入数据库
+	counterCtx, stopCounter := context.WithCancel(context.Background())
+	counterDone := make(chan struct{})
+	go func() {
+		defer close(counterDone)
+		if err := counterService.Run(counterCtx, cfg.Counter.FlushInterval); err != nil {
+			log.Printf("文章计数写入服务退出: %v", err)
+		}
+	}()
+
+	server := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("启动服务器失败:", err)
+		}
+	}()
+
+	// 收到退出信号后先停止接收请求，再把还在Redis中的计数写入数据库
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("正在关闭服务器...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// 实时推送等长连接不会自己结束，超时后直接关闭
+		log.Printf("等待请求结束超时: %v", err)
+		server.Close()
+	}
+
+	stopCounter()
+	<-counterDone
+	log.Println("服务器已关闭")
 }
 
 // 创建上传目录
-func createUploadDirs() {
+func createUploadDirs(uploadsDir string) {
 	// 创建头像上传目录
 	dirs := []string{
-		"./uploads",
-		"./uploads/avatars",
+		uploadsDir,
+		filepath.Join(uploadsDir, "avatars"),
 	}
 
 	for _, dir := range dirs {
@@ -119,26 +560,4 @@ func createUploadDirs() {
 			log.Printf("创建目录失败: %s, error: %v", dir, err)
 		}
 	}
-
-	// 创建默认头像文件（如果不存在）
-	defaultAvatarPath := "./uploads/default-avatar.png"
-	if _, err := os.Stat(defaultAvatarPath); os.IsNotExist(err) {
-		createDefaultAvatar(defaultAvatarPath)
-	}
-}
-
-// 创建默认头像
-func createDefaultAvatar(path string) {
-	// 这里可以生成一个简单的默认头像
-	// 为了简单起见，我们创建一个空的PNG文件占位
-	file, err := os.Create(path)
-	if err != nil {
-		log.Printf("创建默认头像失败: %v", err)
-		return
-	}
-	defer file.Close()
-
-	// 可以在这里添加生成默认头像的逻辑
-	// 现在只是创建一个空文件
-	log.Printf("默认头像已创建: %s", path)
 }
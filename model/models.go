@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -38,6 +40,9 @@ type User struct {
 	Comments []Comment `json:"comments,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// LockedPassword 导入或恢复的用户没有可用密码时的占位（不是有效的bcrypt哈希，永远无法登录），需要重置密码后使用
+const LockedPassword = "!"
+
 type Post struct {
 	// 帖子基础模型
 	ID      uint   `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -54,6 +59,10 @@ type Post struct {
 	AuthorName string `json:"author_name" gorm:"type:varchar(100)"`
 	Author     *User  `json:"author,omitempty" gorm:"foreignKey:UserID"`
 
+	// 封面图（媒体库中的图片）
+	CoverID *uint  `json:"cover_id" gorm:"index"`
+	Cover   *Media `json:"cover,omitempty" gorm:"foreignKey:CoverID"`
+
 	// 分类
 	CategoryID uint      `json:"category_id" gorm:"index"`
 	Category   *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
@@ -66,13 +75,23 @@ type Post struct {
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 
 	// 作者与读者互动
-	Clicktimes     uint `json:"clicktimes" gorm:"default:0"`
+	Clicktimes     uint `json:"clicktimes" gorm:"default:0"` // 去重浏览量（每位访客每天计一次）
+	PageViews      uint `json:"page_views" gorm:"default:0"` // 原始浏览量（每次打开都计）
 	Liketimes      uint `json:"liketimes" gorm:"default:0"`
 	Staredtimes    uint `json:"staredtimes" gorm:"default:0"`
 	CommentNumbers uint `json:"comment_numbers" gorm:"default:0"`
 
 	// 可见性
 	Visibility Visibility `json:"visibility" gorm:"type:varchar(20);default:'public';index"`
+	Hidden     bool       `json:"hidden" gorm:"default:false;index"` // 被举报隐藏，等待管理员审核
+	HiddenBy   string     `json:"-" gorm:"type:varchar(20)"`         // 隐藏来源（HiddenByReports/HiddenByAdmin）
+
+	// 搜索结果（不入库）
+	Score      float64             `json:"score,omitempty" gorm:"-"`
+	Highlights map[string][]string `json:"highlights,omitempty" gorm:"-"`
+
+	// 所属系列及上一篇/下一篇（不入库）
+	Series *SeriesNav `json:"series,omitempty" gorm:"-"`
 
 	// 关联关系
 	StarredBy []*User   `json:"starred_by,omitempty" gorm:"many2many:user_star_posts;foreignKey:ID;joinForeignKey:PostID;joinReferences:UserID"`
@@ -83,9 +102,11 @@ type Post struct {
 type Comment struct {
 	ID       uint   `json:"id" gorm:"primaryKey;autoIncrement"`
 	Content  string `json:"content" gorm:"type:text;not null"`
+	Rendered string `json:"rendered,omitempty" gorm:"type:text"` // 渲染后的HTML（含@提及链接）
 	ParentID *uint  `json:"parent_id" gorm:"index"`
 	Level    uint   `json:"level" gorm:"default:0;index"`
 	Status   string `json:"status" gorm:"type:varchar(20);default:'published';index"`
+	HiddenBy string `json:"-" gorm:"type:varchar(20)"` // 隐藏来源（HiddenByReports/HiddenByAdmin）
 	// 关联
 	UserID uint `json:"user_id" gorm:"index;not null"`
 	PostID uint `json:"post_id" gorm:"index;not null"`
@@ -104,6 +125,17 @@ type Comment struct {
 	LikeCount uint `json:"like_count" gorm:"default:0"`
 }
 
+const (
+	CommentStatusPublished = "published"
+	CommentStatusHidden    = "hidden" // 被举报隐藏，等待管理员审核
+)
+
+// 帖子和评论的隐藏来源：驳回举报时只恢复被自动隐藏的内容
+const (
+	HiddenByReports = "reports" // 举报数达到阈值自动隐藏
+	HiddenByAdmin   = "admin"   // 管理员处理举报时隐藏
+)
+
 type UserStatus string
 
 const (
@@ -183,6 +215,274 @@ type PostTag struct {
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
 
+type MentionTarget string
+
+const (
+	MentionTargetPost    MentionTarget = "post"
+	MentionTargetComment MentionTarget = "comment"
+)
+
+// Mention @提及记录
+type Mention struct {
+	ID         uint          `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_mention_target"` // 被提及的用户
+	ActorID    uint          `json:"actor_id" gorm:"not null;index"`                         // 发起提及的用户
+	TargetType MentionTarget `json:"target_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_mention_target"`
+	TargetID   uint          `json:"target_id" gorm:"not null;uniqueIndex:idx_mention_target"`
+	PostID     uint          `json:"post_id" gorm:"index"`
+	CreatedAt  time.Time     `json:"created_at" gorm:"autoCreateTime"`
+}
+
+type NotificationType string
+
+const (
+	NotificationTypeReply   NotificationType = "reply"   // 回复了我的评论
+	NotificationTypeComment NotificationType = "comment" // 评论了我的文章
+	NotificationTypeLike    NotificationType = "like"    // 点赞了我的文章
+	NotificationTypeStar    NotificationType = "star"    // 收藏了我的文章
+	NotificationTypeFollow  NotificationType = "follow"  // 关注了我
+	NotificationTypeMention NotificationType = "mention" // @提及了我
+)
+
+// NotificationTypes 所有通知类型
+var NotificationTypes = []NotificationType{
+	NotificationTypeReply,
+	NotificationTypeComment,
+	NotificationTypeLike,
+	NotificationTypeStar,
+	NotificationTypeFollow,
+	NotificationTypeMention,
+}
+
+// Notification 站内通知
+// 同一GroupKey的未读通知会被聚合（如"5人赞了你的文章"），ActorID为最近一位触发者
+type Notification struct {
+	ID         uint             `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint             `json:"user_id" gorm:"not null;index"` // 接收者
+	ActorID    uint             `json:"actor_id" gorm:"index"`         // 触发者
+	Type       NotificationType `json:"type" gorm:"type:varchar(20);not null;index"`
+	PostID     uint             `json:"post_id" gorm:"index"`
+	CommentID  uint             `json:"comment_id"`
+	GroupKey   string           `json:"-" gorm:"type:varchar(100);index"`
+	ActorCount uint             `json:"actor_count" gorm:"default:1"`
+	IsRead     bool             `json:"is_read" gorm:"default:false;index"`
+	CreatedAt  time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time        `json:"updated_at" gorm:"autoUpdateTime;index"`
+
+	// 展示文案（不入库）
+	Message string `json:"message" gorm:"-"`
+
+	// 关联关系
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+	Post  *Post `json:"post,omitempty" gorm:"foreignKey:PostID"`
+}
+
+// NotificationActor 聚合通知的触发者，同一个人只记录一次，人数按记录数统计
+type NotificationActor struct {
+	NotificationID uint      `json:"notification_id" gorm:"primaryKey"`
+	ActorID        uint      `json:"actor_id" gorm:"primaryKey"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// NotificationPreference 通知偏好，没有记录的类型默认开启
+type NotificationPreference struct {
+	UserID    uint             `json:"user_id" gorm:"primaryKey"`
+	Type      NotificationType `json:"type" gorm:"type:varchar(20);primaryKey"`
+	Enabled   bool             `json:"enabled"`
+	UpdatedAt time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type ReportTarget string
+
+const (
+	ReportTargetPost    ReportTarget = "post"
+	ReportTargetComment ReportTarget = "comment"
+	ReportTargetUser    ReportTarget = "user"
+)
+
+type ReportReason string
+
+const (
+	ReportReasonSpam           ReportReason = "spam"           // 垃圾广告
+	ReportReasonHarassment     ReportReason = "harassment"     // 骚扰、人身攻击
+	ReportReasonHate           ReportReason = "hate"           // 仇恨言论
+	ReportReasonSexual         ReportReason = "sexual"         // 色情内容
+	ReportReasonViolence       ReportReason = "violence"       // 暴力内容
+	ReportReasonIllegal        ReportReason = "illegal"        // 违法信息
+	ReportReasonMisinformation ReportReason = "misinformation" // 虚假信息
+	ReportReasonOther          ReportReason = "other"          // 其他
+)
+
+// ReportReasons 所有举报原因
+var ReportReasons = []ReportReason{
+	ReportReasonSpam,
+	ReportReasonHarassment,
+	ReportReasonHate,
+	ReportReasonSexual,
+	ReportReasonViolence,
+	ReportReasonIllegal,
+	ReportReasonMisinformation,
+	ReportReasonOther,
+}
+
+type ReportStatus string
+
+const (
+	ReportStatusPending   ReportStatus = "pending"
+	ReportStatusResolved  ReportStatus = "resolved"
+	ReportStatusDismissed ReportStatus = "dismissed"
+)
+
+type ReportAction string
+
+const (
+	ReportActionNone   ReportAction = "none"   // 不做处理，仅关闭举报
+	ReportActionHide   ReportAction = "hide"   // 隐藏内容
+	ReportActionRemove ReportAction = "remove" // 删除内容
+	ReportActionBan    ReportAction = "ban"    // 封禁作者
+)
+
+// Report 举报记录，每个用户对同一目标只能举报一次
+type Report struct {
+	ID         uint         `json:"id" gorm:"primaryKey;autoIncrement"`
+	ReporterID uint         `json:"reporter_id" gorm:"not null;uniqueIndex:idx_report_target"`
+	TargetType ReportTarget `json:"target_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_report_target;index:idx_report_queue"`
+	TargetID   uint         `json:"target_id" gorm:"not null;uniqueIndex:idx_report_target;index:idx_report_queue"`
+	Reason     ReportReason `json:"reason" gorm:"type:varchar(30);not null"`
+	Details    string       `json:"details" gorm:"type:text"`
+	Status     ReportStatus `json:"status" gorm:"type:varchar(20);default:'pending';index"`
+
+	// 审核
+	Action     ReportAction `json:"action,omitempty" gorm:"type:varchar(20)"`
+	ReviewerID uint         `json:"reviewer_id,omitempty"`
+	ReviewNote string       `json:"review_note,omitempty" gorm:"type:text"`
+	ReviewedAt *time.Time   `json:"reviewed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 关联关系
+	Reporter *User `json:"reporter,omitempty" gorm:"foreignKey:ReporterID"`
+}
+
+// ImportRecord 导入记录：来源中的ID与本地ID的对应，重复导入时据此跳过已导入的内容
+type ImportRecord struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Source     string    `json:"source" gorm:"type:varchar(191);not null;uniqueIndex:idx_import_record"` // 如 wordpress:example.com
+	Kind       string    `json:"kind" gorm:"type:varchar(20);not null;uniqueIndex:idx_import_record"`    // user/post/comment
+	ExternalID string    `json:"external_id" gorm:"type:varchar(191);not null;uniqueIndex:idx_import_record"`
+	LocalID    uint      `json:"local_id" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Redirect 旧链接跳转（如从其他博客迁移后文章的旧地址）
+type Redirect struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Path      string    `json:"path" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Target    string    `json:"target" gorm:"type:varchar(500);not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Media 媒体库文件（文章图片和附件）
+// 文件按内容的 SHA-256 存放，相同内容只存一份；每个用户上传的文件各有一条记录，计入各自的存储配额
+type Media struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_media_user_hash"`
+	Hash        string    `json:"hash" gorm:"type:char(64);not null;uniqueIndex:idx_media_user_hash;index"`
+	Key         string    `json:"key" gorm:"type:varchar(255);not null"` // 上传存储中的key
+	FileName    string    `json:"file_name" gorm:"type:varchar(255)"`    // 上传时的文件名
+	ContentType string    `json:"content_type" gorm:"type:varchar(100)"`
+	Size        int64     `json:"size" gorm:"not null"`
+	URL         string    `json:"url" gorm:"type:varchar(500)"`
+	Width       int       `json:"width,omitempty"` // 图片尺寸
+	Height      int       `json:"height,omitempty"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+
+	// 图片的响应式版本（按宽度从小到大，不含原图）和模糊占位图（data URI）
+	Variants    []MediaVariant `json:"variants,omitempty" gorm:"type:text;serializer:json"`
+	Placeholder string         `json:"placeholder,omitempty" gorm:"type:text"`
+
+	// 引用该文件的文章（不入库）
+	Posts []*Post `json:"posts,omitempty" gorm:"-"`
+}
+
+// MediaVariant 图片缩小后的版本
+type MediaVariant struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
+}
+
+// IsImage 是否为可作为封面的图片
+func (m *Media) IsImage() bool {
+	return m.Width > 0 && m.Height > 0
+}
+
+// SrcSet 图片的 srcset 属性值（各版本及原图）
+func (m *Media) SrcSet() string {
+	var parts []string
+	for _, v := range m.Variants {
+		parts = append(parts, fmt.Sprintf("%s %dw", v.URL, v.Width))
+	}
+	if m.Width > 0 {
+		parts = append(parts, fmt.Sprintf("%s %dw", m.URL, m.Width))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MediaReference 文章对媒体文件的引用，文章保存时按内容中的文件地址更新；被引用的文件不能删除
+type MediaReference struct {
+	MediaID   uint      `json:"media_id" gorm:"primaryKey"`
+	PostID    uint      `json:"post_id" gorm:"primaryKey;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Series 系列文章：作者把多篇文章按顺序组成合集（如分多篇的教程）
+type Series struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	Title       string    `json:"title" gorm:"type:varchar(255);not null"`
+	Slug        string    `json:"slug" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 关联关系
+	Author *User `json:"author,omitempty" gorm:"foreignKey:UserID"`
+
+	// 按顺序排列的文章（不入库）
+	Posts []*Post `json:"posts" gorm:"-"`
+}
+
+func (Series) TableName() string { return "series" }
+
+// SeriesPost 系列中的文章，一篇文章最多属于一个系列
+type SeriesPost struct {
+	SeriesID  uint      `json:"series_id" gorm:"primaryKey"`
+	PostID    uint      `json:"post_id" gorm:"primaryKey;uniqueIndex"`
+	Position  int       `json:"position" gorm:"not null;default:0"` // 从1开始
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// SeriesNav 文章在系列中的位置和前后文章
+type SeriesNav struct {
+	ID       uint            `json:"id"`
+	Title    string          `json:"title"`
+	Slug     string          `json:"slug"`
+	Position int             `json:"position"` // 当前文章是第几篇（从1开始）
+	Total    int             `json:"total"`
+	Prev     *SeriesNavEntry `json:"prev,omitempty"`
+	Next     *SeriesNavEntry `json:"next,omitempty"`
+}
+
+// SeriesNavEntry 系列导航中的文章
+type SeriesNavEntry struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
 // AutoMigrate 自动迁移数据库表
 func AutoMigrate(db *gorm.DB) error {
 	tables := []interface{}{
@@ -190,15 +490,26 @@ func AutoMigrate(db *gorm.DB) error {
 		&User{},
 		&Category{},
 		&Tag{},
+		&Media{},
 		// 主表
 		&Post{},
 		&Comment{},
+		&Mention{},
+		&Notification{},
+		&NotificationPreference{},
+		&Report{},
+		&ImportRecord{},
+		&Redirect{},
+		&Series{},
 		// 关联表
 		&UserFollower{},
 		&UserStarPost{},
 		&UserLikePost{},
 		&PostTag{},
 		&CommentLike{},
+		&MediaReference{},
+		&SeriesPost{},
+		&NotificationActor{},
 	}
 	// 批量创建表
 	for _, table := range tables {
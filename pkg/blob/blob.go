@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"blog/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("文件不存在")
+	ErrInvalidKey = errors.New("无效的文件路径")
+)
+
+// Store 上传文件的存储（本地目录或 S3 兼容的对象存储）
+// key 为 / 分隔的相对路径，如 avatars/avatar_1_1700000000.png
+type Store interface {
+	// Put 写入文件，已存在时覆盖；size 未知时传 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取文件，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// Walk 按 key 顺序遍历 prefix 下的所有文件
+	Walk(ctx context.Context, prefix string, fn func(obj Object) error) error
+
+	// URL 文件的访问地址
+	URL(key string) string
+	// Key 从访问地址解析出 key，不是本存储的地址时返回 false
+	Key(url string) (string, bool)
+}
+
+// Object 存储中的文件
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// New 按配置创建存储
+func New(cfg *config.StorageConfig) (Store, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStore(cfg.Local.Dir, cfg.Local.URL), nil
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			PathStyle: cfg.S3.PathStyle,
+			PublicURL: cfg.S3.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Driver)
+	}
+}
+
+// CleanKey 规范化 key，拒绝绝对路径和指向存储之外的路径
+func CleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	clean := path.Clean(key)
+	if key == "" || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	return clean, nil
+}
+
+// escapeKey 逐段转义 key，用于拼接地址
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = pathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// pathEscape 按 RFC 3986 转义（只保留非保留字符），与 S3 签名的编码规则一致
+func pathEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,336 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Options S3 兼容存储的配置
+type S3Options struct {
+	Endpoint  string // 服务地址，如 https://s3.amazonaws.com 或 http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool   // 使用 endpoint/bucket/key 形式的地址，否则为 bucket.endpoint/key
+	PublicURL string // 对外访问地址（如CDN），为空时使用对象的存储地址
+
+	// HTTP 客户端，默认 30 秒超时
+	Client *http.Client
+}
+
+// S3Store S3 兼容的对象存储（AWS S3、MinIO 等），请求使用 AWS Signature V4 签名
+type S3Store struct {
+	options  S3Options
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3Store(options S3Options) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimRight(options.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("无效的S3服务地址: %q", options.Endpoint)
+	}
+	if options.Bucket == "" {
+		return nil, errors.New("没有配置S3存储桶")
+	}
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+	options.PublicURL = strings.TrimRight(options.PublicURL, "/")
+
+	client := options.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &S3Store{
+		options:  options,
+		endpoint: endpoint,
+		client:   client,
+	}, nil
+}
+
+// target 请求的主机和已转义的路径
+func (s *S3Store) target(key string) (host, escapedPath string) {
+	base := strings.TrimRight(s.endpoint.EscapedPath(), "/")
+	if s.options.PathStyle {
+		host = s.endpoint.Host
+		base += "/" + pathEscape(s.options.Bucket)
+	} else {
+		host = s.options.Bucket + "." + s.endpoint.Host
+	}
+	if key == "" {
+		return host, base + "/"
+	}
+	return host, base + "/" + escapeKey(key)
+}
+
+func (s *S3Store) objectURL(key string) string {
+	host, escapedPath := s.target(key)
+	return s.endpoint.Scheme + "://" + host + escapedPath
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	// S3 的 PUT 需要 Content-Length，大小未知时先读入内存
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, nil, header, r, size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil, 0)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listResult ListObjectsV2 的响应
+type listResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// Walk 用 ListObjectsV2 分页遍历，S3 按 key 的字节序返回
+func (s *S3Store) Walk(ctx context.Context, prefix string, fn func(obj Object) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil, 0)
+		if err != nil {
+			return err
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("解析S3文件列表失败: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			if err := fn(Object{Key: c.Key, Size: c.Size, ModTime: c.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3Store) URL(key string) string {
+	key = strings.TrimPrefix(key, "/")
+	if s.options.PublicURL != "" {
+		return s.options.PublicURL + "/" + escapeKey(key)
+	}
+	return s.objectURL(key)
+}
+
+func (s *S3Store) Key(rawURL string) (string, bool) {
+	base := s.options.PublicURL
+	if base == "" {
+		base = strings.TrimSuffix(s.objectURL(""), "/")
+	}
+	rest, ok := strings.CutPrefix(rawURL, base+"/")
+	if !ok {
+		return "", false
+	}
+	key, err := url.PathUnescape(rest)
+	if err != nil {
+		return "", false
+	}
+	if key, err = CleanKey(key); err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// s3Error S3 的错误响应
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do 发送签名后的请求，非 2xx 响应转换为错误（404 为 ErrNotFound）
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	host, escapedPath := s.target(key)
+	rawQuery := canonicalQuery(query)
+	rawURL := s.endpoint.Scheme + "://" + host + escapedPath
+	if rawQuery != "" {
+		rawURL += "?" + rawQuery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, escapedPath, rawQuery, unsignedPayload, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求S3失败: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, ErrNotFound
+	}
+	var e s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("S3 %s %s 失败: %s: %s", method, key, e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("S3 %s %s 失败: %s", method, key, resp.Status)
+}
+
+// 不对请求体签名（HTTPS 已保证完整性），上传时不需要先计算整个文件的哈希
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// sign AWS Signature V4 签名
+func (s *S3Store) sign(req *http.Request, escapedPath, rawQuery, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// 签名的请求头：host 和所有 x-amz-*，按名称排序
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapedPath,
+		rawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.options.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+s.options.SecretKey), date)
+	key = hmacSHA256(key, s.options.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.options.AccessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery 按参数名排序并转义的查询字符串（签名和请求使用同一个）
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, pathEscape(k)+"="+pathEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
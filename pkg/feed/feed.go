@@ -0,0 +1,339 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
+
+// 订阅格式
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+// ContentTypes 各订阅格式的Content-Type
+var ContentTypes = map[string]string{
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// FileNames 各格式订阅源的文件名（页面路径下的 feed.xml 等）
+var FileNames = map[string]string{
+	FormatRSS:  "feed.xml",
+	FormatAtom: "atom.xml",
+	FormatJSON: "feed.json",
+}
+
+// Feed 订阅源（与输出格式无关）
+type Feed struct {
+	Title       string
+	Description string
+	Link        string // 站点/页面地址
+	FeedURL     string // 订阅源自身地址
+	Language    string
+	Updated     time.Time
+	Items       []*Item
+}
+
+// Item 订阅条目
+type Item struct {
+	ID         string
+	Title      string
+	Link       string
+	Summary    string // 纯文本摘要
+	Content    string // HTML正文，为空时只输出摘要
+	Author     string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+	Image      *Image // 封面图，可为空
+}
+
+// Image 条目的封面图（绝对地址）
+type Image struct {
+	URL    string
+	Type   string // Content-Type
+	Length int64  // 文件大小（字节）
+}
+
+// Render 按格式输出订阅源
+func Render(format string, feed *Feed) ([]byte, error) {
+	switch format {
+	case FormatAtom:
+		return renderAtom(feed)
+	case FormatJSON:
+		return renderJSON(feed)
+	default:
+		return renderRSS(feed)
+	}
+}
+
+// RSS 2.0
+type rssDoc struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	Language      string     `xml:"language,omitempty"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Self          rssSelf    `xml:"atom:link"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description"`
+	Content     *cdata        `xml:"content:encoded,omitempty"`
+	Creator     string        `xml:"dc:creator,omitempty"`
+	Categories  []string      `xml:"category"`
+	PubDate     string        `xml:"pubDate"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+func renderRSS(feed *Feed) ([]byte, error) {
+	doc := rssDoc{
+		Version:   "2.0",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.Link,
+			Description: feed.Description,
+			Language:    feed.Language,
+			Self:        rssSelf{Href: feed.FeedURL, Rel: "self", Type: "application/rss+xml"},
+			Items:       make([]*rssItem, 0, len(feed.Items)),
+		},
+	}
+	if !feed.Updated.IsZero() {
+		doc.Channel.LastBuildDate = feed.Updated.Format(time.RFC1123Z)
+	}
+
+	for _, item := range feed.Items {
+		ri := &rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{IsPermaLink: item.ID == item.Link, Value: item.ID},
+			Description: item.Summary,
+			Creator:     item.Author,
+			Categories:  item.Categories,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+		}
+		if item.Content != "" {
+			ri.Content = &cdata{Value: item.Content}
+		}
+		if item.Image != nil {
+			ri.Enclosure = &rssEnclosure{URL: item.Image.URL, Length: item.Image.Length, Type: item.Image.Type}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, ri)
+	}
+
+	return marshalXML(doc)
+}
+
+// Atom 1.0
+type atomDoc struct {
+	XMLName  xml.Name     `xml:"feed"`
+	NS       string       `xml:"xmlns,attr"`
+	Lang     string       `xml:"xml:lang,attr,omitempty"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	ID       string       `xml:"id"`
+	Updated  string       `xml:"updated"`
+	Links    []atomLink   `xml:"link"`
+	Entries  []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+func renderAtom(feed *Feed) ([]byte, error) {
+	doc := atomDoc{
+		NS:       "http://www.w3.org/2005/Atom",
+		Lang:     feed.Language,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		ID:       feed.FeedURL,
+		Updated:  feed.Updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.Link, Rel: "alternate", Type: "text/html"},
+			{Href: feed.FeedURL, Rel: "self", Type: "application/atom+xml"},
+		},
+		Entries: make([]*atomEntry, 0, len(feed.Items)),
+	}
+
+	for _, item := range feed.Items {
+		entry := &atomEntry{
+			Title:     item.Title,
+			ID:        item.ID,
+			Links:     []atomLink{{Href: item.Link, Rel: "alternate", Type: "text/html"}},
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   item.Updated.Format(time.RFC3339),
+		}
+		if item.Image != nil {
+			entry.Links = append(entry.Links, atomLink{Href: item.Image.URL, Rel: "enclosure", Type: item.Image.Type, Length: item.Image.Length})
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		for _, c := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: c})
+		}
+		if item.Summary != "" {
+			entry.Summary = &atomText{Type: "text", Value: item.Summary}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Value: item.Content}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return marshalXML(doc)
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// JSON Feed 1.1
+type jsonFeed struct {
+	Version     string          `json:"version"`
+	Title       string          `json:"title"`
+	HomePageURL string          `json:"home_page_url"`
+	FeedURL     string          `json:"feed_url"`
+	Description string          `json:"description,omitempty"`
+	Language    string          `json:"language,omitempty"`
+	Items       []*jsonFeedItem `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string            `json:"id"`
+	URL           string            `json:"url"`
+	Title         string            `json:"title"`
+	ContentHTML   string            `json:"content_html,omitempty"`
+	ContentText   string            `json:"content_text,omitempty"`
+	Summary       string            `json:"summary,omitempty"`
+	Image         string            `json:"image,omitempty"`
+	DatePublished string            `json:"date_published"`
+	DateModified  string            `json:"date_modified"`
+	Authors       []*jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string          `json:"tags,omitempty"`
+}
+
+func renderJSON(feed *Feed) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.Link,
+		FeedURL:     feed.FeedURL,
+		Description: feed.Description,
+		Language:    feed.Language,
+		Items:       make([]*jsonFeedItem, 0, len(feed.Items)),
+	}
+
+	for _, item := range feed.Items {
+		ji := &jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.Link,
+			Title:         item.Title,
+			Summary:       item.Summary,
+			DatePublished: item.Published.Format(time.RFC3339),
+			DateModified:  item.Updated.Format(time.RFC3339),
+			Tags:          item.Categories,
+		}
+		// content_html 和 content_text 至少需要一个
+		if item.Content != "" {
+			ji.ContentHTML = item.Content
+		} else {
+			ji.ContentText = item.Summary
+		}
+		if item.Image != nil {
+			ji.Image = item.Image.URL
+		}
+		if item.Author != "" {
+			ji.Authors = []*jsonFeedAuthor{{Name: item.Author}}
+		}
+		doc.Items = append(doc.Items, ji)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
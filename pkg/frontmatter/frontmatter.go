@@ -0,0 +1,207 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// 前言格式
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+	FormatNone = ""
+)
+
+var ErrUnclosed = errors.New("前言没有结束分隔符")
+
+// Split 拆分 Markdown 文件的前言和正文：
+//   - YAML：--- 包围（Jekyll/Hugo/Hexo），Hexo 也允许省略开头的 ---
+//   - TOML：+++ 包围（Hugo）
+//   - JSON：以 { 开头的对象（Hugo）
+//
+// 没有前言时返回 FormatNone 和原文
+func Split(content []byte) (format string, meta map[string]interface{}, body []byte, err error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+
+	switch {
+	case strings.HasPrefix(text, "---\n"):
+		raw, rest, ok := cut(text[4:], "---")
+		if !ok {
+			return "", nil, nil, ErrUnclosed
+		}
+		meta, err = parseYAML(raw)
+		return FormatYAML, meta, []byte(rest), err
+
+	case strings.HasPrefix(text, "+++\n"):
+		raw, rest, ok := cut(text[4:], "+++")
+		if !ok {
+			return "", nil, nil, ErrUnclosed
+		}
+		meta = map[string]interface{}{}
+		if err := toml.Unmarshal([]byte(raw), &meta); err != nil {
+			return "", nil, nil, fmt.Errorf("解析TOML前言失败: %w", err)
+		}
+		return FormatTOML, meta, []byte(rest), nil
+
+	case strings.HasPrefix(text, "{"):
+		dec := json.NewDecoder(strings.NewReader(text))
+		meta = map[string]interface{}{}
+		if err := dec.Decode(&meta); err != nil {
+			return "", nil, nil, fmt.Errorf("解析JSON前言失败: %w", err)
+		}
+		rest := text[dec.InputOffset():]
+		return FormatJSON, meta, []byte(strings.TrimLeft(rest, "\n")), nil
+	}
+
+	// Hexo：没有开头分隔符，第一行 --- 之前是 YAML
+	if raw, rest, ok := cut(text, "---"); ok {
+		if meta, err := parseYAML(raw); err == nil && len(meta) > 0 {
+			return FormatYAML, meta, []byte(rest), nil
+		}
+	}
+	return FormatNone, map[string]interface{}{}, []byte(text), nil
+}
+
+// cut 在单独成行的分隔符处拆开
+func cut(text, delim string) (before, after string, ok bool) {
+	for offset := 0; offset <= len(text); {
+		line := text[offset:]
+		end := strings.IndexByte(line, '\n')
+		if end >= 0 {
+			line = line[:end]
+		}
+		if strings.TrimRight(line, " \t") == delim {
+			rest := ""
+			if end >= 0 {
+				rest = text[offset+end+1:]
+			}
+			return text[:offset], strings.TrimLeft(rest, "\n"), true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return "", "", false
+}
+
+func parseYAML(raw string) (map[string]interface{}, error) {
+	meta := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(raw), &meta); err != nil {
+		return nil, fmt.Errorf("解析YAML前言失败: %w", err)
+	}
+	return meta, nil
+}
+
+// String 字符串字段
+func String(meta map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := meta[key].(type) {
+		case string:
+			if s := strings.TrimSpace(v); s != "" {
+				return s
+			}
+		case nil:
+		default:
+			return strings.TrimSpace(fmt.Sprint(v))
+		}
+	}
+	return ""
+}
+
+// Strings 列表字段，也接受逗号分隔的字符串；嵌套列表（Hexo 多级分类）会展开
+func Strings(meta map[string]interface{}, keys ...string) []string {
+	var out []string
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					out = append(out, s)
+				}
+			}
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case nil:
+		default:
+			out = append(out, fmt.Sprint(v))
+		}
+	}
+	for _, key := range keys {
+		collect(meta[key])
+	}
+	return out
+}
+
+// Bool 布尔字段，不存在时返回 def
+func Bool(meta map[string]interface{}, def bool, keys ...string) bool {
+	for _, key := range keys {
+		switch v := meta[key].(type) {
+		case bool:
+			return v
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "yes", "1":
+				return true
+			case "false", "no", "0":
+				return false
+			}
+		}
+	}
+	return def
+}
+
+// 常见的日期写法
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"2006-01-02",
+}
+
+// Time 时间字段，没有时区的时间按 loc 解析
+func Time(meta map[string]interface{}, loc *time.Location, keys ...string) (time.Time, error) {
+	for _, key := range keys {
+		switch v := meta[key].(type) {
+		case time.Time:
+			// YAML 把没有时区的时间解析为 UTC，按 loc 重新解释
+			if v.Location() == time.UTC && loc != time.UTC {
+				return time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), loc), nil
+			}
+			return v, nil
+		case toml.LocalDateTime:
+			return v.AsTime(loc), nil
+		case toml.LocalDate:
+			return v.AsTime(loc), nil
+		case string:
+			s := strings.TrimSpace(v)
+			if s == "" {
+				continue
+			}
+			for _, layout := range dateLayouts {
+				if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+					return t, nil
+				}
+			}
+			return time.Time{}, fmt.Errorf("无法识别的日期 %s: %q", key, s)
+		}
+	}
+	return time.Time{}, nil
+}
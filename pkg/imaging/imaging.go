@@ -0,0 +1,209 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// 解码前限制图片的像素数，防止很小的文件解压出巨大的图片占满内存
+const MaxPixels int64 = 40 * 1000 * 1000
+
+var (
+	ErrUnsupportedFormat = errors.New("不支持的图片格式，仅支持 JPEG、PNG、GIF、WebP")
+	ErrImageTooLarge     = errors.New("图片尺寸过大")
+)
+
+// Decode 解码图片（JPEG、PNG、GIF、WebP，GIF 只取第一帧），并按 EXIF 方向信息摆正
+// 返回的图片不带任何元数据，重新编码后 EXIF（拍摄地点、设备等）即被去除
+func Decode(data []byte) (image.Image, string, error) {
+	return DecodeLimit(data, MaxPixels)
+}
+
+// DecodeLimit 同 Decode，像素数超过 maxPixels 时返回 ErrImageTooLarge
+func DecodeLimit(data []byte, maxPixels int64) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, "", ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if format == "jpeg" {
+		img = Orient(img, jpegOrientation(data))
+	}
+	return img, format, nil
+}
+
+// Orient 按 EXIF 方向值（1-8）旋转/翻转图片，其他值原样返回
+// 逐行转换到一行大小的缓冲区再写入结果，不额外复制整张图片
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	row := image.NewNRGBA(image.Rect(0, 0, w, 1))
+
+	// 5-8 需要转置，宽高互换
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		draw.Draw(row, row.Bounds(), img, image.Pt(b.Min.X, b.Min.Y+y), draw.Src)
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转180度
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿左上-右下对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转90度
+				dx, dy = h-1-y, x
+			case 7: // 沿右上-左下对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转90度
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], row.Pix[x*4:][:4])
+		}
+	}
+	return dst
+}
+
+// CropSquare 从中间裁出最大的正方形
+func CropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	rect := image.Rect(x0, y0, x0+side, y0+side)
+
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst
+}
+
+// Resize 缩放到指定尺寸（Catmull-Rom 插值）
+func Resize(img image.Image, width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+// ResizeWidth 按宽度等比缩放
+func ResizeWidth(img image.Image, width int) *image.NRGBA {
+	b := img.Bounds()
+	height := max(1, (b.Dy()*width+b.Dx()/2)/b.Dx())
+	return Resize(img, width, height)
+}
+
+// Opaque 图片是否没有透明像素
+func Opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// EncodeJPEG 编码为 JPEG（不支持透明）
+func EncodeJPEG(w io.Writer, img image.Image, quality int) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+// EncodeWebP 编码为 WebP（无损）
+func EncodeWebP(w io.Writer, img image.Image) error {
+	return nativewebp.Encode(w, img, nil)
+}
+
+// jpegOrientation 读取 JPEG 中 EXIF 的方向值，没有或无法解析时返回 1
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		// 遇到图像数据（SOS）或结束标记后不会再有 EXIF
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(data[i+2])<<8 | int(data[i+3])
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation 在 TIFF 结构的第 0 个 IFD 中查找 Orientation（0x0112）
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var u16 func(b []byte) int
+	var u32 func(b []byte) int
+	switch string(tiff[:2]) {
+	case "II":
+		u16 = func(b []byte) int { return int(b[0]) | int(b[1])<<8 }
+		u32 = func(b []byte) int { return int(b[0]) | int(b[1])<<8 | int(b[2])<<16 | int(b[3])<<24 }
+	case "MM":
+		u16 = func(b []byte) int { return int(b[0])<<8 | int(b[1]) }
+		u32 = func(b []byte) int { return int(b[0])<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3]) }
+	default:
+		return 1
+	}
+
+	offset := u32(tiff[4:8])
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := u16(tiff[offset:])
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// 方向值为 SHORT 类型，直接存放在值字段中
+		if u16(tiff[entry:]) == 0x0112 {
+			if v := u16(tiff[entry+8:]); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
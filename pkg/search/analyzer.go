@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"blog/utils"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+const (
+	// ChineseTokenizerName 中文分词器名称
+	ChineseTokenizerName = "blog_zh"
+	// ChineseAnalyzerName 中文分析器名称（分词 + 全角转半角 + 小写）
+	ChineseAnalyzerName = "blog_zh"
+)
+
+// chineseTokenizer 基于词典的中英文混合分词器
+type chineseTokenizer struct{}
+
+func (t *chineseTokenizer) Tokenize(input []byte) analysis.TokenStream {
+	words := utils.SegmentSearchWords(string(input))
+	stream := make(analysis.TokenStream, 0, len(words))
+	position, end := 0, 0
+	for _, w := range words {
+		// 拆出的短词与所在长词共用同一位置
+		if position == 0 || w.Start >= end {
+			position++
+			end = w.End
+		}
+		stream = append(stream, &analysis.Token{
+			Term:     []byte(w.Text),
+			Start:    w.Start,
+			End:      w.End,
+			Position: position,
+			Type:     tokenType(w.Text),
+		})
+	}
+	return stream
+}
+
+// tokenType 包含汉字的词标记为表意文字，其余为字母数字
+func tokenType(text string) analysis.TokenType {
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return analysis.Ideographic
+		}
+	}
+	return analysis.AlphaNumeric
+}
+
+func chineseTokenizerConstructor(config map[string]interface{}, cache *registry.Cache) (analysis.Tokenizer, error) {
+	return &chineseTokenizer{}, nil
+}
+
+func chineseAnalyzerConstructor(config map[string]interface{}, cache *registry.Cache) (analysis.Analyzer, error) {
+	tokenizer, err := cache.TokenizerNamed(ChineseTokenizerName)
+	if err != nil {
+		return nil, err
+	}
+	widthFilter, err := cache.TokenFilterNamed(cjk.WidthName)
+	if err != nil {
+		return nil, err
+	}
+	toLowerFilter, err := cache.TokenFilterNamed(lowercase.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.DefaultAnalyzer{
+		Tokenizer: tokenizer,
+		TokenFilters: []analysis.TokenFilter{
+			widthFilter,
+			toLowerFilter,
+		},
+	}, nil
+}
+
+func init() {
+	if err := registry.RegisterTokenizer(ChineseTokenizerName, chineseTokenizerConstructor); err != nil {
+		panic(err)
+	}
+	if err := registry.RegisterAnalyzer(ChineseAnalyzerName, chineseAnalyzerConstructor); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"blog/config"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+// PostAnalyzer 帖子文本字段使用的分析器
+var PostAnalyzer = ChineseAnalyzerName
+
+// NewPostIndexMapping 帖子索引映射（BM25评分）
+func NewPostIndexMapping() mapping.IndexMapping {
+	textField := func() *mapping.FieldMapping {
+		f := bleve.NewTextFieldMapping()
+		f.Analyzer = PostAnalyzer
+		f.Store = true
+		f.IncludeTermVectors = true // 高亮需要词位置
+		return f
+	}
+
+	postMapping := bleve.NewDocumentMapping()
+	postMapping.AddFieldMappingsAt("title", textField())
+	postMapping.AddFieldMappingsAt("summary", textField())
+	postMapping.AddFieldMappingsAt("content", textField())
+	postMapping.AddFieldMappingsAt("author_name", textField())
+
+	// 过滤和分面统计字段（不分词）
+	keywordField := func() *mapping.FieldMapping {
+		f := bleve.NewKeywordFieldMapping()
+		f.Store = false
+		f.IncludeInAll = false
+		return f
+	}
+	postMapping.AddFieldMappingsAt("category_id", keywordField())
+	postMapping.AddFieldMappingsAt("tag_ids", keywordField())
+	postMapping.AddFieldMappingsAt("author_id", keywordField())
+
+	createdAt := bleve.NewDateTimeFieldMapping()
+	createdAt.Store = false
+	postMapping.AddFieldMappingsAt("created_at", createdAt)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = postMapping
+	indexMapping.DefaultAnalyzer = PostAnalyzer
+	indexMapping.ScoringModel = index.BM25Scoring
+	return indexMapping
+}
+
+// OpenSearchIndex 打开全文索引，不存在时创建
+func OpenSearchIndex(path string) (bleve.Index, error) {
+	idx, err := bleve.Open(path)
+	if err == nil {
+		return idx, nil
+	}
+	if err != bleve.ErrorIndexPathDoesNotExist {
+		return nil, fmt.Errorf("打开全文索引失败: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建索引目录失败: %w", err)
+	}
+	idx, err = bleve.New(path, NewPostIndexMapping())
+	if err != nil {
+		return nil, fmt.Errorf("创建全文索引失败: %w", err)
+	}
+	return idx, nil
+}
+
+// InitSearchIndex 初始化全文索引
+func InitSearchIndex(cfg *config.SearchConfig) (bleve.Index, error) {
+	return OpenSearchIndex(cfg.IndexPath)
+}
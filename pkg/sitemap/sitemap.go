@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MaxURLs 单个站点地图文件最多包含的URL数（协议限制）
+const MaxURLs = 50000
+
+const sitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL 站点地图条目
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+// Sitemap 站点地图索引中的子站点地图
+type Sitemap struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name  `xml:"urlset"`
+	NS      string    `xml:"xmlns,attr"`
+	URLs    []xmlItem `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name  `xml:"sitemapindex"`
+	NS       string    `xml:"xmlns,attr"`
+	Sitemaps []xmlItem `xml:"sitemap"`
+}
+
+type xmlItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+func lastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// RenderURLSet 输出 urlset 站点地图
+func RenderURLSet(urls []URL) ([]byte, error) {
+	doc := urlSet{NS: sitemapNS, URLs: make([]xmlItem, 0, len(urls))}
+	for _, u := range urls {
+		doc.URLs = append(doc.URLs, xmlItem{Loc: u.Loc, LastMod: lastMod(u.LastMod)})
+	}
+	return marshal(doc)
+}
+
+// RenderIndex 输出站点地图索引
+func RenderIndex(sitemaps []Sitemap) ([]byte, error) {
+	doc := sitemapIndex{NS: sitemapNS, Sitemaps: make([]xmlItem, 0, len(sitemaps))}
+	for _, s := range sitemaps {
+		doc.Sitemaps = append(doc.Sitemaps, xmlItem{Loc: s.Loc, LastMod: lastMod(s.LastMod)})
+	}
+	return marshal(doc)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,166 @@
+package pkg
+
+import (
+	"blog/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 页面模板名（主题目录下的同名 .html 文件）
+const (
+	PageIndex    = "index"
+	PagePost     = "post"
+	PageCategory = "category"
+	PageTag      = "tag"
+	PageAuthor   = "author"
+	PageNotFound = "404"
+)
+
+// 公共模板文件：布局和以下划线开头的片段，所有页面共享
+const layoutFile = "layout.html"
+
+// Meta 页面SEO信息
+type Meta struct {
+	Title       string
+	Description string
+	Canonical   string // 规范地址（绝对地址）
+	Type        string // og:type，website 或 article
+	Image       string // og:image（绝对地址）
+	ImageWidth  int
+	ImageHeight int
+	Author      string
+	Keywords    []string
+	Published   time.Time
+	Modified    time.Time
+	Prev        string // 上一页/下一页地址
+	Next        string
+	NoIndex     bool
+	JSONLD      interface{} // 结构化数据，输出为 application/ld+json
+}
+
+// Theme 已加载的主题模板
+type Theme struct {
+	dir   string
+	pages map[string]*template.Template
+}
+
+// Load 加载主题目录：layout.html 和 _*.html 为公共模板，其余每个 .html 为一个页面
+func Load(dir string) (*Theme, error) {
+	base := template.New(layoutFile).Funcs(funcs)
+
+	shared, err := filepath.Glob(filepath.Join(dir, "_*.html"))
+	if err != nil {
+		return nil, err
+	}
+	shared = append([]string{filepath.Join(dir, layoutFile)}, shared...)
+	if base, err = base.ParseFiles(shared...); err != nil {
+		return nil, fmt.Errorf("解析主题公共模板失败: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Theme{dir: dir, pages: make(map[string]*template.Template)}
+	for _, file := range files {
+		name := filepath.Base(file)
+		if name == layoutFile || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		page, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := page.New(name).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("解析主题模板 %s 失败: %w", name, err)
+		}
+		t.pages[strings.TrimSuffix(name, ".html")] = page
+	}
+
+	for _, name := range []string{PageIndex, PagePost, PageCategory, PageTag, PageAuthor, PageNotFound} {
+		if t.pages[name] == nil {
+			return nil, fmt.Errorf("主题缺少模板 %s.html", name)
+		}
+	}
+	return t, nil
+}
+
+// Dir 主题目录
+func (t *Theme) Dir() string {
+	return t.dir
+}
+
+// Render 渲染页面：页面模板定义各个 block，由 layout.html 组装
+func (t *Theme) Render(name string, data interface{}) ([]byte, error) {
+	page, ok := t.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("模板 %s 不存在", name)
+	}
+
+	var buf bytes.Buffer
+	if err := page.ExecuteTemplate(&buf, layoutFile, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 模板函数
+var funcs = template.FuncMap{
+	// 日期显示
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+	// ISO 8601，用于 meta 和 <time datetime>
+	"isoDate": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	},
+	// 已经过滤的HTML正文
+	"safeHTML": func(s string) template.HTML {
+		return template.HTML(s)
+	},
+	// 结构化数据；json.Marshal 会转义 < > &，不会提前闭合 script 标签
+	"jsonLD": func(v interface{}) (template.JS, error) {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return template.JS(body), nil
+	},
+	// 站内链接
+	"postPath":     utils.PostPath,
+	"categoryPath": utils.CategoryPath,
+	"tagPath":      utils.TagPath,
+	"userPath":     utils.UserPath,
+	// 摘要，没有填写时从正文截取
+	"summary": utils.Summarize,
+	"join":    strings.Join,
+	// 响应式图片的 srcset（地址由服务端生成）
+	"srcset": func(s string) template.Srcset {
+		return template.Srcset(s)
+	},
+	// 图片占位图，只放行 data:image/ 开头的地址
+	"dataURL": func(s string) template.URL {
+		if !strings.HasPrefix(s, "data:image/") {
+			return ""
+		}
+		return template.URL(s)
+	},
+	"add": func(a, b int) int {
+		return a + b
+	},
+}
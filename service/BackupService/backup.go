@@ -0,0 +1,448 @@
+package service
+
+import (
+	"archive/zip"
+	"blog/model"
+	blobpkg "blog/pkg/blob"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 归档格式
+const (
+	ArchiveFormat  = "blog-backup"
+	ArchiveVersion = 1
+
+	manifestName = "manifest.json"
+	dataDir      = "data/"
+	uploadsDir   = "uploads/"
+)
+
+var (
+	ErrDatabaseNotEmpty   = errors.New("数据库不是空的，只能恢复到空数据库")
+	ErrInvalidArchive     = errors.New("不是有效的备份归档")
+	ErrUnsupportedVersion = errors.New("不支持的备份归档版本")
+)
+
+type BackupService interface {
+	// Export 导出整个博客（数据库和上传的文件）为 ZIP 归档
+	Export(ctx context.Context, w io.Writer, opts ExportOptions) (*Manifest, error)
+	// Restore 把归档恢复到空数据库，上传的文件写入上传存储
+	Restore(ctx context.Context, r io.ReaderAt, size int64) (*Manifest, error)
+}
+
+// Options 备份配置
+type Options struct {
+	Uploads blobpkg.Store // 上传文件存储
+}
+
+// ExportOptions 导出参数
+type ExportOptions struct {
+	// 不导出用户的密码哈希，恢复后需要重置密码
+	WithoutPasswords bool
+}
+
+// Manifest 归档说明（manifest.json）
+type Manifest struct {
+	Format    string         `json:"format"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Driver    string         `json:"driver"`    // 导出时的数据库
+	Passwords bool           `json:"passwords"` // 是否包含密码哈希
+	Tables    map[string]int `json:"tables"`    // 表 => 行数
+	Files     int            `json:"files"`     // 上传的文件数
+}
+
+type backupService struct {
+	// 数据库
+	db *gorm.DB
+
+	options Options
+}
+
+func NewBackupService(db *gorm.DB, options Options) BackupService {
+	return &backupService{
+		db:      db,
+		options: options,
+	}
+}
+
+// backupUser 用户的密码哈希在 json 中是隐藏的，单独导出
+type backupUser struct {
+	model.User
+	Password string `json:"password,omitempty"`
+}
+
+// backupNotification 通知的聚合键在 json 中是隐藏的，单独导出
+type backupNotification struct {
+	model.Notification
+	GroupKey string `json:"group_key,omitempty"`
+}
+
+// backupTable 一张表的导出和恢复
+type backupTable struct {
+	name  string      // 归档中的文件 data/{name}.json
+	model interface{} // 表对应的模型
+	// export 按顺序逐行读取并写入归档，返回行数
+	export func(tx *gorm.DB, opts *ExportOptions, write func(row interface{}) error) (int, error)
+	// restore 从归档逐行读取并写入数据库，返回行数
+	restore func(tx *gorm.DB, dec *json.Decoder) (int, error)
+}
+
+// tables 按依赖顺序排列（被引用的表在前），与 model.AutoMigrate 保持一致
+var tables = []*backupTable{
+	tableOf("users", "id", func(u *model.User, opts *ExportOptions) interface{} {
+		row := &backupUser{User: *u}
+		if !opts.WithoutPasswords {
+			row.Password = u.Password
+		}
+		return row
+	}, func(row *backupUser) *model.User {
+		row.User.Password = row.Password
+		if row.User.Password == "" {
+			row.User.Password = model.LockedPassword
+		}
+		return &row.User
+	}),
+	plainTable[model.Category]("categories", "id"),
+	plainTable[model.Tag]("tags", "id"),
+	// 文章的封面引用媒体文件
+	plainTable[model.Media]("media", "id"),
+	plainTable[model.Post]("posts", "id"),
+	// 回复的ID总是大于父评论
+	plainTable[model.Comment]("comments", "id"),
+	plainTable[model.Mention]("mentions", "id"),
+	tableOf("notifications", "id", func(n *model.Notification, opts *ExportOptions) interface{} {
+		return &backupNotification{Notification: *n, GroupKey: n.GroupKey}
+	}, func(row *backupNotification) *model.Notification {
+		row.Notification.GroupKey = row.GroupKey
+		return &row.Notification
+	}),
+	plainTable[model.NotificationPreference]("notification_preferences", "user_id, type"),
+	plainTable[model.Report]("reports", "id"),
+	plainTable[model.ImportRecord]("import_records", "id"),
+	plainTable[model.Redirect]("redirects", "id"),
+	plainTable[model.UserFollower]("user_followers", "user_id, following_id"),
+	plainTable[model.UserStarPost]("user_star_posts", "user_id, post_id"),
+	plainTable[model.UserLikePost]("user_like_posts", "user_id, post_id"),
+	plainTable[model.PostTag]("post_tags", "post_id, tag_id"),
+	plainTable[model.CommentLike]("comment_likes", "user_id, comment_id"),
+	plainTable[model.MediaReference]("media_references", "media_id, post_id"),
+	plainTable[model.Series]("series", "id"),
+	plainTable[model.SeriesPost]("series_posts", "series_id, position"),
+}
+
+// plainTable 模型直接序列化的表
+func plainTable[T any](name, order string) *backupTable {
+	return tableOf(name, order, func(row *T, _ *ExportOptions) interface{} { return row }, func(row *T) *T { return row })
+}
+
+// restoreBatchSize 恢复时每批写入的行数
+const restoreBatchSize = 200
+
+// tableOf 逐行读取模型 T，转换为归档中的 S 写入；恢复时反向转换
+func tableOf[T, S any](name, order string, toArchive func(*T, *ExportOptions) interface{}, fromArchive func(*S) *T) *backupTable {
+	return &backupTable{
+		name:  name,
+		model: new(T),
+		export: func(tx *gorm.DB, opts *ExportOptions, write func(row interface{}) error) (int, error) {
+			rows, err := tx.Model(new(T)).Order(order).Rows()
+			if err != nil {
+				return 0, err
+			}
+			defer rows.Close()
+
+			count := 0
+			for rows.Next() {
+				var row T
+				if err := tx.ScanRows(rows, &row); err != nil {
+					return count, err
+				}
+				if err := write(toArchive(&row, opts)); err != nil {
+					return count, err
+				}
+				count++
+			}
+			return count, rows.Err()
+		},
+		restore: func(tx *gorm.DB, dec *json.Decoder) (int, error) {
+			count := 0
+			batch := make([]*T, 0, restoreBatchSize)
+			flush := func() error {
+				if len(batch) == 0 {
+					return nil
+				}
+				// 不级联写入关联，关联表单独恢复
+				err := tx.Omit(clause.Associations).CreateInBatches(batch, restoreBatchSize).Error
+				batch = batch[:0]
+				return err
+			}
+
+			for dec.More() {
+				row := new(S)
+				if err := dec.Decode(row); err != nil {
+					return count, err
+				}
+				batch = append(batch, fromArchive(row))
+				count++
+				if len(batch) == restoreBatchSize {
+					if err := flush(); err != nil {
+						return count, err
+					}
+				}
+			}
+			return count, flush()
+		},
+	}
+}
+
+// Export 导出整个博客：data/ 下每张表一个 JSON 数组，uploads/ 下为上传的文件，最后写入 manifest.json
+func (s *backupService) Export(ctx context.Context, w io.Writer, opts ExportOptions) (*Manifest, error) {
+	manifest := &Manifest{
+		Format:    ArchiveFormat,
+		Version:   ArchiveVersion,
+		CreatedAt: time.Now(),
+		Driver:    s.db.Dialector.Name(),
+		Passwords: !opts.WithoutPasswords,
+		Tables:    make(map[string]int, len(tables)),
+	}
+	zw := zip.NewWriter(w)
+
+	// 在一个事务中读取，保证各表数据一致
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range tables {
+			count, err := exportTable(zw, tx, table, &opts, manifest.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("导出%s失败: %w", table.name, err)
+			}
+			manifest.Tables[table.name] = count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Files, err = s.exportUploads(ctx, zw); err != nil {
+		return nil, fmt.Errorf("导出上传文件失败: %w", err)
+	}
+
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: manifestName, Method: zip.Deflate, Modified: manifest.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportTable 把一张表写为 JSON 数组，每行一个对象
+func exportTable(zw *zip.Writer, tx *gorm.DB, table *backupTable, opts *ExportOptions, modified time.Time) (int, error) {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: dataDir + table.name + ".json", Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(fw, "[\n"); err != nil {
+		return 0, err
+	}
+
+	enc := json.NewEncoder(fw)
+	first := true
+	count, err := table.export(tx, opts, func(row interface{}) error {
+		if !first {
+			if _, err := io.WriteString(fw, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		// Encode 会追加换行，每行一个对象
+		return enc.Encode(row)
+	})
+	if err != nil {
+		return count, err
+	}
+	_, err = io.WriteString(fw, "]\n")
+	return count, err
+}
+
+// exportUploads 把上传存储中的文件写入 uploads/
+func (s *backupService) exportUploads(ctx context.Context, zw *zip.Writer) (int, error) {
+	if s.options.Uploads == nil {
+		return 0, nil
+	}
+	count := 0
+	err := s.options.Uploads.Walk(ctx, "", func(obj blobpkg.Object) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: uploadsDir + obj.Key, Method: zip.Deflate, Modified: obj.ModTime})
+		if err != nil {
+			return err
+		}
+		rc, err := s.options.Uploads.Open(ctx, obj.Key)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if _, err := io.Copy(fw, rc); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// Restore 恢复归档：数据库在一个事务中写入，失败时不留下部分数据；成功后再写入上传的文件
+func (s *backupService) Restore(ctx context.Context, r io.ReaderAt, size int64) (*Manifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifest, err := readManifest(files[manifestName])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkEmpty(ctx); err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 归档可能来自不强制外键的 SQLite，其中可能有悬空引用（如已删除文章的评论）
+		if tx.Dialector.Name() == "mysql" {
+			if err := tx.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+				return err
+			}
+			defer tx.Exec("SET FOREIGN_KEY_CHECKS = 1")
+		}
+
+		for _, table := range tables {
+			f := files[dataDir+table.name+".json"]
+			if f == nil {
+				// 旧版本的归档可能没有后来新增的表
+				continue
+			}
+			count, err := restoreTable(tx, f, table)
+			if err != nil {
+				return fmt.Errorf("恢复%s失败: %w", table.name, err)
+			}
+			if expected, ok := manifest.Tables[table.name]; ok && count != expected {
+				return fmt.Errorf("恢复%s失败: 归档中有%d行，说明中为%d行", table.name, count, expected)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.restoreUploads(ctx, zr.File); err != nil {
+		return manifest, fmt.Errorf("数据库已恢复，但写入上传文件失败: %w", err)
+	}
+	return manifest, nil
+}
+
+func readManifest(f *zip.File) (*Manifest, error) {
+	if f == nil {
+		return nil, ErrInvalidArchive
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil || manifest.Format != ArchiveFormat {
+		return nil, ErrInvalidArchive
+	}
+	if manifest.Version < 1 || manifest.Version > ArchiveVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	return &manifest, nil
+}
+
+// checkEmpty 所有表都没有数据才能恢复，避免与已有数据的ID冲突
+func (s *backupService) checkEmpty(ctx context.Context) error {
+	for _, table := range tables {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(table.model).Count(&count).Error; err != nil {
+			return fmt.Errorf("检查%s失败: %w", table.name, err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%w（%s有%d行）", ErrDatabaseNotEmpty, table.name, count)
+		}
+	}
+	return nil
+}
+
+// restoreTable 逐行读取 JSON 数组并写入
+func restoreTable(tx *gorm.DB, f *zip.File, table *backupTable) (int, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	dec := json.NewDecoder(rc)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return 0, ErrInvalidArchive
+	}
+	count, err := table.restore(tx, dec)
+	if err != nil {
+		return count, err
+	}
+	if _, err := dec.Token(); err != nil {
+		return count, ErrInvalidArchive
+	}
+	return count, nil
+}
+
+// restoreUploads 把 uploads/ 下的文件写入上传存储
+func (s *backupService) restoreUploads(ctx context.Context, files []*zip.File) error {
+	if s.options.Uploads == nil {
+		return nil
+	}
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, uploadsDir) || f.FileInfo().IsDir() {
+			continue
+		}
+		// 拒绝 ../ 等指向存储之外的路径
+		key, err := blobpkg.CleanKey(strings.TrimPrefix(f.Name, uploadsDir))
+		if err != nil {
+			return fmt.Errorf("%w: 非法路径 %s", ErrInvalidArchive, f.Name)
+		}
+		if err := s.restoreFile(ctx, f, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *backupService) restoreFile(ctx context.Context, f *zip.File, key string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return s.options.Uploads.Put(ctx, key, rc, int64(f.UncompressedSize64), mime.TypeByExtension(path.Ext(key)))
+}
@@ -88,7 +88,8 @@ func (s *categoryService) CreateCategory(ctx context.Context, req *CreateCategor
 	slug := ""
 	if req.Slug != "" {
 		slug = utils.SanitizeSlug(req.Slug)
-	} else {
+	}
+	if slug == "" {
 		slug = utils.GenerateSlug(name)
 	}
 
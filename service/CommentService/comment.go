@@ -4,10 +4,16 @@ import (
 	mysql "blog/dao/mysql"
 	redis "blog/dao/redis"
 	"blog/model"
+	counterservice "blog/service/CounterService"
+	mentionservice "blog/service/MentionService"
+	notificationservice "blog/service/NotificationService"
+	streamservice "blog/service/StreamService"
+	trendingservice "blog/service/TrendingService"
 	"blog/utils"
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -25,14 +31,42 @@ var (
 	ErrUnauthorized              = errors.New("未授权操作")
 	ErrRateLimited               = errors.New("操作过于频繁，请稍后再试")
 	ErrOperationInProgress       = errors.New("操作正在进行中，请稍后再试")
+	ErrInvalidCommentSort        = errors.New("无效的评论排序方式")
 )
 
+// CommentSort 评论排序方式
+type CommentSort string
+
+const (
+	CommentSortNew CommentSort = "new" // 最新
+	CommentSortOld CommentSort = "old" // 最早
+	CommentSortTop CommentSort = "top" // 点赞最多
+	CommentSortHot CommentSort = "hot" // 热度（点赞数随时间衰减）
+)
+
+// ParseCommentSort 解析排序参数，空字符串返回默认值
+func ParseCommentSort(value string, defaultSort CommentSort) (CommentSort, error) {
+	switch CommentSort(strings.ToLower(strings.TrimSpace(value))) {
+	case "":
+		return defaultSort, nil
+	case CommentSortNew:
+		return CommentSortNew, nil
+	case CommentSortOld:
+		return CommentSortOld, nil
+	case CommentSortTop:
+		return CommentSortTop, nil
+	case CommentSortHot:
+		return CommentSortHot, nil
+	}
+	return "", ErrInvalidCommentSort
+}
+
 type CommentService interface {
 	// 评论基础功能
 	CreateComment(ctx context.Context, req *CreateCommentRequest) (*model.Comment, error)
 	GetComment(ctx context.Context, id uint) (*model.Comment, error)
 	DeleteComment(ctx context.Context, id uint) error
-	ListCommentsByPost(ctx context.Context, postID uint, page, size int) ([]*model.Comment, int64, error)
+	ListCommentsByPost(ctx context.Context, postID uint, sortBy CommentSort, page, size int) ([]*model.Comment, int64, error)
 	ListCommentsByUser(ctx context.Context, userID uint, page, size int) ([]*model.Comment, int64, error)
 
 	// 评论点赞功能
@@ -43,7 +77,10 @@ type CommentService interface {
 
 	// 评论回复功能
 	CreateReply(ctx context.Context, req *CreateReplyRequest) (*model.Comment, error)
-	ListReplies(ctx context.Context, commentID uint, page, size int) ([]*model.Comment, int64, error)
+	ListReplies(ctx context.Context, commentID uint, sortBy CommentSort, page, size int) ([]*model.Comment, int64, error)
+
+	// 审核
+	SetCommentStatus(ctx context.Context, commentID uint, status, hiddenBy string) error
 }
 
 // 请求结构体
@@ -81,6 +118,21 @@ type commentService struct {
 	// 限流器
 	rateLimiter *utils.RateLimiter
 
+	// @提及
+	mentionService mentionservice.MentionService
+
+	// 通知
+	notificationService notificationservice.NotificationService
+
+	// 实时推送
+	streamService streamservice.StreamService
+
+	// 热门文章
+	trendingService trendingservice.TrendingService
+
+	// 计数写缓冲（帖子评论数定时写入数据库）
+	counterService counterservice.CounterService
+
 	// 缓存
 	hotCommentsCache map[uint]*model.Comment
 	hotCommentsTTL   map[uint]time.Time
@@ -97,18 +149,28 @@ func NewCommentService(
 	db *gorm.DB,
 	lockManager *utils.LockManager,
 	rateLimiter *utils.RateLimiter,
+	mentionService mentionservice.MentionService,
+	notificationService notificationservice.NotificationService,
+	streamService streamservice.StreamService,
+	trendingService trendingservice.TrendingService,
+	counterService counterservice.CounterService,
 ) CommentService {
 	return &commentService{
-		commentSQL:       commentSQL,
-		postSQL:          postSQL,
-		userSQL:          userSQL,
-		commentLikeSQL:   commentLikeSQL,
-		commentCache:     commentCache,
-		db:               db,
-		lockManager:      lockManager,
-		rateLimiter:      rateLimiter,
-		hotCommentsCache: make(map[uint]*model.Comment),
-		hotCommentsTTL:   make(map[uint]time.Time),
+		commentSQL:          commentSQL,
+		postSQL:             postSQL,
+		userSQL:             userSQL,
+		commentLikeSQL:      commentLikeSQL,
+		commentCache:        commentCache,
+		db:                  db,
+		lockManager:         lockManager,
+		rateLimiter:         rateLimiter,
+		mentionService:      mentionService,
+		notificationService: notificationService,
+		streamService:       streamService,
+		trendingService:     trendingService,
+		counterService:      counterService,
+		hotCommentsCache:    make(map[uint]*model.Comment),
+		hotCommentsTTL:      make(map[uint]time.Time),
 	}
 }
 
@@ -179,6 +241,124 @@ func (s *commentService) queryCommentWithUser(ctx context.Context, commentID uin
 	return &comment, nil
 }
 
+// hotScoreEpoch 热度计算的时间基准
+const hotScoreEpoch int64 = 1700000000
+
+// hotScore 计算评论热度
+// 采用Reddit式算法：点赞数取对数，发布时间线性加成，每12.5小时约等于点赞数增长10倍
+func hotScore(likes uint, createdAt time.Time) float64 {
+	order := math.Log10(math.Max(float64(likes), 1))
+	return order + float64(createdAt.Unix()-hotScoreEpoch)/45000
+}
+
+// parentIDOf 获取评论所属的热度排行范围（一级评论为0）
+func parentIDOf(c *model.Comment) uint {
+	if c.ParentID == nil {
+		return 0
+	}
+	return *c.ParentID
+}
+
+// updateHotScore 更新评论在Redis中的热度分数
+func (s *commentService) updateHotScore(ctx context.Context, c *model.Comment, likes uint) {
+	score := hotScore(likes, c.CreatedAt)
+	if err := s.commentCache.SetCommentHotScore(ctx, c.PostID, parentIDOf(c), c.ID, score); err != nil {
+		fmt.Printf("Redis评论热度更新失败: %v\n", err)
+	}
+}
+
+// commentOrder 获取排序方式对应的SQL排序语句
+func commentOrder(sortBy CommentSort) string {
+	switch sortBy {
+	case CommentSortOld:
+		return "created_at ASC"
+	case CommentSortTop, CommentSortHot:
+		return "like_count DESC, created_at DESC"
+	default:
+		return "created_at DESC"
+	}
+}
+
+// findComments 按排序方式分页查询评论
+// 热度排序从Redis有序集合读取，集合缺失或Redis不可用时重建或降级为点赞数排序
+func (s *commentService) findComments(ctx context.Context, postID, parentID uint, sortBy CommentSort, total int64, condition string, args []interface{}, offset, size int) ([]*model.Comment, error) {
+	preloadUser := func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, avatar_url")
+	}
+
+	if sortBy == CommentSortHot {
+		ids, err := s.rangeHotComments(ctx, postID, parentID, total, condition, args, offset, size)
+		if err == nil {
+			var comments []*model.Comment
+			if len(ids) > 0 {
+				err = s.db.WithContext(ctx).
+					Preload("User", preloadUser).
+					Where("id IN ?", ids).
+					Where(condition, args...).
+					Find(&comments).Error
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			// 按热度顺序重新排列
+			byID := make(map[uint]*model.Comment, len(comments))
+			for _, c := range comments {
+				byID[c.ID] = c
+			}
+			ordered := make([]*model.Comment, 0, len(comments))
+			for _, id := range ids {
+				if c, ok := byID[id]; ok {
+					ordered = append(ordered, c)
+				}
+			}
+			return ordered, nil
+		}
+		fmt.Printf("Redis评论热度读取失败，降级为点赞数排序: %v\n", err)
+	}
+
+	var comments []*model.Comment
+	err := s.db.WithContext(ctx).
+		Preload("User", preloadUser).
+		Where(condition, args...).
+		Order(commentOrder(sortBy)).
+		Limit(size).
+		Offset(offset).
+		Find(&comments).Error
+	return comments, err
+}
+
+// rangeHotComments 读取热度排行，排行数量与数据库不一致时从数据库重建
+func (s *commentService) rangeHotComments(ctx context.Context, postID, parentID uint, total int64, condition string, args []interface{}, offset, size int) ([]uint, error) {
+	count, err := s.commentCache.CountHotComments(ctx, postID, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if count != total {
+		lockKey := fmt.Sprintf("comment_hot_rebuild:%d:%d", postID, parentID)
+		err = s.lockManager.GetLock(lockKey, 10*time.Second).Mutex(ctx, func() error {
+			var comments []*model.Comment
+			if err := s.db.WithContext(ctx).
+				Select("id, post_id, parent_id, like_count, created_at").
+				Where(condition, args...).
+				Find(&comments).Error; err != nil {
+				return err
+			}
+			scores := make(map[uint]float64, len(comments))
+			for _, c := range comments {
+				scores[c.ID] = hotScore(c.LikeCount, c.CreatedAt)
+			}
+			return s.commentCache.ResetHotComments(ctx, postID, parentID, scores)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.commentCache.RangeHotComments(ctx, postID, parentID, offset, size)
+}
+
 func (s *commentService) getCurrentUser(ctx context.Context) (*model.User, error) {
 	userID, err := utils.GetCurrentUserIDFromContext(ctx)
 	if err != nil {
@@ -241,9 +421,11 @@ func (s *commentService) CreateComment(ctx context.Context, req *CreateCommentRe
 		return nil, fmt.Errorf("获取帖子失败: %w", err)
 	}
 
-	// 5. 创建评论对象
+	// 5. 解析@提及并创建评论对象
+	mentioned := s.mentionService.ResolveMentions(ctx, content)
 	comment := &model.Comment{
 		Content:   content,
+		Rendered:  mentionservice.RenderMentions(content, mentioned),
 		PostID:    req.PostID,
 		UserID:    currentUser.ID,
 		Status:    "published",
@@ -252,41 +434,58 @@ func (s *commentService) CreateComment(ctx context.Context, req *CreateCommentRe
 		UpdatedAt: time.Now(),
 	}
 
-	// 6. 使用分布式锁保护评论创建
-	lockKey := fmt.Sprintf("post_comment:%d", req.PostID)
-	var createdComment *model.Comment
+	// 6. 保存评论到数据库（帖子评论数由计数写缓冲累加，不需要加锁）
+	if err := s.commentSQL.InsertComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("保存评论失败: %w", err)
+	}
 
-	err = s.lockManager.GetLock(lockKey, 10*time.Second).Mutex(ctx, func() error {
-		// 保存评论到数据库
-		if err := s.commentSQL.InsertComment(ctx, comment); err != nil {
-			return fmt.Errorf("保存评论失败: %w", err)
-		}
+	// 更新帖子评论数
+	if err := s.counterService.Incr(ctx, req.PostID, counterservice.FieldComments, 1); err != nil {
+		return nil, fmt.Errorf("更新帖子评论数失败: %w", err)
+	}
 
-		// 更新帖子评论数
-		updates := map[string]interface{}{
-			"comment_numbers": post.CommentNumbers + 1,
-			"updated_at":      time.Now(),
-		}
-		if err := s.postSQL.UpdatePost(ctx, req.PostID, updates); err != nil {
-			return fmt.Errorf("更新帖子评论数失败: %w", err)
-		}
+	// 更新Redis缓存
+	if err := s.commentCache.IncrCommentCount(ctx, req.PostID); err != nil {
+		fmt.Printf("Redis评论数缓存失败: %v\n", err)
+	}
+	s.updateHotScore(ctx, comment, 0)
 
-		// 更新Redis缓存
-		if err := s.commentCache.IncrCommentCount(ctx, req.PostID); err != nil {
-			fmt.Printf("Redis评论数缓存失败: %v\n", err)
-		}
+	// 获取完整的评论信息
+	createdComment, err := s.getCommentWithUser(ctx, comment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取评论详情失败: %w", err)
+	}
 
-		// 获取完整的评论信息
-		createdComment, err = s.getCommentWithUser(ctx, comment.ID)
-		if err != nil {
-			return fmt.Errorf("获取评论详情失败: %w", err)
-		}
+	// 7. 保存提及记录并通知被提及用户
+	if err := s.mentionService.RecordMentions(ctx, &mentionservice.RecordMentionsRequest{
+		ActorID:    currentUser.ID,
+		TargetType: model.MentionTargetComment,
+		TargetID:   comment.ID,
+		PostID:     comment.PostID,
+		Users:      mentioned,
+	}); err != nil {
+		fmt.Printf("保存评论提及失败: %v\n", err)
+	}
 
-		return nil
-	})
+	// 8. 通知帖子作者
+	if err := s.notificationService.Notify(ctx, &notificationservice.NotificationEvent{
+		UserID:    post.UserID,
+		ActorID:   currentUser.ID,
+		Type:      model.NotificationTypeComment,
+		PostID:    post.ID,
+		CommentID: comment.ID,
+	}); err != nil {
+		fmt.Printf("创建评论通知失败: %v\n", err)
+	}
 
-	if err != nil {
-		return nil, err
+	// 9. 推送给正在浏览该帖子的客户端
+	if err := s.streamService.Publish(ctx, streamservice.EventComment, 0, post.ID, createdComment); err != nil {
+		fmt.Printf("推送新评论失败: %v\n", err)
+	}
+
+	// 10. 计入文章热度
+	if err := s.trendingService.Record(ctx, post.ID, trendingservice.EventComment); err != nil {
+		fmt.Printf("记录文章热度失败: %v\n", err)
 	}
 
 	return createdComment, nil
@@ -311,6 +510,14 @@ func (s *commentService) GetComment(ctx context.Context, id uint) (*model.Commen
 	if err != nil {
 		return nil, err
 	}
+
+	// 被举报隐藏的评论只有作者和管理员可见
+	if comment.Status != model.CommentStatusPublished {
+		currentUser, err := s.getCurrentUser(ctx)
+		if err != nil || (currentUser.ID != comment.UserID && currentUser.Relation != model.UserRoleAdmin) {
+			return nil, ErrCommentNotFound
+		}
+	}
 	return comment, nil
 }
 
@@ -328,7 +535,7 @@ func (s *commentService) DeleteComment(ctx context.Context, id uint) error {
 		return err
 	}
 
-	if comment.UserID != currentUser.ID {
+	if comment.UserID != currentUser.ID && currentUser.Relation != model.UserRoleAdmin {
 		return ErrUnauthorized
 	}
 
@@ -341,23 +548,8 @@ func (s *commentService) DeleteComment(ctx context.Context, id uint) error {
 			return fmt.Errorf("删除评论失败: %w", err)
 		}
 
-		// 更新帖子评论数
-		post, err := s.postSQL.GetPostByID(ctx, comment.PostID)
-		if err != nil {
-			return nil
-		}
-
-		newCount := uint(0)
-		if post.CommentNumbers > 0 {
-			newCount = post.CommentNumbers - 1
-		}
-
-		updates := map[string]interface{}{
-			"comment_numbers": newCount,
-			"updated_at":      time.Now(),
-		}
-
-		if err := s.postSQL.UpdatePost(ctx, comment.PostID, updates); err != nil {
+		// 更新帖子评论数（写入数据库时保证不小于0）
+		if err := s.counterService.Incr(ctx, comment.PostID, counterservice.FieldComments, -1); err != nil {
 			return fmt.Errorf("更新帖子评论数失败: %w", err)
 		}
 
@@ -371,6 +563,16 @@ func (s *commentService) DeleteComment(ctx context.Context, id uint) error {
 			fmt.Printf("Redis评论点赞缓存删除失败: %v\n", err)
 		}
 
+		// 从热度排行中移除
+		if err := s.commentCache.RemoveCommentHotScore(ctx, comment.PostID, parentIDOf(comment), id); err != nil {
+			fmt.Printf("Redis评论热度删除失败: %v\n", err)
+		}
+
+		// 删除提及记录
+		if err := s.mentionService.DeleteMentions(ctx, model.MentionTargetComment, id); err != nil {
+			fmt.Printf("删除评论提及失败: %v\n", err)
+		}
+
 		// 清除缓存
 		s.hotCommentLock.Lock()
 		delete(s.hotCommentsCache, id)
@@ -384,7 +586,7 @@ func (s *commentService) DeleteComment(ctx context.Context, id uint) error {
 }
 
 // ListCommentsByPost 获取帖子评论列表（带缓存和限流）
-func (s *commentService) ListCommentsByPost(ctx context.Context, postID uint, page, size int) ([]*model.Comment, int64, error) {
+func (s *commentService) ListCommentsByPost(ctx context.Context, postID uint, sortBy CommentSort, page, size int) ([]*model.Comment, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -427,16 +629,7 @@ func (s *commentService) ListCommentsByPost(ctx context.Context, postID uint, pa
 		return nil, 0, fmt.Errorf("获取评论总数失败: %w", err)
 	}
 
-	var comments []*model.Comment
-	err = s.db.WithContext(ctx).
-		Preload("User", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id,name,avatar_url")
-		}).
-		Where(condition, args...).
-		Order("created_at DESC").
-		Limit(size).
-		Offset(offset).
-		Find(&comments).Error
+	comments, err := s.findComments(ctx, post.ID, 0, sortBy, total, condition, args, offset, size)
 	if err != nil {
 		return nil, 0, fmt.Errorf("获取评论列表失败: %w", err)
 	}
@@ -621,6 +814,7 @@ func (s *commentService) LikeComment(ctx context.Context, commentID uint) error
 			if err := s.commentCache.LikeComment(ctx, currentUser.ID, commentID); err != nil {
 				fmt.Printf("Redis评论点赞缓存失败: %v\n", err)
 			}
+			s.updateHotScore(ctx, comment, comment.LikeCount+1)
 
 			// 清除缓存
 			s.hotCommentLock.Lock()
@@ -686,6 +880,9 @@ func (s *commentService) UnlikeComment(ctx context.Context, commentID uint) erro
 			if err := s.commentCache.UnlikeComment(ctx, currentuser.ID, commentID); err != nil {
 				fmt.Printf("Redis取消评论点赞缓存失败: %v\n", err)
 			}
+			if comment.LikeCount > 0 {
+				s.updateHotScore(ctx, comment, comment.LikeCount-1)
+			}
 
 			// 清除缓存
 			s.hotCommentLock.Lock()
@@ -781,9 +978,11 @@ func (s *commentService) CreateReply(ctx context.Context, req *CreateReplyReques
 		return nil, ErrReplyToNonexistentComment
 	}
 
-	// 创建回复
+	// 解析@提及并创建回复
+	mentioned := s.mentionService.ResolveMentions(ctx, content)
 	reply := &model.Comment{
 		Content:   content,
+		Rendered:  mentionservice.RenderMentions(content, mentioned),
 		PostID:    req.PostID,
 		ParentID:  &req.ParentID,
 		UserID:    currentUser.ID,
@@ -803,18 +1002,14 @@ func (s *commentService) CreateReply(ctx context.Context, req *CreateReplyReques
 			return fmt.Errorf("保存回复失败:%w", err)
 		}
 
-		updates := map[string]interface{}{
-			"comment_numbers": post.CommentNumbers + 1,
-			"updated_at":      time.Now(),
-		}
-
-		if err := s.postSQL.UpdatePost(ctx, req.PostID, updates); err != nil {
+		if err := s.counterService.Incr(ctx, req.PostID, counterservice.FieldComments, 1); err != nil {
 			return fmt.Errorf("更新帖子评论数失败:%w", err)
 		}
 
 		if err := s.commentCache.IncrCommentCount(ctx, req.PostID); err != nil {
 			return fmt.Errorf("评论数缓存失败:%w", err)
 		}
+		s.updateHotScore(ctx, reply, 0)
 
 		createdReply, err = s.getCommentWithUser(ctx, reply.ID)
 		if err != nil {
@@ -834,11 +1029,43 @@ func (s *commentService) CreateReply(ctx context.Context, req *CreateReplyReques
 		return nil, err
 	}
 
+	// 保存提及记录并通知被提及用户
+	if err := s.mentionService.RecordMentions(ctx, &mentionservice.RecordMentionsRequest{
+		ActorID:    currentUser.ID,
+		TargetType: model.MentionTargetComment,
+		TargetID:   reply.ID,
+		PostID:     reply.PostID,
+		Users:      mentioned,
+	}); err != nil {
+		fmt.Printf("保存回复提及失败: %v\n", err)
+	}
+
+	// 通知被回复的评论作者
+	if err := s.notificationService.Notify(ctx, &notificationservice.NotificationEvent{
+		UserID:    parentComment.UserID,
+		ActorID:   currentUser.ID,
+		Type:      model.NotificationTypeReply,
+		PostID:    post.ID,
+		CommentID: reply.ID,
+	}); err != nil {
+		fmt.Printf("创建回复通知失败: %v\n", err)
+	}
+
+	// 推送给正在浏览该帖子的客户端
+	if err := s.streamService.Publish(ctx, streamservice.EventComment, 0, post.ID, createdReply); err != nil {
+		fmt.Printf("推送新回复失败: %v\n", err)
+	}
+
+	// 计入文章热度
+	if err := s.trendingService.Record(ctx, post.ID, trendingservice.EventComment); err != nil {
+		fmt.Printf("记录文章热度失败: %v\n", err)
+	}
+
 	return createdReply, nil
 }
 
 // ListReplies 获取评论回复列表（带缓存和限流）
-func (s *commentService) ListReplies(ctx context.Context, commentID uint, page, size int) ([]*model.Comment, int64, error) {
+func (s *commentService) ListReplies(ctx context.Context, commentID uint, sortBy CommentSort, page, size int) ([]*model.Comment, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -861,7 +1088,7 @@ func (s *commentService) ListReplies(ctx context.Context, commentID uint, page,
 	offset := (page - 1) * size
 
 	// 检查上级评论
-	_, err := s.commentSQL.GetCommentByID(ctx, commentID)
+	parent, err := s.commentSQL.GetCommentByID(ctx, commentID)
 	if err != nil {
 		return nil, 0, ErrCommentNotFound
 	}
@@ -877,16 +1104,9 @@ func (s *commentService) ListReplies(ctx context.Context, commentID uint, page,
 	}
 
 	// 获取回复列表
-	var replies []*model.Comment
-	err = s.db.WithContext(ctx).
-		Preload("User", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id,name,avatar_url")
-		}).
-		Where("parent_id = ? AND status = 'published'", commentID).
-		Order("created_at ASC").
-		Limit(size).
-		Offset(offset).
-		Find(&replies).Error
+	condition := "parent_id = ? AND status = 'published'"
+	args := []interface{}{commentID}
+	replies, err := s.findComments(ctx, parent.PostID, commentID, sortBy, total, condition, args, offset, size)
 	if err != nil {
 		return nil, 0, fmt.Errorf("获取回复列表失败：%w", err)
 	}
@@ -912,3 +1132,33 @@ func (s *commentService) ListReplies(ctx context.Context, commentID uint, page,
 
 	return replies, total, nil
 }
+
+// SetCommentStatus 更新评论状态（用于举报审核隐藏/恢复），hiddenBy 为隐藏来源
+func (s *commentService) SetCommentStatus(ctx context.Context, commentID uint, status, hiddenBy string) error {
+	comment, err := s.commentSQL.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return ErrCommentNotFound
+	}
+
+	if status != model.CommentStatusHidden {
+		hiddenBy = ""
+	}
+	if err := s.commentSQL.UpdateComment(ctx, commentID, map[string]interface{}{"status": status, "hidden_by": hiddenBy}); err != nil {
+		return fmt.Errorf("更新评论状态失败: %w", err)
+	}
+
+	// 隐藏的评论移出热度排行，恢复时重新加入
+	if status == model.CommentStatusPublished {
+		s.updateHotScore(ctx, comment, comment.LikeCount)
+	} else if err := s.commentCache.RemoveCommentHotScore(ctx, comment.PostID, parentIDOf(comment), commentID); err != nil {
+		fmt.Printf("Redis评论热度删除失败: %v\n", err)
+	}
+
+	// 清除缓存
+	s.hotCommentLock.Lock()
+	delete(s.hotCommentsCache, commentID)
+	delete(s.hotCommentsTTL, commentID)
+	s.hotCommentLock.Unlock()
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	redis "blog/dao/redis"
+	"blog/model"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Field 文章计数（posts 表中的列）
+type Field string
+
+const (
+	FieldLikes     Field = "liketimes"
+	FieldStars     Field = "staredtimes"
+	FieldComments  Field = "comment_numbers"
+	FieldViews     Field = "clicktimes"
+	FieldPageViews Field = "page_views"
+)
+
+// fields 允许写入的计数列（列名会拼进SQL，只接受这些）
+var fields = map[Field]bool{
+	FieldLikes:     true,
+	FieldStars:     true,
+	FieldComments:  true,
+	FieldViews:     true,
+	FieldPageViews: true,
+}
+
+const (
+	// 默认写入间隔
+	defaultFlushInterval = 5 * time.Second
+	// 每个事务写入的文章数
+	flushBatchSize = 100
+	// 写入锁的有效期（写入期间自动续期），多个实例同时只有一个在写入
+	flushLockTTL = time.Minute
+	// 退出前写入剩余增量的超时时间
+	drainTimeout = 30 * time.Second
+)
+
+type CounterService interface {
+	// Incr 累加文章计数：先写Redis，由后台定时批量写入数据库（Redis不可用时直接写数据库）
+	Incr(ctx context.Context, postID uint, field Field, delta int64) error
+	// Pending 尚未写入数据库的增量，读取计数时加上才是最新值
+	Pending(ctx context.Context, postID uint) (map[Field]int64, error)
+
+	// Flush 把所有待写入的增量写入数据库，返回写入的文章数
+	Flush(ctx context.Context) (int, error)
+	// Run 启动时及之后每隔 interval 写入一次；ctx 结束后写入剩余的增量再返回
+	Run(ctx context.Context, interval time.Duration) error
+}
+
+type counterService struct {
+	// 数据库
+	db *gorm.DB
+
+	// 计数写缓冲
+	counterCache redis.CounterCache
+
+	// 分布式锁管理器
+	lockManager *utils.LockManager
+}
+
+func NewCounterService(
+	db *gorm.DB,
+	counterCache redis.CounterCache,
+	lockManager *utils.LockManager,
+) CounterService {
+	return &counterService{
+		db:           db,
+		counterCache: counterCache,
+		lockManager:  lockManager,
+	}
+}
+
+// Incr 累加计数
+func (s *counterService) Incr(ctx context.Context, postID uint, field Field, delta int64) error {
+	if !fields[field] {
+		return fmt.Errorf("未知的文章计数: %s", field)
+	}
+	if delta == 0 {
+		return nil
+	}
+
+	if err := s.counterCache.IncrCounter(ctx, postID, string(field), delta); err != nil {
+		fmt.Printf("Redis计数缓存失败，直接写入数据库: %v\n", err)
+		return applyCounters(s.db.WithContext(ctx), postID, map[string]int64{string(field): delta})
+	}
+	return nil
+}
+
+// Pending 待写入的增量
+func (s *counterService) Pending(ctx context.Context, postID uint) (map[Field]int64, error) {
+	counters, err := s.counterCache.PendingCounters(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[Field]int64, len(counters))
+	for field, delta := range counters {
+		if fields[Field(field)] {
+			pending[Field(field)] = delta
+		}
+	}
+	return pending, nil
+}
+
+// Flush 分批取出增量，每批在一个事务中写入后再确认；
+// 写入失败的增量留在Redis中下次重试，确认前进程退出则重启后重新写入（可能重复计入这一批）
+// 写入期间锁自动续期，写入较慢时其他实例也不会取出同一批增量重复写入
+func (s *counterService) Flush(ctx context.Context) (int, error) {
+	var flushed int
+	err := s.lockManager.GetLock("counter_flush", flushLockTTL).Mutex(ctx, func() error {
+		ids, err := s.counterCache.DirtyCounterPosts(ctx)
+		if err != nil {
+			return fmt.Errorf("读取待写入计数失败: %w", err)
+		}
+
+		for start := 0; start < len(ids); start += flushBatchSize {
+			batch := ids[start:min(start+flushBatchSize, len(ids))]
+
+			claimed := make(map[uint]map[string]int64, len(batch))
+			for _, id := range batch {
+				counters, err := s.counterCache.ClaimCounters(ctx, id)
+				if err != nil {
+					return fmt.Errorf("取出待写入计数失败: %w", err)
+				}
+				claimed[id] = counters
+			}
+
+			err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for id, counters := range claimed {
+					if err := applyCounters(tx, id, counters); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := s.counterCache.AckCounters(ctx, batch); err != nil {
+				return fmt.Errorf("确认计数写入失败: %w", err)
+			}
+			flushed += len(claimed)
+		}
+		return nil
+	}, utils.WithAutoRenew(0))
+	return flushed, err
+}
+
+// Run 定时写入
+func (s *counterService) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// 其他实例正在写入时跳过本次
+		if _, err := s.Flush(ctx); err != nil && !errors.Is(err, utils.ErrLockNotAcquired) && ctx.Err() == nil {
+			fmt.Printf("写入文章计数失败: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return s.drain()
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain 退出前写入剩余的增量（其他实例正在写入时由它们写入）
+func (s *counterService) drain() error {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if _, err := s.Flush(ctx); err != nil && !errors.Is(err, utils.ErrLockNotAcquired) {
+		return fmt.Errorf("写入剩余的文章计数失败: %w", err)
+	}
+	return nil
+}
+
+// applyCounters 把增量加到文章的计数上（减少时不小于0），不修改 updated_at
+func applyCounters(db *gorm.DB, postID uint, counters map[string]int64) error {
+	updates := make(map[string]interface{}, len(counters))
+	for field, delta := range counters {
+		if !fields[Field(field)] || delta == 0 {
+			continue
+		}
+		if delta > 0 {
+			updates[field] = gorm.Expr(field+" + ?", delta)
+		} else {
+			updates[field] = gorm.Expr("CASE WHEN "+field+" > ? THEN "+field+" - ? ELSE 0 END", -delta, -delta)
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := db.Model(&model.Post{}).Where("id = ?", postID).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("写入文章计数失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	"blog/model"
+	feedpkg "blog/pkg/feed"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFeedNotFound = errors.New("订阅源不存在")
+	ErrRateLimited  = errors.New("操作过于频繁，请稍后再试")
+)
+
+type FeedService interface {
+	// 全站订阅
+	SiteFeed(ctx context.Context, format string) (*feedpkg.Feed, error)
+
+	// 按分类/标签/作者订阅
+	CategoryFeed(ctx context.Context, format, slug string) (*feedpkg.Feed, error)
+	TagFeed(ctx context.Context, format, slug string) (*feedpkg.Feed, error)
+	AuthorFeed(ctx context.Context, format, username string) (*feedpkg.Feed, error)
+
+	// 根据已加载的文章（按发布时间倒序）生成订阅源，供静态导出使用，不限流
+	BuildSiteFeed(format string, posts []*model.Post) *feedpkg.Feed
+	BuildCategoryFeed(format string, category *model.Category, posts []*model.Post) *feedpkg.Feed
+	BuildTagFeed(format string, tag *model.Tag, posts []*model.Post) *feedpkg.Feed
+	BuildAuthorFeed(format string, user *model.User, posts []*model.Post) *feedpkg.Feed
+}
+
+// Options 订阅源配置
+type Options struct {
+	Title       string
+	Description string
+	BaseURL     string // 站点地址，用于生成文章链接
+	Language    string
+	Size        int  // 每个订阅源的文章数
+	FullContent bool // 输出全文，否则只输出摘要
+}
+
+type feedService struct {
+	categorySQL mysql.CategorySQL
+	tagSQL      mysql.TagSQL
+	userSQL     mysql.UserSQL
+
+	// 数据库
+	db *gorm.DB
+
+	// 限流器
+	rateLimiter *utils.RateLimiter
+
+	options Options
+}
+
+func NewFeedService(
+	categorySQL mysql.CategorySQL,
+	tagSQL mysql.TagSQL,
+	userSQL mysql.UserSQL,
+	db *gorm.DB,
+	rateLimiter *utils.RateLimiter,
+	options Options,
+) FeedService {
+	options.BaseURL = strings.TrimRight(options.BaseURL, "/")
+	if options.Size < 1 {
+		options.Size = 20
+	}
+	return &feedService{
+		categorySQL: categorySQL,
+		tagSQL:      tagSQL,
+		userSQL:     userSQL,
+		db:          db,
+		rateLimiter: rateLimiter,
+		options:     options,
+	}
+}
+
+// feedURL 订阅源自身地址，path为对应页面的路径
+func (s *feedService) feedURL(format, path string) string {
+	return s.options.BaseURL + strings.TrimRight(path, "/") + "/" + feedpkg.FileNames[format]
+}
+
+// allow 订阅源限流（阅读器会定时轮询）
+func (s *feedService) allow(ctx context.Context) error {
+	ip := utils.GetIPFromContext(ctx)
+	rateLimitKey := fmt.Sprintf("feed:ip:%s", ip)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Minute,
+		MaxRequests: 120,
+	}
+
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// SiteFeed 全站最新文章
+func (s *feedService) SiteFeed(ctx context.Context, format string) (*feedpkg.Feed, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	posts, err := s.recentPosts(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	return s.BuildSiteFeed(format, posts), nil
+}
+
+// CategoryFeed 分类下的最新文章
+func (s *feedService) CategoryFeed(ctx context.Context, format, slug string) (*feedpkg.Feed, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	category, err := s.categorySQL.GetCategoryBySlug(ctx, slug)
+	if err != nil || category == nil {
+		return nil, ErrFeedNotFound
+	}
+
+	posts, err := s.recentPosts(ctx, s.db.Where("category_id = ?", category.ID))
+	if err != nil {
+		return nil, err
+	}
+	return s.BuildCategoryFeed(format, category, posts), nil
+}
+
+// TagFeed 标签下的最新文章
+func (s *feedService) TagFeed(ctx context.Context, format, slug string) (*feedpkg.Feed, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.tagSQL.GetTagBySlug(ctx, slug)
+	if err != nil || tag == nil {
+		return nil, ErrFeedNotFound
+	}
+
+	posts, err := s.recentPosts(ctx, s.db.Where("id IN (?)",
+		s.db.Model(&model.PostTag{}).Select("post_id").Where("tag_id = ?", tag.ID)))
+	if err != nil {
+		return nil, err
+	}
+	return s.BuildTagFeed(format, tag, posts), nil
+}
+
+// AuthorFeed 作者的最新文章
+func (s *feedService) AuthorFeed(ctx context.Context, format, username string) (*feedpkg.Feed, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userSQL.GetUserByName(ctx, username)
+	if err != nil || user == nil || user.Status != model.UserStatusActive {
+		return nil, ErrFeedNotFound
+	}
+
+	posts, err := s.recentPosts(ctx, s.db.Where("user_id = ?", user.ID))
+	if err != nil {
+		return nil, err
+	}
+	return s.BuildAuthorFeed(format, user, posts), nil
+}
+
+// BuildSiteFeed 全站订阅源
+func (s *feedService) BuildSiteFeed(format string, posts []*model.Post) *feedpkg.Feed {
+	feed := &feedpkg.Feed{
+		Title:       s.options.Title,
+		Description: s.options.Description,
+		Link:        s.options.BaseURL + "/",
+		FeedURL:     s.feedURL(format, ""),
+	}
+	return s.fill(feed, posts)
+}
+
+// BuildCategoryFeed 分类订阅源
+func (s *feedService) BuildCategoryFeed(format string, category *model.Category, posts []*model.Post) *feedpkg.Feed {
+	feed := &feedpkg.Feed{
+		Title:       fmt.Sprintf("%s - 分类：%s", s.options.Title, category.Name),
+		Description: fmt.Sprintf("分类「%s」下的最新文章", category.Name),
+		Link:        s.options.BaseURL + utils.CategoryPath(category.Slug),
+		FeedURL:     s.feedURL(format, utils.CategoryPath(category.Slug)),
+	}
+	return s.fill(feed, posts)
+}
+
+// BuildTagFeed 标签订阅源
+func (s *feedService) BuildTagFeed(format string, tag *model.Tag, posts []*model.Post) *feedpkg.Feed {
+	feed := &feedpkg.Feed{
+		Title:       fmt.Sprintf("%s - 标签：%s", s.options.Title, tag.Name),
+		Description: fmt.Sprintf("标签「%s」下的最新文章", tag.Name),
+		Link:        s.options.BaseURL + utils.TagPath(tag.Slug),
+		FeedURL:     s.feedURL(format, utils.TagPath(tag.Slug)),
+	}
+	return s.fill(feed, posts)
+}
+
+// BuildAuthorFeed 作者订阅源
+func (s *feedService) BuildAuthorFeed(format string, user *model.User, posts []*model.Post) *feedpkg.Feed {
+	feed := &feedpkg.Feed{
+		Title:       fmt.Sprintf("%s - %s的文章", s.options.Title, user.Name),
+		Description: user.Bio,
+		Link:        s.options.BaseURL + utils.UserPath(user.Name),
+		FeedURL:     s.feedURL(format, utils.UserPath(user.Name)),
+	}
+	if feed.Description == "" {
+		feed.Description = fmt.Sprintf("%s的最新文章", user.Name)
+	}
+	return s.fill(feed, posts)
+}
+
+// recentPosts 最新的公开文章（与 ListPosts 的过滤条件一致）
+func (s *feedService) recentPosts(ctx context.Context, query *gorm.DB) ([]*model.Post, error) {
+	var posts []*model.Post
+	err := query.WithContext(ctx).
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name")
+		}).
+		Preload("Category").
+		Preload("Tags").
+		Preload("Cover").
+		Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false).
+		Order("created_at DESC").
+		Limit(s.options.Size).
+		Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取订阅文章失败: %w", err)
+	}
+	return posts, nil
+}
+
+// absURL 站内路径转为绝对地址（对象存储的地址本身就是绝对地址）
+func (s *feedService) absURL(path string) string {
+	if path == "" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return s.options.BaseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+// fill 填充订阅源的公共字段和条目，最多取前 Size 篇
+func (s *feedService) fill(feed *feedpkg.Feed, posts []*model.Post) *feedpkg.Feed {
+	if len(posts) > s.options.Size {
+		posts = posts[:s.options.Size]
+	}
+	feed.Language = s.options.Language
+	feed.Items = make([]*feedpkg.Item, 0, len(posts))
+
+	for _, post := range posts {
+		link := s.options.BaseURL + utils.PostPath(post.Slug)
+		item := &feedpkg.Item{
+			ID:        link,
+			Title:     post.Title,
+			Link:      link,
+			Summary:   utils.Summarize(post.Summary, post.Content),
+			Author:    post.AuthorName,
+			Published: post.CreatedAt,
+			Updated:   post.UpdatedAt,
+		}
+		if post.Author != nil && post.Author.Name != "" {
+			item.Author = post.Author.Name
+		}
+		if post.Cover != nil {
+			item.Image = &feedpkg.Image{URL: s.absURL(post.Cover.URL), Type: post.Cover.ContentType, Length: post.Cover.Size}
+		}
+		if post.Category != nil {
+			item.Categories = append(item.Categories, post.Category.Name)
+		}
+		for _, tag := range post.Tags {
+			item.Categories = append(item.Categories, tag.Name)
+		}
+		if s.options.FullContent {
+			item.Content = post.Rendered
+			if item.Content == "" {
+				item.Content = html.EscapeString(post.Content)
+			}
+		}
+		if post.UpdatedAt.After(feed.Updated) {
+			feed.Updated = post.UpdatedAt
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	return feed
+}
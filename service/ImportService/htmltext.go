@@ -0,0 +1,271 @@
+package service
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlToMarkdown 把 WordPress 的 HTML 正文/评论转换为 Markdown 文本，与其他来源导入的正文格式一致
+// 只保留段落、换行、标题、列表、引用、代码、链接和图片，其他标签只保留文字，脚本、样式等整个去掉
+// WordPress 经典编辑器的正文没有 <p>，用空行分段、单个换行表示 <br>，所以文字中的换行原样保留
+func htmlToMarkdown(s string) string {
+	body := &xhtml.Node{Type: xhtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := xhtml.ParseFragment(strings.NewReader(strings.ReplaceAll(s, "\r\n", "\n")), body)
+	if err != nil {
+		return strings.TrimSpace(stripTags(s))
+	}
+	var w markdownWriter
+	for _, n := range nodes {
+		w.node(n)
+	}
+	return cleanMarkdown(w.b.String())
+}
+
+// markdownWriter 逐个节点输出 Markdown
+type markdownWriter struct {
+	b     strings.Builder
+	lists []int // 嵌套的列表，有序列表为下一项的序号，无序列表为0
+}
+
+// 整个去掉的元素
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Head: true, atom.Title: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+}
+
+// 前后分段的元素
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Header: true,
+	atom.Footer: true, atom.Aside: true, atom.Nav: true, atom.Main: true, atom.Figure: true,
+	atom.Figcaption: true, atom.Table: true, atom.Dl: true, atom.Address: true, atom.Details: true,
+}
+
+// 只包含子元素的容器，其中的空白文字是源码的缩进，不是内容
+var containerElements = map[atom.Atom]bool{
+	atom.Ul: true, atom.Ol: true, atom.Table: true, atom.Thead: true, atom.Tbody: true,
+	atom.Tfoot: true, atom.Tr: true, atom.Dl: true,
+}
+
+func (w *markdownWriter) node(n *xhtml.Node) {
+	switch n.Type {
+	case xhtml.TextNode:
+		if strings.TrimSpace(n.Data) == "" && n.Parent != nil && containerElements[n.Parent.DataAtom] {
+			return
+		}
+		w.b.WriteString(n.Data)
+		return
+	case xhtml.ElementNode:
+	default:
+		// 注释（包括古腾堡编辑器的 <!-- wp:... --> 标记）、文档类型等
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.node(c)
+		}
+		return
+	}
+	if skippedElements[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		w.b.WriteString("\n")
+	case atom.Hr:
+		w.block()
+		w.b.WriteString("---")
+		w.block()
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.block()
+		level, _ := strconv.Atoi(n.Data[1:])
+		w.b.WriteString(strings.Repeat("#", level) + " " + strings.Join(strings.Fields(w.inline(n)), " "))
+		w.block()
+	case atom.Ul, atom.Ol:
+		if len(w.lists) == 0 {
+			w.block()
+		} else {
+			w.line()
+		}
+		next := 0
+		if n.DataAtom == atom.Ol {
+			next = 1
+			if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+				next = start
+			}
+		}
+		w.lists = append(w.lists, next)
+		w.children(n)
+		w.lists = w.lists[:len(w.lists)-1]
+		if len(w.lists) == 0 {
+			w.block()
+		} else {
+			w.line()
+		}
+	case atom.Li:
+		w.line()
+		marker := "- "
+		if depth := len(w.lists); depth > 0 {
+			w.b.WriteString(strings.Repeat("  ", depth-1))
+			if next := w.lists[depth-1]; next > 0 {
+				marker = strconv.Itoa(next) + ". "
+				w.lists[depth-1]++
+			}
+		}
+		w.b.WriteString(marker + strings.TrimSpace(w.inline(n)))
+		w.line()
+	case atom.Blockquote:
+		w.block()
+		var sub markdownWriter
+		sub.children(n)
+		lines := strings.Split(cleanMarkdown(sub.b.String()), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		w.b.WriteString(strings.Join(lines, "\n"))
+		w.block()
+	case atom.Pre:
+		w.block()
+		w.b.WriteString("```\n" + strings.Trim(textContent(n), "\n") + "\n```")
+		w.block()
+	case atom.Code, atom.Kbd, atom.Samp:
+		w.wrap(n, "`")
+	case atom.Strong, atom.B:
+		w.wrap(n, "**")
+	case atom.Em, atom.I:
+		w.wrap(n, "*")
+	case atom.Del, atom.S, atom.Strike:
+		w.wrap(n, "~~")
+	case atom.A:
+		text := strings.TrimSpace(w.inline(n))
+		href, ok := safeURL(attr(n, "href"))
+		switch {
+		case !ok:
+			w.b.WriteString(text)
+		case text == "" || text == href:
+			w.b.WriteString(href)
+		default:
+			w.b.WriteString("[" + text + "](" + href + ")")
+		}
+	case atom.Img:
+		if src, ok := safeURL(attr(n, "src")); ok {
+			w.b.WriteString("![" + strings.TrimSpace(attr(n, "alt")) + "](" + src + ")")
+		}
+	case atom.Tr:
+		w.line()
+		var cells []string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+				cells = append(cells, strings.Join(strings.Fields(w.inline(c)), " "))
+			}
+		}
+		w.b.WriteString(strings.Join(cells, " | "))
+		w.line()
+	default:
+		if blockElements[n.DataAtom] {
+			w.block()
+			w.children(n)
+			w.block()
+		} else {
+			w.children(n)
+		}
+	}
+}
+
+func (w *markdownWriter) children(n *xhtml.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+// inline 子节点转换后的文字，不写入输出
+func (w *markdownWriter) inline(n *xhtml.Node) string {
+	sub := markdownWriter{lists: append([]int(nil), w.lists...)}
+	sub.children(n)
+	return sub.b.String()
+}
+
+// wrap 用标记包住子节点的文字，没有文字时不加标记
+func (w *markdownWriter) wrap(n *xhtml.Node, mark string) {
+	text := w.inline(n)
+	if strings.TrimSpace(text) == "" {
+		w.b.WriteString(text)
+		return
+	}
+	w.b.WriteString(mark + text + mark)
+}
+
+// line 确保从新的一行开始
+func (w *markdownWriter) line() {
+	if s := w.b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		w.b.WriteString("\n")
+	}
+}
+
+// block 确保与前面的内容之间有空行
+func (w *markdownWriter) block() {
+	s := w.b.String()
+	switch {
+	case s == "" || strings.HasSuffix(s, "\n\n"):
+	case strings.HasSuffix(s, "\n"):
+		w.b.WriteString("\n")
+	default:
+		w.b.WriteString("\n\n")
+	}
+}
+
+func attr(n *xhtml.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textContent 元素中的全部文字（用于 <pre>）
+func textContent(n *xhtml.Node) string {
+	var b strings.Builder
+	var walk func(n *xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.DataAtom == atom.Br {
+			b.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// safeURL 只保留 http(s)、mailto 和相对地址，过滤 javascript: 等
+func safeURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if raw == "" || err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return raw, true
+	}
+	return "", false
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// cleanMarkdown 去掉行尾空白和多余的空行
+func cleanMarkdown(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\u00a0")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,752 @@
+package service
+
+import (
+	"blog/model"
+	blobpkg "blog/pkg/blob"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAuthorNotFound        = errors.New("导入文章的作者不存在")
+	ErrInvalidConflictPolicy = errors.New("无效的冲突处理方式")
+)
+
+// slug冲突处理方式
+const (
+	ConflictSkip   = "skip"   // 跳过与已有文章slug相同的文章
+	ConflictRename = "rename" // 在slug后追加序号
+)
+
+// 导入计划中每篇文章的处理方式
+const (
+	ActionCreate = "create"
+	ActionRename = "rename"
+	ActionSkip   = "skip"
+	ActionError  = "error"
+)
+
+type ImportService interface {
+	// ImportMarkdown 从 Hexo/Jekyll/Hugo 的 Markdown 目录导入文章
+	ImportMarkdown(ctx context.Context, req *MarkdownImportRequest) (*ImportReport, error)
+	// ImportWordPress 从 WordPress 导出的 WXR 文件导入用户、文章、分类、标签和评论
+	ImportWordPress(ctx context.Context, req *WordPressImportRequest) (*ImportReport, error)
+}
+
+// Options 导入配置
+type Options struct {
+	Uploads blobpkg.Store // 图片保存到 import/{slug}/ 下
+}
+
+// ImportRequest 各来源通用的导入参数
+type ImportRequest struct {
+	Author          string // 导入文章的作者（用户名），来源中带作者时只用于找不到作者的文章
+	DefaultCategory string // 没有分类的文章归入此分类（不存在时创建）
+	OnConflict      string // slug已存在时的处理方式，默认跳过
+	DryRun          bool   // 只生成报告，不写入数据库和上传目录
+}
+
+// ImportReport 导入报告（试运行时为导入计划）
+type ImportReport struct {
+	DryRun        bool
+	Items         []*ImportItem
+	NewUsers      []string
+	NewCategories []string
+	NewTags       []string
+
+	Created int
+	Skipped int
+	Failed  int
+
+	// 评论
+	Comments        int
+	CommentsSkipped int
+}
+
+// ImportItem 单篇文章的导入结果
+type ImportItem struct {
+	Source   string // 来源文件或条目
+	Title    string
+	Slug     string
+	Date     time.Time
+	Draft    bool
+	Action   string
+	Redirect string   // 旧链接路径，导入后跳转到新文章
+	Problems []string // 冲突、错误和警告
+}
+
+func (item *ImportItem) problem(format string, args ...interface{}) {
+	item.Problems = append(item.Problems, fmt.Sprintf(format, args...))
+}
+
+// importBatch 一次导入的内容（与来源格式无关）
+type importBatch struct {
+	// 来源标识，非空时记录导入的ID，重复导入时跳过
+	source string
+	posts  []*importPost
+}
+
+// importUser 来源中的用户
+type importUser struct {
+	externalID string
+	user       *model.User
+}
+
+// importPost 待导入的文章
+type importPost struct {
+	item *ImportItem
+
+	externalID string
+	existingID uint        // 已导入过的文章ID，只补充新评论
+	author     *importUser // 为nil时使用导入参数中的作者
+	comments   []*importComment
+
+	title     string
+	slug      string
+	summary   string
+	content   string
+	createdAt time.Time
+	updatedAt time.Time
+	category  string
+	tags      []string
+	draft     bool
+
+	// 图片链接对应的本地文件，找不到时返回false；为nil时不处理图片
+	resolveImage func(link string) (string, bool)
+	// 需要复制的图片：本地文件 => 上传路径
+	images map[string]string
+}
+
+// importComment 待导入的评论，按时间顺序排列（父评论在前）
+type importComment struct {
+	externalID string
+	parentID   string // 来源中父评论的ID，空为顶级评论
+	imported   bool   // 已导入过
+	user       *importUser
+	content    string
+	status     string
+	createdAt  time.Time
+}
+
+type importService struct {
+	// 数据库
+	db *gorm.DB
+
+	options Options
+}
+
+func NewImportService(db *gorm.DB, options Options) ImportService {
+	return &importService{
+		db:      db,
+		options: options,
+	}
+}
+
+// importPlan 导入计划
+type importPlan struct {
+	source     string
+	users      []*importUser              // 待创建的用户
+	categories map[string]*model.Category // 名称 => 分类（新分类ID为0）
+	tags       map[string]*model.Tag
+	posts      []*importPost // 待创建的文章
+	existing   []*importPost // 已导入过的文章（只导入新评论）
+}
+
+// run 规划并（非试运行时）执行导入
+func (s *importService) run(ctx context.Context, req *ImportRequest, batch *importBatch) (*ImportReport, error) {
+	if req.OnConflict == "" {
+		req.OnConflict = ConflictSkip
+	}
+	if req.OnConflict != ConflictSkip && req.OnConflict != ConflictRename {
+		return nil, ErrInvalidConflictPolicy
+	}
+
+	var author *importUser
+	if req.Author != "" {
+		var user model.User
+		if err := s.db.WithContext(ctx).Where("name = ?", req.Author).First(&user).Error; err != nil {
+			return nil, ErrAuthorNotFound
+		}
+		author = &importUser{user: &user}
+	}
+
+	plan, report, err := s.plan(ctx, req, author, batch)
+	if err != nil || req.DryRun {
+		return report, err
+	}
+
+	if err := s.commit(ctx, plan); err != nil {
+		return nil, err
+	}
+	s.copyImages(ctx, plan)
+	return report, nil
+}
+
+// plan 检查slug冲突、确定要新建的分类和标签，并改写图片链接
+func (s *importService) plan(ctx context.Context, req *ImportRequest, author *importUser, batch *importBatch) (*importPlan, *ImportReport, error) {
+	plan := &importPlan{
+		source:     batch.source,
+		categories: make(map[string]*model.Category),
+		tags:       make(map[string]*model.Tag),
+	}
+	report := &ImportReport{DryRun: req.DryRun}
+	posts := batch.posts
+
+	// 已有的slug（数据库和本批次中）
+	var slugs []string
+	for _, p := range posts {
+		if p.item.Action == ActionCreate && p.existingID == 0 {
+			slugs = append(slugs, p.slug)
+		}
+	}
+	// 之前导入的文章可能已被删除
+	var importedIDs []uint
+	for _, p := range posts {
+		if p.existingID != 0 {
+			importedIDs = append(importedIDs, p.existingID)
+		}
+	}
+	stillExists := make(map[uint]bool)
+	if len(importedIDs) > 0 {
+		var ids []uint
+		if err := s.db.WithContext(ctx).Model(&model.Post{}).Where("id IN ?", importedIDs).Pluck("id", &ids).Error; err != nil {
+			return nil, nil, fmt.Errorf("检查已导入的文章失败: %w", err)
+		}
+		for _, id := range ids {
+			stillExists[id] = true
+		}
+	}
+
+	taken := make(map[string]bool)
+	if len(slugs) > 0 {
+		var existing []string
+		if err := s.db.WithContext(ctx).Model(&model.Post{}).Where("slug IN ?", slugs).Pluck("slug", &existing).Error; err != nil {
+			return nil, nil, fmt.Errorf("检查slug失败: %w", err)
+		}
+		for _, slug := range existing {
+			taken[slug] = true
+		}
+	}
+
+	for _, p := range posts {
+		report.Items = append(report.Items, p.item)
+		if p.item.Action == ActionError {
+			report.Failed++
+			continue
+		}
+		if p.item.Action == ActionSkip {
+			report.Skipped++
+			continue
+		}
+
+		if p.existingID != 0 {
+			p.item.Action = ActionSkip
+			report.Skipped++
+			if !stillExists[p.existingID] {
+				p.item.problem("已导入过（文章ID %d），但文章已被删除，不再导入其评论", p.existingID)
+				continue
+			}
+			p.item.problem("已导入过（文章ID %d）", p.existingID)
+			plan.existing = append(plan.existing, p)
+			continue
+		}
+
+		if p.author == nil {
+			p.author = author
+		}
+		if p.author == nil {
+			p.item.problem("找不到作者，且未指定默认作者")
+			p.item.Action = ActionError
+			report.Failed++
+			continue
+		}
+
+		if taken[p.slug] {
+			if req.OnConflict == ConflictSkip {
+				p.item.problem("slug %q 已存在", p.slug)
+				p.item.Action = ActionSkip
+				report.Skipped++
+				continue
+			}
+			slug, err := s.uniqueSlug(ctx, p.slug, taken)
+			if err != nil {
+				return nil, nil, err
+			}
+			p.item.problem("slug %q 已存在，改为 %q", p.slug, slug)
+			p.item.Action = ActionRename
+			p.slug = slug
+		}
+		taken[p.slug] = true
+		p.item.Slug = p.slug
+
+		if p.category == "" {
+			p.category = req.DefaultCategory
+		}
+		if p.category == "" {
+			p.item.problem("没有分类，且未指定默认分类")
+			p.item.Action = ActionError
+			report.Failed++
+			continue
+		}
+
+		s.rewriteImages(p)
+		plan.posts = append(plan.posts, p)
+		report.Created++
+	}
+
+	// 评论：跳过已导入的，作者为新用户时一并创建
+	newUsers := make(map[*importUser]bool)
+	addUser := func(u *importUser) {
+		if u != nil && u.user.ID == 0 && !newUsers[u] {
+			newUsers[u] = true
+			plan.users = append(plan.users, u)
+			report.NewUsers = append(report.NewUsers, u.user.Name)
+		}
+	}
+	for _, p := range plan.posts {
+		addUser(p.author)
+	}
+	// 已导入过的文章不再创建，作者可能无法解析（当时使用了默认作者），只处理评论的作者
+	for _, p := range append(append([]*importPost{}, plan.posts...), plan.existing...) {
+		for _, c := range p.comments {
+			if c.imported {
+				report.CommentsSkipped++
+				continue
+			}
+			addUser(c.user)
+			report.Comments++
+		}
+	}
+
+	// 分类和标签：按名称匹配已有的，其余新建
+	for _, p := range plan.posts {
+		if _, ok := plan.categories[p.category]; !ok {
+			category, err := s.findCategory(ctx, p.category)
+			if err != nil {
+				return nil, nil, err
+			}
+			if category.ID == 0 {
+				report.NewCategories = append(report.NewCategories, p.category)
+			}
+			plan.categories[p.category] = category
+		}
+		for _, name := range p.tags {
+			if _, ok := plan.tags[name]; ok {
+				continue
+			}
+			tag, err := s.findTag(ctx, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			if tag.ID == 0 {
+				report.NewTags = append(report.NewTags, name)
+			}
+			plan.tags[name] = tag
+		}
+	}
+
+	return plan, report, nil
+}
+
+// uniqueSlug 追加序号直到slug未被占用
+func (s *importService) uniqueSlug(ctx context.Context, slug string, taken map[string]bool) (string, error) {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", slug, i)
+		if taken[candidate] {
+			continue
+		}
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&model.Post{}).Where("slug = ?", candidate).Count(&count).Error; err != nil {
+			return "", fmt.Errorf("检查slug失败: %w", err)
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		taken[candidate] = true
+	}
+}
+
+// findCategory 按名称查找分类，不存在时返回待创建的分类
+func (s *importService) findCategory(ctx context.Context, name string) (*model.Category, error) {
+	var category model.Category
+	err := s.db.WithContext(ctx).Where("name = ?", name).First(&category).Error
+	if err == nil {
+		return &category, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询分类失败: %w", err)
+	}
+	return &model.Category{Name: name, Slug: utils.GenerateSlug(name)}, nil
+}
+
+// findTag 按名称查找标签，不存在时返回待创建的标签
+func (s *importService) findTag(ctx context.Context, name string) (*model.Tag, error) {
+	var tag model.Tag
+	err := s.db.WithContext(ctx).Where("name = ?", name).First(&tag).Error
+	if err == nil {
+		return &tag, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询标签失败: %w", err)
+	}
+	return &model.Tag{Name: name, Slug: utils.GenerateSlug(name)}, nil
+}
+
+// commit 在一个事务中创建用户、分类、标签、文章和评论，任何一步失败都不会留下部分数据
+func (s *importService) commit(ctx context.Context, plan *importPlan) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, u := range plan.users {
+			if err := createWithUnique(tx, u.user, "name", &u.user.Name, &model.User{}); err != nil {
+				return fmt.Errorf("创建用户「%s」失败: %w", u.user.Name, err)
+			}
+			if err := s.record(tx, plan.source, recordUser, u.externalID, u.user.ID); err != nil {
+				return err
+			}
+		}
+		for _, category := range plan.categories {
+			if category.ID != 0 {
+				continue
+			}
+			if err := createWithUnique(tx, category, "slug", &category.Slug, &model.Category{}); err != nil {
+				return fmt.Errorf("创建分类「%s」失败: %w", category.Name, err)
+			}
+		}
+		for _, tag := range plan.tags {
+			if tag.ID != 0 {
+				continue
+			}
+			if err := createWithUnique(tx, tag, "slug", &tag.Slug, &model.Tag{}); err != nil {
+				return fmt.Errorf("创建标签「%s」失败: %w", tag.Name, err)
+			}
+		}
+
+		for _, p := range plan.posts {
+			visibility := model.VisibilityPublic
+			if p.draft {
+				visibility = model.VisibilityPrivate
+			}
+			post := &model.Post{
+				Title:      p.title,
+				Slug:       p.slug,
+				Summary:    p.summary,
+				Content:    p.content,
+				Rendered:   html.EscapeString(p.content),
+				UserID:     p.author.user.ID,
+				AuthorName: p.author.user.Name,
+				CategoryID: plan.categories[p.category].ID,
+				Visibility: visibility,
+				CreatedAt:  p.createdAt,
+				UpdatedAt:  p.updatedAt,
+			}
+			if err := tx.Create(post).Error; err != nil {
+				return fmt.Errorf("导入 %s 失败: %w", p.item.Source, err)
+			}
+			p.existingID = post.ID
+			if err := s.record(tx, plan.source, recordPost, p.externalID, post.ID); err != nil {
+				return err
+			}
+
+			seen := make(map[uint]bool)
+			for _, name := range p.tags {
+				tagID := plan.tags[name].ID
+				if seen[tagID] {
+					continue
+				}
+				seen[tagID] = true
+				if err := tx.Create(&model.PostTag{PostID: post.ID, TagID: tagID, CreatedAt: time.Now()}).Error; err != nil {
+					return fmt.Errorf("关联标签失败: %w", err)
+				}
+			}
+
+			if p.item.Redirect != "" {
+				if err := saveRedirect(tx, p.item.Redirect, utils.PostPath(post.Slug)); err != nil {
+					return fmt.Errorf("保存旧链接跳转失败: %w", err)
+				}
+			}
+		}
+
+		for _, p := range append(append([]*importPost{}, plan.posts...), plan.existing...) {
+			if err := s.commitComments(tx, plan.source, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// commitComments 创建文章的评论，保留回复层级，并更新文章评论数
+func (s *importService) commitComments(tx *gorm.DB, source string, p *importPost) error {
+	created := make(map[string]*model.Comment)
+	count := 0
+	for _, c := range p.comments {
+		if c.imported {
+			continue
+		}
+
+		comment := &model.Comment{
+			Content:   c.content,
+			Rendered:  utils.RenderMentions(c.content, nil),
+			PostID:    p.existingID,
+			UserID:    c.user.user.ID,
+			Status:    c.status,
+			HiddenBy:  hiddenBy(c.status),
+			CreatedAt: c.createdAt,
+			UpdatedAt: c.createdAt,
+		}
+		if c.parentID != "" {
+			parent, err := s.findComment(tx, source, c.parentID, created)
+			if err != nil {
+				return err
+			}
+			if parent != nil {
+				comment.ParentID = &parent.ID
+				comment.Level = parent.Level + 1
+			} else {
+				p.item.problem("评论 %s 的父评论 %s 不存在，作为顶级评论导入", c.externalID, c.parentID)
+			}
+		}
+
+		if err := tx.Create(comment).Error; err != nil {
+			return fmt.Errorf("导入 %s 的评论 %s 失败: %w", p.item.Source, c.externalID, err)
+		}
+		if err := s.record(tx, source, recordComment, c.externalID, comment.ID); err != nil {
+			return err
+		}
+		created[c.externalID] = comment
+		count++
+	}
+
+	if count == 0 {
+		return nil
+	}
+	err := tx.Model(&model.Post{}).Where("id = ?", p.existingID).
+		UpdateColumn("comment_numbers", gorm.Expr("comment_numbers + ?", count)).Error
+	if err != nil {
+		return fmt.Errorf("更新帖子评论数失败: %w", err)
+	}
+	return nil
+}
+
+// hiddenBy 来源中未通过审核的评论按管理员隐藏导入，驳回举报时不会被恢复
+func hiddenBy(status string) string {
+	if status == model.CommentStatusHidden {
+		return model.HiddenByAdmin
+	}
+	return ""
+}
+
+// findComment 查找父评论：本次导入的或之前导入过的
+func (s *importService) findComment(tx *gorm.DB, source, externalID string, created map[string]*model.Comment) (*model.Comment, error) {
+	if comment, ok := created[externalID]; ok {
+		return comment, nil
+	}
+	if source == "" {
+		return nil, nil
+	}
+
+	var record model.ImportRecord
+	err := tx.Where("source = ? AND kind = ? AND external_id = ?", source, recordComment, externalID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询导入记录失败: %w", err)
+	}
+
+	var comment model.Comment
+	err = tx.Select("id", "level").First(&comment, record.LocalID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询父评论失败: %w", err)
+	}
+	return &comment, nil
+}
+
+// 导入记录的类型
+const (
+	recordUser    = "user"
+	recordPost    = "post"
+	recordComment = "comment"
+)
+
+// record 记录来源ID对应的本地ID（没有来源标识时不记录）
+func (s *importService) record(tx *gorm.DB, source, kind, externalID string, localID uint) error {
+	if source == "" || externalID == "" {
+		return nil
+	}
+	err := tx.Create(&model.ImportRecord{Source: source, Kind: kind, ExternalID: externalID, LocalID: localID}).Error
+	if err != nil {
+		return fmt.Errorf("保存导入记录失败: %w", err)
+	}
+	return nil
+}
+
+// importedIDs 来源中已导入的ID => 本地ID
+func (s *importService) importedIDs(ctx context.Context, source, kind string) (map[string]uint, error) {
+	var records []model.ImportRecord
+	err := s.db.WithContext(ctx).Where("source = ? AND kind = ?", source, kind).Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询导入记录失败: %w", err)
+	}
+	ids := make(map[string]uint, len(records))
+	for _, r := range records {
+		ids[r.ExternalID] = r.LocalID
+	}
+	return ids, nil
+}
+
+// saveRedirect 保存旧链接跳转，已存在时更新目标
+func saveRedirect(tx *gorm.DB, from, to string) error {
+	var redirect model.Redirect
+	err := tx.Where("path = ?", from).First(&redirect).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&model.Redirect{Path: from, Target: to}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&redirect).Update("target", to).Error
+}
+
+// createWithUnique 创建用户/分类/标签，唯一字段被占用时追加序号
+func createWithUnique(tx *gorm.DB, value interface{}, column string, field *string, table interface{}) error {
+	base := *field
+	for i := 2; ; i++ {
+		var count int64
+		if err := tx.Model(table).Where(column+" = ?", *field).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			break
+		}
+		*field = fmt.Sprintf("%s-%d", base, i)
+	}
+	return tx.Create(value).Error
+}
+
+// rewriteImages 把正文中的本地图片链接改为上传目录下的地址
+func (s *importService) rewriteImages(p *importPost) {
+	if p.resolveImage == nil {
+		return
+	}
+
+	p.images = make(map[string]string)
+	names := make(map[string]bool)
+	p.content = rewriteImageLinks(p.content, func(link string) string {
+		if !isLocalLink(link) {
+			return link
+		}
+		src, ok := p.resolveImage(link)
+		if !ok {
+			p.item.problem("图片不存在: %s", link)
+			return link
+		}
+
+		dest, ok := p.images[src]
+		if !ok {
+			// 同一篇文章中不同目录下的同名图片加序号区分
+			name := filepath.Base(src)
+			for i := 2; names[name]; i++ {
+				name = strconv.Itoa(i) + "-" + filepath.Base(src)
+			}
+			names[name] = true
+			dest = path.Join("import", p.slug, name)
+			p.images[src] = dest
+		}
+		return s.options.Uploads.URL(dest)
+	})
+}
+
+// copyImages 复制图片到上传存储（数据库已提交，失败时只打印日志）
+func (s *importService) copyImages(ctx context.Context, plan *importPlan) {
+	for _, p := range plan.posts {
+		for src, dest := range p.images {
+			if err := s.uploadFile(ctx, src, dest); err != nil {
+				fmt.Printf("复制图片失败: %s: %v\n", src, err)
+			}
+		}
+	}
+}
+
+func (s *importService) uploadFile(ctx context.Context, src, key string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return s.options.Uploads.Put(ctx, key, f, info.Size(), mime.TypeByExtension(filepath.Ext(src)))
+}
+
+// Print 输出文本格式的报告
+func (r *ImportReport) Print(w io.Writer) {
+	if r.DryRun {
+		fmt.Fprintln(w, "导入计划（试运行，未写入任何数据）：")
+	} else {
+		fmt.Fprintln(w, "导入结果：")
+	}
+
+	for _, item := range r.Items {
+		date := ""
+		if !item.Date.IsZero() {
+			date = item.Date.Format("2006-01-02")
+		}
+		draft := ""
+		if item.Draft {
+			draft = " [草稿]"
+		}
+		fmt.Fprintf(w, "  %-6s %s  %s  %s (%s)%s\n", item.Action, date, item.Source, item.Title, item.Slug, draft)
+		if item.Redirect != "" {
+			fmt.Fprintf(w, "         旧链接 %s\n", item.Redirect)
+		}
+		for _, problem := range item.Problems {
+			fmt.Fprintf(w, "         ! %s\n", problem)
+		}
+	}
+
+	if len(r.NewUsers) > 0 {
+		fmt.Fprintf(w, "新建用户: %s\n", strings.Join(r.NewUsers, ", "))
+	}
+	if len(r.NewCategories) > 0 {
+		fmt.Fprintf(w, "新建分类: %s\n", strings.Join(r.NewCategories, ", "))
+	}
+	if len(r.NewTags) > 0 {
+		fmt.Fprintf(w, "新建标签: %s\n", strings.Join(r.NewTags, ", "))
+	}
+	fmt.Fprintf(w, "共%d篇：导入%d篇，跳过%d篇，失败%d篇\n", len(r.Items), r.Created, r.Skipped, r.Failed)
+	if r.Comments > 0 || r.CommentsSkipped > 0 {
+		fmt.Fprintf(w, "评论：导入%d条，已导入过%d条\n", r.Comments, r.CommentsSkipped)
+	}
+}
+
+// WriteRedirects 输出本次导入文章的旧链接与新地址的对应（每行：旧路径<TAB>新路径），可用于配置反向代理的跳转
+func (r *ImportReport) WriteRedirects(w io.Writer) error {
+	for _, item := range r.Items {
+		if item.Redirect == "" || (item.Action != ActionCreate && item.Action != ActionRename) {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", item.Redirect, utils.PostPath(item.Slug)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
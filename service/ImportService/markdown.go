@@ -0,0 +1,234 @@
+package service
+
+import (
+	frontmatter "blog/pkg/frontmatter"
+	"blog/utils"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MarkdownImportRequest Markdown目录导入参数
+type MarkdownImportRequest struct {
+	ImportRequest
+
+	// Markdown文件所在目录（会递归查找 .md/.markdown 文件）
+	Dir string
+	// 以 / 开头的图片链接相对的站点资源目录（如 Hexo 的 source），默认为 Dir
+	AssetsDir string
+	// 没有时区的日期按此时区解析，默认本地时区
+	Location *time.Location
+}
+
+// Jekyll 文件名：2006-01-02-slug.md
+var jekyllName = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+
+// Hexo 摘要分隔符
+const moreMarker = "<!-- more -->"
+
+// ImportMarkdown 从 Markdown 目录导入文章
+func (s *importService) ImportMarkdown(ctx context.Context, req *MarkdownImportRequest) (*ImportReport, error) {
+	if req.AssetsDir == "" {
+		req.AssetsDir = req.Dir
+	}
+	if req.Location == nil {
+		req.Location = time.Local
+	}
+
+	var files []string
+	err := filepath.WalkDir(req.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		// Hugo 的 _index.md 是栏目页，不是文章
+		if strings.HasPrefix(d.Name(), "_index.") {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".md", ".markdown":
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取导入目录失败: %w", err)
+	}
+	sort.Strings(files)
+
+	posts := make([]*importPost, 0, len(files))
+	for _, file := range files {
+		posts = append(posts, s.parseMarkdown(req, file))
+	}
+	return s.run(ctx, &req.ImportRequest, &importBatch{posts: posts})
+}
+
+// parseMarkdown 解析单个 Markdown 文件，错误记录在报告条目中
+func (s *importService) parseMarkdown(req *MarkdownImportRequest, file string) *importPost {
+	rel, _ := filepath.Rel(req.Dir, file)
+	p := &importPost{item: &ImportItem{Source: filepath.ToSlash(rel), Action: ActionCreate}}
+
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		p.item.problem("读取失败: %v", err)
+		p.item.Action = ActionError
+		return p
+	}
+	_, meta, body, err := frontmatter.Split(raw)
+	if err != nil {
+		p.item.problem("%v", err)
+		p.item.Action = ActionError
+		return p
+	}
+
+	// 文件名：Jekyll 带日期前缀，Hugo 页面包用目录名
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if strings.EqualFold(name, "index") {
+		name = filepath.Base(filepath.Dir(file))
+	}
+	var nameDate string
+	if m := jekyllName.FindStringSubmatch(name); m != nil {
+		nameDate, name = m[1], m[2]
+	}
+
+	p.title = frontmatter.String(meta, "title")
+	if p.title == "" {
+		p.title = name
+		p.item.problem("没有标题，使用文件名")
+	}
+	p.item.Title = p.title
+
+	p.slug = utils.SanitizeSlug(frontmatter.String(meta, "slug"))
+	if p.slug == "" {
+		p.slug = utils.SanitizeSlug(name)
+	}
+	if p.slug == "" {
+		p.slug = utils.GenerateSlug(p.title)
+	}
+	p.item.Slug = p.slug
+
+	p.createdAt, err = frontmatter.Time(meta, req.Location, "date", "publishDate", "created")
+	if err != nil {
+		p.item.problem("%v", err)
+	}
+	if p.createdAt.IsZero() && nameDate != "" {
+		p.createdAt, _ = time.ParseInLocation("2006-01-02", nameDate, req.Location)
+	}
+	if p.createdAt.IsZero() {
+		info, _ := os.Stat(file)
+		p.createdAt = info.ModTime()
+		p.item.problem("没有日期，使用文件修改时间")
+	}
+	p.updatedAt, err = frontmatter.Time(meta, req.Location, "updated", "lastmod", "last_modified_at", "modified")
+	if err != nil {
+		p.item.problem("%v", err)
+	}
+	if p.updatedAt.Before(p.createdAt) {
+		p.updatedAt = p.createdAt
+	}
+	p.item.Date = p.createdAt
+
+	// 只支持一个分类，多个（或 Hexo 多级分类）时取第一个
+	categories := frontmatter.Strings(meta, "categories", "category")
+	if len(categories) > 0 {
+		p.category = categories[0]
+		if len(categories) > 1 {
+			p.item.problem("有多个分类，只使用「%s」", p.category)
+		}
+	}
+	p.tags = frontmatter.Strings(meta, "tags", "tag")
+
+	// Hugo 的 draft、Jekyll 的 published: false、Hexo 的 _drafts 目录
+	p.draft = frontmatter.Bool(meta, false, "draft") || !frontmatter.Bool(meta, true, "published") ||
+		strings.Contains(filepath.ToSlash(rel), "_drafts/")
+	p.item.Draft = p.draft
+
+	p.content = strings.TrimSpace(string(body))
+	p.summary = frontmatter.String(meta, "summary", "description", "excerpt")
+	if p.summary == "" {
+		if i := strings.Index(p.content, moreMarker); i >= 0 {
+			p.summary = utils.Summarize("", p.content[:i])
+		} else {
+			p.summary = utils.Summarize("", p.content)
+		}
+	}
+
+	// 图片：相对文件所在目录、Hexo 资源文件夹（与文件同名的目录），以 / 开头的相对资源目录
+	dir := filepath.Dir(file)
+	assetDir := strings.TrimSuffix(file, filepath.Ext(file))
+	p.resolveImage = func(link string) (string, bool) {
+		link = cleanLink(link)
+		var candidates []string
+		if strings.HasPrefix(link, "/") {
+			candidates = []string{filepath.Join(req.AssetsDir, filepath.FromSlash(link))}
+		} else {
+			candidates = []string{
+				filepath.Join(dir, filepath.FromSlash(link)),
+				filepath.Join(assetDir, filepath.FromSlash(link)),
+			}
+		}
+		for _, candidate := range candidates {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate, true
+			}
+		}
+		return "", false
+	}
+	return p
+}
+
+var (
+	// ![alt](link "title")
+	markdownImage = regexp.MustCompile(`(!\[[^\]]*\]\(\s*)<?([^)\s>]+)>?`)
+	// <img src="link">
+	htmlImage = regexp.MustCompile(`(<img\s[^>]*?src\s*=\s*["'])([^"']+)`)
+	// Hexo：{% asset_img name.png 标题 %}
+	hexoAssetImage = regexp.MustCompile(`\{%\s*asset_img\s+(\S+)\s*(.*?)\s*%\}`)
+)
+
+// rewriteImageLinks 用 rewrite 替换正文中的图片地址
+func rewriteImageLinks(content string, rewrite func(link string) string) string {
+	content = hexoAssetImage.ReplaceAllStringFunc(content, func(match string) string {
+		m := hexoAssetImage.FindStringSubmatch(match)
+		return fmt.Sprintf("![%s](%s)", m[2], m[1])
+	})
+	for _, re := range []*regexp.Regexp{markdownImage, htmlImage} {
+		re := re
+		content = re.ReplaceAllStringFunc(content, func(match string) string {
+			m := re.FindStringSubmatch(match)
+			return m[1] + rewrite(m[2])
+		})
+	}
+	return content
+}
+
+// isLocalLink 是否为本地文件链接（不是外部地址、锚点或 data URI）
+func isLocalLink(link string) bool {
+	switch {
+	case link == "", strings.HasPrefix(link, "#"), strings.HasPrefix(link, "//"), strings.HasPrefix(link, "data:"):
+		return false
+	case strings.Contains(link, "://"):
+		return false
+	}
+	return true
+}
+
+// cleanLink 去掉查询参数和锚点，并解码路径
+func cleanLink(link string) string {
+	if i := strings.IndexAny(link, "?#"); i >= 0 {
+		link = link[:i]
+	}
+	if unescaped, err := url.PathUnescape(link); err == nil {
+		link = unescaped
+	}
+	return link
+}
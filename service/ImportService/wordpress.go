@@ -0,0 +1,479 @@
+package service
+
+import (
+	"blog/model"
+	"blog/utils"
+	"context"
+	"crypto/sha1"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WordPressImportRequest WordPress 导入参数
+type WordPressImportRequest struct {
+	ImportRequest
+
+	// WordPress 后台「工具 → 导出」得到的 WXR 文件
+	File string
+	// 没有GMT时间的日期（如草稿）按此时区解析，默认本地时区
+	Location *time.Location
+}
+
+// WXR 文件结构（只包含用到的字段，元素按本地名匹配，兼容各版本的命名空间）
+type wxrFile struct {
+	Channel struct {
+		Link        string      `xml:"link"`
+		BaseSiteURL string      `xml:"base_site_url"`
+		Authors     []wxrAuthor `xml:"author"`
+		Items       []wxrItem   `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrAuthor struct {
+	ID    string `xml:"author_id"`
+	Login string `xml:"author_login"`
+	Email string `xml:"author_email"`
+}
+
+type wxrItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Creator     string        `xml:"creator"`
+	Encoded     []wxrEncoded  `xml:"encoded"`
+	ID          string        `xml:"post_id"`
+	Date        string        `xml:"post_date"`
+	DateGMT     string        `xml:"post_date_gmt"`
+	Modified    string        `xml:"post_modified"`
+	ModifiedGMT string        `xml:"post_modified_gmt"`
+	Name        string        `xml:"post_name"`
+	Status      string        `xml:"status"`
+	Type        string        `xml:"post_type"`
+	Password    string        `xml:"post_password"`
+	Categories  []wxrCategory `xml:"category"`
+	Comments    []wxrComment  `xml:"comment"`
+}
+
+// wxrEncoded content:encoded 和 excerpt:encoded 同名，按命名空间区分
+type wxrEncoded struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type wxrCategory struct {
+	Domain string `xml:"domain,attr"`
+	Name   string `xml:",chardata"`
+}
+
+type wxrComment struct {
+	ID          string `xml:"comment_id"`
+	Author      string `xml:"comment_author"`
+	AuthorEmail string `xml:"comment_author_email"`
+	Date        string `xml:"comment_date"`
+	DateGMT     string `xml:"comment_date_gmt"`
+	Content     string `xml:"comment_content"`
+	Approved    string `xml:"comment_approved"`
+	Type        string `xml:"comment_type"`
+	Parent      string `xml:"comment_parent"`
+	UserID      string `xml:"comment_user_id"`
+}
+
+// content 正文和摘要
+func (item *wxrItem) content() (content, excerpt string) {
+	for _, e := range item.Encoded {
+		if strings.Contains(e.XMLName.Space, "excerpt") {
+			excerpt = e.Value
+		} else {
+			content = e.Value
+		}
+	}
+	return content, excerpt
+}
+
+// wordpressImport 一次 WordPress 导入的上下文
+type wordpressImport struct {
+	s   *importService
+	ctx context.Context
+	req *WordPressImportRequest
+
+	source   string
+	imported map[string]map[string]uint // 类型 => 来源ID => 本地ID
+
+	byAuthor  map[string]*importUser // WordPress 用户ID => 用户
+	byLogin   map[string]*importUser
+	byEmail   map[string]*importUser
+	commenter map[string]*importUser // 匿名评论者（邮箱或昵称）=> 用户
+}
+
+// ImportWordPress 从 WordPress 导出的 WXR 文件导入
+// 重复导入同一站点时跳过已导入的用户、文章和评论，只补充新增的内容
+func (s *importService) ImportWordPress(ctx context.Context, req *WordPressImportRequest) (*ImportReport, error) {
+	if req.Location == nil {
+		req.Location = time.Local
+	}
+
+	file, err := os.Open(req.File)
+	if err != nil {
+		return nil, fmt.Errorf("读取导入文件失败: %w", err)
+	}
+	defer file.Close()
+
+	var doc wxrFile
+	dec := xml.NewDecoder(file)
+	dec.Entity = xml.HTMLEntity
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析WXR文件失败: %w", err)
+	}
+
+	w := &wordpressImport{
+		s:         s,
+		ctx:       ctx,
+		req:       req,
+		source:    wordpressSource(doc.Channel.BaseSiteURL, doc.Channel.Link),
+		imported:  make(map[string]map[string]uint),
+		byAuthor:  make(map[string]*importUser),
+		byLogin:   make(map[string]*importUser),
+		byEmail:   make(map[string]*importUser),
+		commenter: make(map[string]*importUser),
+	}
+	for _, kind := range []string{recordUser, recordPost, recordComment} {
+		if w.imported[kind], err = s.importedIDs(ctx, w.source, kind); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range doc.Channel.Authors {
+		if err := w.addAuthor(a); err != nil {
+			return nil, err
+		}
+	}
+
+	var posts []*importPost
+	for i := range doc.Channel.Items {
+		p, err := w.parseItem(&doc.Channel.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			posts = append(posts, p)
+		}
+	}
+	return s.run(ctx, &req.ImportRequest, &importBatch{source: w.source, posts: posts})
+}
+
+// wordpressSource 导入记录中的来源标识，区分不同的 WordPress 站点
+func wordpressSource(links ...string) string {
+	for _, link := range links {
+		if u, err := url.Parse(strings.TrimSpace(link)); err == nil && u.Host != "" {
+			return "wordpress:" + strings.ToLower(u.Host) + strings.TrimSuffix(u.Path, "/")
+		}
+	}
+	return "wordpress"
+}
+
+// findUser 按导入记录或邮箱查找已有用户，都没有时返回nil
+func (w *wordpressImport) findUser(externalID, email string) (*model.User, error) {
+	db := w.s.db.WithContext(w.ctx)
+	if id, ok := w.imported[recordUser][externalID]; ok {
+		var user model.User
+		err := db.First(&user, id).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("查询用户失败: %w", err)
+		}
+		// 导入后被删除的用户重新创建
+		delete(w.imported[recordUser], externalID)
+	}
+	if email == "" {
+		return nil, nil
+	}
+
+	var user model.User
+	err := db.Where("email = ?", email).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	return nil, nil
+}
+
+// addUser 匹配已有用户或准备新建用户
+func (w *wordpressImport) addUser(externalID, name, email string, status model.UserStatus, role model.UserRole) (*importUser, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if u, ok := w.byEmail[email]; ok && email != "" {
+		return u, nil
+	}
+
+	user, err := w.findUser(externalID, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if email == "" {
+			// 邮箱必填且唯一，没有邮箱的评论者用昵称生成一个无效地址
+			email = fmt.Sprintf("%x@import.invalid", sha1.Sum([]byte(w.source+"\x00"+name)))
+		}
+		user = &model.User{
+			Name:     name,
+			Email:    email,
+			Password: model.LockedPassword,
+			Status:   status,
+			Relation: role,
+		}
+	}
+
+	u := &importUser{externalID: externalID, user: user}
+	if email != "" {
+		w.byEmail[email] = u
+	}
+	return u, nil
+}
+
+// addAuthor 导入 WordPress 用户（文章作者）
+func (w *wordpressImport) addAuthor(a wxrAuthor) error {
+	login := strings.TrimSpace(a.Login)
+	if login == "" {
+		return nil
+	}
+	u, err := w.addUser(a.ID, truncateName(login), a.Email, model.UserStatusActive, model.UserRoleUser)
+	if err != nil {
+		return err
+	}
+	w.byLogin[login] = u
+	if a.ID != "" {
+		w.byAuthor[a.ID] = u
+	}
+	return nil
+}
+
+// commentUser 评论者：登录用户对应导入的作者，匿名评论者按邮箱（没有时按昵称）合并为访客账号
+func (w *wordpressImport) commentUser(c *wxrComment) (*importUser, error) {
+	if u, ok := w.byAuthor[c.UserID]; ok && c.UserID != "0" {
+		return u, nil
+	}
+
+	name := truncateName(strings.TrimSpace(html.UnescapeString(c.Author)))
+	if name == "" {
+		name = "访客"
+	}
+	key := strings.ToLower(strings.TrimSpace(c.AuthorEmail))
+	if key == "" {
+		key = "name:" + name
+	}
+	if u, ok := w.commenter[key]; ok {
+		return u, nil
+	}
+
+	u, err := w.addUser("commenter:"+key, name, c.AuthorEmail, model.UserStatusInactive, model.UserRoleGuest)
+	if err != nil {
+		return nil, err
+	}
+	w.commenter[key] = u
+	return u, nil
+}
+
+// truncateName 用户名最长100个字符
+func truncateName(name string) string {
+	if runes := []rune(name); len(runes) > 100 {
+		return string(runes[:100])
+	}
+	return name
+}
+
+// parseItem 解析一个条目，只导入文章（post），页面等其他类型返回nil或跳过
+func (w *wordpressImport) parseItem(it *wxrItem) (*importPost, error) {
+	if it.Type != "post" && it.Type != "page" {
+		return nil, nil
+	}
+	if it.Status == "auto-draft" || it.Status == "inherit" {
+		return nil, nil
+	}
+
+	p := &importPost{
+		item:       &ImportItem{Source: fmt.Sprintf("%s #%s", it.Type, it.ID), Action: ActionCreate},
+		externalID: it.ID,
+	}
+	p.title = strings.TrimSpace(html.UnescapeString(it.Title))
+	p.item.Title = p.title
+
+	switch {
+	case it.Type == "page":
+		p.item.problem("页面不导入")
+		p.item.Action = ActionSkip
+		return p, nil
+	case it.Status == "trash":
+		p.item.problem("在回收站中，不导入")
+		p.item.Action = ActionSkip
+		return p, nil
+	}
+
+	if id, ok := w.imported[recordPost][it.ID]; ok {
+		p.existingID = id
+	}
+
+	name, _ := url.PathUnescape(it.Name)
+	p.slug = utils.SanitizeSlug(name)
+	if p.slug == "" {
+		p.slug = utils.GenerateSlug(p.title)
+	}
+	if p.slug == "" {
+		p.slug = "post-" + it.ID
+	}
+	p.item.Slug = p.slug
+	if p.title == "" {
+		p.title = p.slug
+		p.item.problem("没有标题，使用slug")
+	}
+
+	p.author = w.byLogin[strings.TrimSpace(it.Creator)]
+	if p.author == nil {
+		p.item.problem("作者 %q 不在导出的用户中", it.Creator)
+	}
+
+	p.createdAt = w.parseDate(it.DateGMT, it.Date)
+	if p.createdAt.IsZero() {
+		p.createdAt = time.Now()
+		p.item.problem("没有日期，使用当前时间")
+	}
+	p.updatedAt = w.parseDate(it.ModifiedGMT, it.Modified)
+	if p.updatedAt.Before(p.createdAt) {
+		p.updatedAt = p.createdAt
+	}
+	p.item.Date = p.createdAt
+
+	// 已发布的公开，草稿、待审、定时发布和私密文章导入为私密
+	switch it.Status {
+	case "publish":
+	case "future":
+		p.draft = true
+		p.item.problem("定时发布的文章，导入为私密")
+	default:
+		p.draft = true
+	}
+	if it.Password != "" {
+		p.draft = true
+		p.item.problem("有访问密码，导入为私密")
+	}
+	p.item.Draft = p.draft
+
+	for _, c := range it.Categories {
+		name := strings.TrimSpace(html.UnescapeString(c.Name))
+		if name == "" {
+			continue
+		}
+		switch c.Domain {
+		case "category":
+			if p.category == "" {
+				p.category = name
+			} else {
+				p.item.problem("有多个分类，只使用「%s」", p.category)
+			}
+		case "post_tag":
+			p.tags = append(p.tags, name)
+		}
+	}
+
+	// 正文是 HTML，转换为 Markdown 后与其他来源的文章一样按文本保存和显示
+	content, excerpt := it.content()
+	p.content = htmlToMarkdown(content)
+	p.summary = utils.Summarize(htmlToMarkdown(excerpt), p.content)
+
+	// 旧链接：只记录路径形式的固定链接（?p=123 这种默认链接无法按路径跳转）
+	if u, err := url.Parse(strings.TrimSpace(it.Link)); err == nil && u.Path != "" && u.Path != "/" {
+		p.item.Redirect = utils.RedirectPath(u.Path)
+	}
+
+	if err := w.parseComments(p, it.Comments); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseComments 解析文章的评论：跳过 pingback/trackback、垃圾和回收站中的评论，待审核的导入为隐藏
+func (w *wordpressImport) parseComments(p *importPost, comments []wxrComment) error {
+	for i := range comments {
+		c := &comments[i]
+		if c.Type != "" && c.Type != "comment" {
+			continue
+		}
+		var status string
+		switch c.Approved {
+		case "1":
+			status = model.CommentStatusPublished
+		case "0":
+			status = model.CommentStatusHidden
+		default:
+			continue
+		}
+		content := htmlToMarkdown(c.Content)
+		if content == "" {
+			continue
+		}
+
+		user, err := w.commentUser(c)
+		if err != nil {
+			return err
+		}
+		comment := &importComment{
+			externalID: c.ID,
+			user:       user,
+			content:    content,
+			status:     status,
+			createdAt:  w.parseDate(c.DateGMT, c.Date),
+		}
+		if comment.createdAt.IsZero() {
+			comment.createdAt = p.createdAt
+		}
+		if c.Parent != "" && c.Parent != "0" {
+			comment.parentID = c.Parent
+		}
+		_, comment.imported = w.imported[recordComment][c.ID]
+		p.comments = append(p.comments, comment)
+	}
+
+	// 父评论在前：按时间排序，同一时间按ID
+	sort.SliceStable(p.comments, func(i, j int) bool {
+		a, b := p.comments[i], p.comments[j]
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.Before(b.createdAt)
+		}
+		x, _ := strconv.Atoi(a.externalID)
+		y, _ := strconv.Atoi(b.externalID)
+		return x < y
+	})
+	return nil
+}
+
+// parseDate 优先使用GMT时间，草稿等没有GMT时间时按本地时间解析
+func (w *wordpressImport) parseDate(gmt, local string) time.Time {
+	const layout = "2006-01-02 15:04:05"
+	// 草稿的GMT时间为 0000-00-00 00:00:00，解析会失败
+	if t, err := time.ParseInLocation(layout, strings.TrimSpace(gmt), time.UTC); err == nil {
+		return t
+	}
+	if t, err := time.ParseInLocation(layout, strings.TrimSpace(local), w.req.Location); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags 去掉HTML标签（HTML 无法解析时使用）
+func stripTags(s string) string {
+	return htmlTag.ReplaceAllString(s, " ")
+}
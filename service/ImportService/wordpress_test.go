@@ -0,0 +1,79 @@
+package service
+
+import (
+	"blog/model"
+	"context"
+	"html"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestImportWordPressRendersHTMLAsText(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := model.AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewImportService(db, Options{})
+	report, err := s.ImportWordPress(context.Background(), &WordPressImportRequest{File: "testdata/wordpress.xml"})
+	if err != nil {
+		t.Fatalf("ImportWordPress: %v", err)
+	}
+	if report.Created != 1 || report.Comments != 1 {
+		t.Fatalf("created %d posts and %d comments, want 1 and 1: %+v", report.Created, report.Comments, report.Items[0])
+	}
+
+	var post model.Post
+	if err := db.Where("slug = ?", "hello").First(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	wantContent := strings.Join([]string{
+		"第一段，**加粗**和[链接](https://example.com/a?x=1&y=2)。",
+		"",
+		"## 小标题",
+		"",
+		"- 一",
+		"- 二 *斜体*",
+		"",
+		"![猫](https://wp.example.com/wp-content/uploads/cat.jpg)危险链接",
+		"",
+		"```",
+		"if a < b {",
+		"\treturn",
+		"}",
+		"```",
+		"",
+		"经典编辑器的段落",
+		"第二行",
+		"",
+		"最后一段 & 符号",
+	}, "\n")
+	if post.Content != wantContent {
+		t.Errorf("Content =\n%s\nwant\n%s", post.Content, wantContent)
+	}
+	if want := html.EscapeString(wantContent); post.Rendered != want {
+		t.Errorf("Rendered =\n%s\nwant\n%s", post.Rendered, want)
+	}
+	if post.Summary != "手写的**摘要**" {
+		t.Errorf("Summary = %q", post.Summary)
+	}
+
+	var comment model.Comment
+	if err := db.Where("post_id = ?", post.ID).First(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+	wantComment := "写得好 **赞**\n[我的博客](https://guest.example.com)"
+	if comment.Content != wantComment {
+		t.Errorf("comment Content = %q, want %q", comment.Content, wantComment)
+	}
+	if want := html.EscapeString(wantComment); comment.Rendered != want {
+		t.Errorf("comment Rendered = %q, want %q", comment.Rendered, want)
+	}
+}
@@ -0,0 +1,615 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	"blog/model"
+	blobpkg "blog/pkg/blob"
+	"blog/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	imagingpkg "blog/pkg/imaging"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrMediaNotFound   = errors.New("文件不存在")
+	ErrMediaInUse      = errors.New("文件正在被文章使用，不能删除")
+	ErrEmptyFile       = errors.New("文件不能为空")
+	ErrFileTooLarge    = errors.New("文件过大")
+	ErrUnsupportedFile = errors.New("不支持的文件类型")
+	ErrQuotaExceeded   = errors.New("存储空间不足")
+	ErrRateLimited     = errors.New("操作过于频繁，请稍后再试")
+	ErrNotImage        = errors.New("封面必须是图片")
+)
+
+// allowedTypes 允许上传的文件类型（按内容识别）及默认扩展名
+// HTML、SVG 等可能包含脚本的类型不允许，避免在站点域名下执行
+var allowedTypes = map[string]string{
+	"image/jpeg":         ".jpg",
+	"image/png":          ".png",
+	"image/gif":          ".gif",
+	"image/webp":         ".webp",
+	"application/pdf":    ".pdf",
+	"application/zip":    ".zip",
+	"application/x-gzip": ".gz",
+	"text/plain":         ".txt",
+	"audio/mpeg":         ".mp3",
+	"audio/wave":         ".wav",
+	"video/mp4":          ".mp4",
+	"video/webm":         ".webm",
+}
+
+const (
+	// 未配置时单个文件的大小上限
+	defaultMaxFileSize = 20 << 20
+	// 每篇文章最多记录的引用文件数
+	maxPostReferences = 200
+	// 缩小版本的 JPEG 质量
+	variantQuality = 82
+	// 占位图宽度，显示时由浏览器放大并模糊
+	placeholderWidth = 16
+)
+
+// 未配置时图片生成的缩小版本宽度
+var defaultImageWidths = []int{320, 640, 1024, 1600}
+
+// Options 媒体库配置
+type Options struct {
+	// 单个文件的大小上限（字节）
+	MaxFileSize int64
+	// 每个用户的存储配额（字节），0表示不限制；管理员不受限制
+	Quota int64
+	// 图片生成的缩小版本宽度（用于 srcset），只生成小于原图宽度的版本
+	ImageWidths []int
+}
+
+// MediaUsage 用户的存储用量
+type MediaUsage struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"` // 0表示不限制
+	Files int64 `json:"files"`
+}
+
+type MediaService interface {
+	// 媒体库
+	Upload(ctx context.Context, userID uint, fileName string, r io.Reader, size int64) (*model.Media, error)
+	ListMedia(ctx context.Context, userID uint, page, size int) ([]*model.Media, int64, error)
+	GetMedia(ctx context.Context, userID, id uint) (*model.Media, error)
+	DeleteMedia(ctx context.Context, userID, id uint) error
+	GetUsage(ctx context.Context, userID uint) (*MediaUsage, error)
+
+	// 文章封面：只能使用作者自己媒体库中的图片
+	GetCover(ctx context.Context, authorID, id uint) (*model.Media, error)
+
+	// 文章引用：文章保存时按内容中的文件地址和封面更新，删除时清除
+	SyncPostReferences(ctx context.Context, postID, authorID uint, content string, coverID *uint) error
+	DeletePostReferences(ctx context.Context, postID uint) error
+}
+
+type mediaService struct {
+	mediaSQL mysql.MediaSQL
+	postSQL  mysql.PostSQL
+	userSQL  mysql.UserSQL
+
+	// 数据库（分页查询）
+	db *gorm.DB
+
+	// 上传文件存储
+	uploads blobpkg.Store
+
+	// 分布式锁管理器
+	lockManager *utils.LockManager
+
+	// 限流器
+	rateLimiter *utils.RateLimiter
+
+	options Options
+
+	// 内容中媒体文件地址的匹配规则
+	urlPattern *regexp.Regexp
+}
+
+func NewMediaService(
+	mediaSQL mysql.MediaSQL,
+	postSQL mysql.PostSQL,
+	userSQL mysql.UserSQL,
+	db *gorm.DB,
+	uploads blobpkg.Store,
+	lockManager *utils.LockManager,
+	rateLimiter *utils.RateLimiter,
+	options Options,
+) MediaService {
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = defaultMaxFileSize
+	}
+	if len(options.ImageWidths) == 0 {
+		options.ImageWidths = defaultImageWidths
+	}
+	return &mediaService{
+		mediaSQL:    mediaSQL,
+		postSQL:     postSQL,
+		userSQL:     userSQL,
+		db:          db,
+		uploads:     uploads,
+		lockManager: lockManager,
+		rateLimiter: rateLimiter,
+		options:     options,
+		// 相对地址和带域名的绝对地址都能匹配
+		urlPattern: regexp.MustCompile(regexp.QuoteMeta(uploads.URL("media/")) + `[0-9a-f]{2}/([0-9a-f]{64})(?:_w[0-9]+)?\.[0-9a-z]+`),
+	}
+}
+
+// mediaKey 按内容哈希生成存储key：media/ab/abcdef....jpg
+func mediaKey(hash, ext string) string {
+	return "media/" + hash[:2] + "/" + hash + ext
+}
+
+// variantKey 图片缩小版本的存储key：media/ab/abcdef..._w640.jpg
+func variantKey(hash string, width int, ext string) string {
+	return fmt.Sprintf("media/%s/%s_w%d%s", hash[:2], hash, width, ext)
+}
+
+// detectType 按内容识别文件类型，返回 Content-Type 和扩展名
+// 文件名的扩展名与识别结果一致时保留（如 .jpeg），否则使用默认扩展名
+func detectType(data []byte, fileName string) (string, string, error) {
+	contentType := http.DetectContentType(data)
+	base, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", "", ErrUnsupportedFile
+	}
+	ext, ok := allowedTypes[base]
+	if !ok {
+		return "", "", ErrUnsupportedFile
+	}
+
+	if fileExt := strings.ToLower(filepath.Ext(fileName)); fileExt != "" {
+		if t, _, err := mime.ParseMediaType(mime.TypeByExtension(fileExt)); err == nil && t == base {
+			ext = fileExt
+		}
+	}
+	return contentType, ext, nil
+}
+
+// cleanFileName 只保留文件名本身，用于展示
+func cleanFileName(fileName string) string {
+	name := path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		name = string([]rune(name)[:255])
+	}
+	return name
+}
+
+// isAdmin 管理员不受配额限制
+func (s *mediaService) isAdmin(ctx context.Context, userID uint) bool {
+	user, err := s.userSQL.GetUserByID(ctx, userID)
+	return err == nil && user != nil && user.Relation == model.UserRoleAdmin
+}
+
+// Upload 上传文件到媒体库
+// 同一用户重复上传相同内容时返回已有的记录；其他用户已上传过的内容不再重复存储，但仍计入本用户的配额
+func (s *mediaService) Upload(ctx context.Context, userID uint, fileName string, r io.Reader, size int64) (*model.Media, error) {
+	// 1. 用户级限流
+	rateLimitKey := fmt.Sprintf("media_upload:user:%d", userID)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Hour,
+		MaxRequests: 100, // 每小时最多上传100个文件
+	}
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return nil, ErrRateLimited
+	}
+
+	// 2. 读取文件（多读一个字节判断是否超过上限）
+	if size > s.options.MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(r, s.options.MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, ErrEmptyFile
+	}
+	if int64(len(data)) > s.options.MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	// 3. 识别类型
+	contentType, ext, err := detectType(data, fileName)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	media := &model.Media{
+		UserID:      userID,
+		Hash:        hash,
+		Key:         mediaKey(hash, ext),
+		FileName:    cleanFileName(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	// GIF 可能是动图，缩小后会丢掉动画，只记录尺寸
+	var img image.Image
+	if strings.HasPrefix(contentType, "image/") {
+		if contentType == "image/gif" {
+			if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+				media.Width, media.Height = cfg.Width, cfg.Height
+			}
+		} else if img, _, err = imagingpkg.Decode(data); err == nil {
+			b := img.Bounds()
+			media.Width, media.Height = b.Dx(), b.Dy()
+		} else {
+			img = nil
+		}
+	}
+
+	// 4. 检查配额并保存（用户锁保证配额不被并发上传突破，内容锁避免与删除同一文件冲突）
+	userLockKey := fmt.Sprintf("media_quota:user:%d", userID)
+	err = s.lockManager.GetLock(userLockKey, 60*time.Second).Mutex(ctx, func() error {
+		return s.lockManager.GetLock("media_blob:"+hash, 60*time.Second).Mutex(ctx, func() error {
+			same, err := s.mediaSQL.FindMedia(ctx, "hash = ?", hash)
+			if err != nil {
+				return fmt.Errorf("查询文件失败: %w", err)
+			}
+			for _, m := range same {
+				if m.UserID == userID {
+					media = m
+					return nil
+				}
+			}
+
+			if s.options.Quota > 0 && !s.isAdmin(ctx, userID) {
+				used, err := s.mediaSQL.SumMediaSize(ctx, userID)
+				if err != nil {
+					return fmt.Errorf("查询存储用量失败: %w", err)
+				}
+				if used+media.Size > s.options.Quota {
+					return ErrQuotaExceeded
+				}
+			}
+
+			// 内容已存在时沿用已有文件及其缩小版本
+			stored := len(same) > 0
+			if stored {
+				media.Key = same[0].Key
+				media.Variants = same[0].Variants
+				media.Placeholder = same[0].Placeholder
+			} else {
+				if err := s.uploads.Put(ctx, media.Key, bytes.NewReader(data), media.Size, contentType); err != nil {
+					return fmt.Errorf("保存文件失败: %w", err)
+				}
+				if img != nil {
+					s.generateVariants(ctx, media, img)
+				}
+			}
+			media.URL = s.uploads.URL(media.Key)
+
+			if err := s.mediaSQL.InsertMedia(ctx, media); err != nil {
+				if !stored {
+					s.deleteStored(ctx, media)
+				}
+				return fmt.Errorf("保存文件记录失败: %w", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if media.URL == "" {
+		media.URL = s.uploads.URL(media.Key)
+	}
+	return media, nil
+}
+
+// generateVariants 生成图片的缩小版本和模糊占位图
+// 不透明的图片编码为 JPEG，有透明像素的编码为 WebP；生成失败只影响响应式显示，不影响上传
+func (s *mediaService) generateVariants(ctx context.Context, media *model.Media, img image.Image) {
+	opaque := imagingpkg.Opaque(img)
+	encode := func(w io.Writer, m image.Image) error {
+		if opaque {
+			return imagingpkg.EncodeJPEG(w, m, variantQuality)
+		}
+		return imagingpkg.EncodeWebP(w, m)
+	}
+	ext, contentType := ".jpg", "image/jpeg"
+	if !opaque {
+		ext, contentType = ".webp", "image/webp"
+	}
+
+	for _, width := range s.options.ImageWidths {
+		if width <= 0 || width >= media.Width {
+			continue
+		}
+		resized := imagingpkg.ResizeWidth(img, width)
+		var buf bytes.Buffer
+		if err := encode(&buf, resized); err != nil {
+			fmt.Printf("生成图片缩小版本失败: %v\n", err)
+			continue
+		}
+		key := variantKey(media.Hash, width, ext)
+		if err := s.uploads.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), contentType); err != nil {
+			fmt.Printf("保存图片缩小版本失败: %v\n", err)
+			continue
+		}
+		media.Variants = append(media.Variants, model.MediaVariant{
+			Width:  width,
+			Height: resized.Bounds().Dy(),
+			URL:    s.uploads.URL(key),
+		})
+	}
+
+	// 占位图很小，直接以 data URI 存在记录里，随页面一起返回
+	var buf bytes.Buffer
+	if err := encode(&buf, imagingpkg.ResizeWidth(img, min(placeholderWidth, media.Width))); err != nil {
+		fmt.Printf("生成占位图失败: %v\n", err)
+		return
+	}
+	media.Placeholder = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// deleteStored 删除存储的文件及其缩小版本
+func (s *mediaService) deleteStored(ctx context.Context, media *model.Media) {
+	if err := s.uploads.Delete(ctx, media.Key); err != nil {
+		// 记录已删除，文件残留不影响使用
+		fmt.Printf("删除媒体文件失败: %v\n", err)
+	}
+	for _, v := range media.Variants {
+		if err := s.uploads.Delete(ctx, variantKey(media.Hash, v.Width, path.Ext(v.URL))); err != nil {
+			fmt.Printf("删除图片缩小版本失败: %v\n", err)
+		}
+	}
+}
+
+// ListMedia 用户的媒体库（最新上传的在前），包含引用每个文件的文章
+func (s *mediaService) ListMedia(ctx context.Context, userID uint, page, size int) ([]*model.Media, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	offset := (page - 1) * size
+
+	query := s.db.WithContext(ctx).Model(&model.Media{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取文件总数失败: %w", err)
+	}
+
+	var media []*model.Media
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(size).
+		Offset(offset).
+		Find(&media).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取文件列表失败: %w", err)
+	}
+
+	if err := s.fillMedia(ctx, media); err != nil {
+		return nil, 0, err
+	}
+	return media, total, nil
+}
+
+// GetMedia 获取文件详情，只能查看自己的文件
+func (s *mediaService) GetMedia(ctx context.Context, userID, id uint) (*model.Media, error) {
+	media, err := s.mediaSQL.GetMediaByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if media.UserID != userID {
+		return nil, ErrMediaNotFound
+	}
+
+	if err := s.fillMedia(ctx, []*model.Media{media}); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// DeleteMedia 删除文件，被文章引用时不能删除；没有其他用户使用相同内容时同时删除存储的文件
+func (s *mediaService) DeleteMedia(ctx context.Context, userID, id uint) error {
+	media, err := s.mediaSQL.GetMediaByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMediaNotFound
+		}
+		return fmt.Errorf("获取文件失败: %w", err)
+	}
+	if media.UserID != userID {
+		return ErrMediaNotFound
+	}
+
+	return s.lockManager.GetLock("media_blob:"+media.Hash, 60*time.Second).Mutex(ctx, func() error {
+		refs, err := s.mediaSQL.FindMediaReferences(ctx, "media_id = ?", media.ID)
+		if err != nil {
+			return fmt.Errorf("查询文件引用失败: %w", err)
+		}
+		if len(refs) > 0 {
+			return ErrMediaInUse
+		}
+
+		if err := s.mediaSQL.DeleteMedia(ctx, media.ID); err != nil {
+			return fmt.Errorf("删除文件记录失败: %w", err)
+		}
+
+		same, err := s.mediaSQL.FindMedia(ctx, "hash = ?", media.Hash)
+		if err != nil {
+			return fmt.Errorf("查询文件失败: %w", err)
+		}
+		if len(same) == 0 {
+			s.deleteStored(ctx, media)
+		}
+		return nil
+	})
+}
+
+// GetUsage 用户的存储用量和配额
+func (s *mediaService) GetUsage(ctx context.Context, userID uint) (*MediaUsage, error) {
+	used, err := s.mediaSQL.SumMediaSize(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询存储用量失败: %w", err)
+	}
+	var files int64
+	if err := s.db.WithContext(ctx).Model(&model.Media{}).Where("user_id = ?", userID).Count(&files).Error; err != nil {
+		return nil, fmt.Errorf("查询文件数失败: %w", err)
+	}
+
+	usage := &MediaUsage{Used: used, Quota: s.options.Quota, Files: files}
+	if s.isAdmin(ctx, userID) {
+		usage.Quota = 0
+	}
+	return usage, nil
+}
+
+// fillMedia 填充访问地址和引用文件的文章
+func (s *mediaService) fillMedia(ctx context.Context, media []*model.Media) error {
+	if len(media) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(media))
+	for _, m := range media {
+		if m.URL == "" {
+			m.URL = s.uploads.URL(m.Key)
+		}
+		ids = append(ids, m.ID)
+	}
+
+	refs, err := s.mediaSQL.FindMediaReferences(ctx, "media_id IN ?", ids)
+	if err != nil {
+		return fmt.Errorf("查询文件引用失败: %w", err)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	postIDs := make([]uint, 0, len(refs))
+	for _, ref := range refs {
+		postIDs = append(postIDs, ref.PostID)
+	}
+	posts, err := s.postSQL.FindPosts(ctx, "id IN ?", postIDs)
+	if err != nil {
+		return fmt.Errorf("查询引用文章失败: %w", err)
+	}
+
+	postByID := make(map[uint]*model.Post, len(posts))
+	for _, p := range posts {
+		// 只返回文章的基本信息
+		postByID[p.ID] = &model.Post{ID: p.ID, Title: p.Title, Slug: p.Slug, UserID: p.UserID, Visibility: p.Visibility}
+	}
+	mediaByID := make(map[uint]*model.Media, len(media))
+	for _, m := range media {
+		mediaByID[m.ID] = m
+	}
+	for _, ref := range refs {
+		if p, ok := postByID[ref.PostID]; ok {
+			m := mediaByID[ref.MediaID]
+			m.Posts = append(m.Posts, p)
+		}
+	}
+	return nil
+}
+
+// GetCover 获取用作文章封面的图片
+func (s *mediaService) GetCover(ctx context.Context, authorID, id uint) (*model.Media, error) {
+	media, err := s.mediaSQL.GetMediaByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	if media.UserID != authorID {
+		return nil, ErrMediaNotFound
+	}
+	if !media.IsImage() {
+		return nil, ErrNotImage
+	}
+	return media, nil
+}
+
+// SyncPostReferences 按文章内容中出现的媒体文件地址和封面更新引用
+// 同一内容有多条记录时优先记到文章作者自己的文件上
+func (s *mediaService) SyncPostReferences(ctx context.Context, postID, authorID uint, content string, coverID *uint) error {
+	var hashes []string
+	seen := make(map[string]bool)
+	for _, m := range s.urlPattern.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			hashes = append(hashes, m[1])
+		}
+		if len(hashes) >= maxPostReferences {
+			break
+		}
+	}
+
+	var mediaIDs []uint
+	if len(hashes) > 0 {
+		media, err := s.mediaSQL.FindMedia(ctx, "hash IN ?", hashes)
+		if err != nil {
+			return fmt.Errorf("查询引用文件失败: %w", err)
+		}
+		chosen := make(map[string]*model.Media, len(hashes))
+		for _, m := range media {
+			current, ok := chosen[m.Hash]
+			switch {
+			case !ok:
+				chosen[m.Hash] = m
+			case current.UserID != authorID && (m.UserID == authorID || m.ID < current.ID):
+				chosen[m.Hash] = m
+			}
+		}
+		for _, hash := range hashes {
+			if m, ok := chosen[hash]; ok {
+				mediaIDs = append(mediaIDs, m.ID)
+			}
+		}
+	}
+
+	// 封面也算引用，避免被删除
+	if coverID != nil && *coverID != 0 && !slices.Contains(mediaIDs, *coverID) {
+		mediaIDs = append(mediaIDs, *coverID)
+	}
+
+	if err := s.mediaSQL.ReplacePostReferences(ctx, postID, mediaIDs); err != nil {
+		return fmt.Errorf("保存文件引用失败: %w", err)
+	}
+	return nil
+}
+
+// DeletePostReferences 删除文章的全部引用
+func (s *mediaService) DeletePostReferences(ctx context.Context, postID uint) error {
+	if err := s.mediaSQL.ReplacePostReferences(ctx, postID, nil); err != nil {
+		return fmt.Errorf("删除文件引用失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	"blog/model"
+	notificationservice "blog/service/NotificationService"
+	"blog/utils"
+	"context"
+	"fmt"
+	"time"
+)
+
+// MentionService @提及服务
+type MentionService interface {
+	// ResolveMentions 解析内容中的@用户名并查找对应用户
+	ResolveMentions(ctx context.Context, content string) []*model.User
+	// RecordMentions 用 req.Users 替换目标内容的提及记录，并通知新增的被提及用户（已存在的提及不会重复通知）
+	RecordMentions(ctx context.Context, req *RecordMentionsRequest) error
+	// DeleteMentions 删除目标内容的提及记录
+	DeleteMentions(ctx context.Context, targetType model.MentionTarget, targetID uint) error
+}
+
+// RecordMentionsRequest 保存提及请求
+type RecordMentionsRequest struct {
+	ActorID    uint
+	TargetType model.MentionTarget
+	TargetID   uint
+	PostID     uint
+	Users      []*model.User
+}
+
+// RenderMentions 把内容渲染为带@提及链接的HTML
+func RenderMentions(content string, users []*model.User) string {
+	names := make(map[string]bool, len(users))
+	for _, u := range users {
+		names[u.Name] = true
+	}
+	return utils.RenderMentions(content, names)
+}
+
+type mentionService struct {
+	mentionSQL mysql.MentionSQL
+	userSQL    mysql.UserSQL
+
+	// 通知服务
+	notificationService notificationservice.NotificationService
+
+	// 分布式锁管理器
+	lockManager *utils.LockManager
+}
+
+func NewMentionService(
+	mentionSQL mysql.MentionSQL,
+	userSQL mysql.UserSQL,
+	notificationService notificationservice.NotificationService,
+	lockManager *utils.LockManager,
+) MentionService {
+	return &mentionService{
+		mentionSQL:          mentionSQL,
+		userSQL:             userSQL,
+		notificationService: notificationService,
+		lockManager:         lockManager,
+	}
+}
+
+// ResolveMentions 解析内容中的@用户名并查找对应用户
+func (s *mentionService) ResolveMentions(ctx context.Context, content string) []*model.User {
+	var users []*model.User
+	seen := make(map[uint]bool)
+
+	for _, candidate := range utils.ParseMentions(content) {
+		// 中文用户名后面可能紧跟正文，依次尝试更短的前缀
+		for _, name := range utils.MentionPrefixes(candidate) {
+			user, err := s.userSQL.GetUserByName(ctx, name)
+			if err != nil {
+				fmt.Printf("查询被提及用户失败: %v\n", err)
+				break
+			}
+			if user == nil {
+				continue
+			}
+			if !seen[user.ID] && user.Status != model.UserStatusBanned {
+				seen[user.ID] = true
+				users = append(users, user)
+			}
+			break
+		}
+	}
+
+	return users
+}
+
+// RecordMentions 保存提及记录并通知被提及用户，编辑后不再提及的用户删除其提及记录
+func (s *mentionService) RecordMentions(ctx context.Context, req *RecordMentionsRequest) error {
+	if len(req.Users) == 0 {
+		return s.mentionSQL.DeleteMentions(ctx, req.TargetType, req.TargetID)
+	}
+
+	lockKey := fmt.Sprintf("mention:%s:%d", req.TargetType, req.TargetID)
+	return s.lockManager.GetLock(lockKey, 10*time.Second).Mutex(ctx, func() error {
+		// 已经提及过的用户不再重复通知（如编辑帖子）
+		existing, err := s.mentionSQL.FindMentions(ctx, "target_type = ? AND target_id = ?", req.TargetType, req.TargetID)
+		if err != nil {
+			return fmt.Errorf("查询提及记录失败: %w", err)
+		}
+		mentioned := make(map[uint]bool, len(existing))
+		for _, m := range existing {
+			mentioned[m.UserID] = true
+		}
+
+		// 删除编辑后不再提及的用户
+		current := make(map[uint]bool, len(req.Users))
+		for _, user := range req.Users {
+			current[user.ID] = true
+		}
+		var removed []uint
+		for userID := range mentioned {
+			if !current[userID] {
+				removed = append(removed, userID)
+			}
+		}
+		if len(removed) > 0 {
+			if err := s.mentionSQL.DeleteUserMentions(ctx, req.TargetType, req.TargetID, removed); err != nil {
+				return fmt.Errorf("删除提及记录失败: %w", err)
+			}
+		}
+
+		for _, user := range req.Users {
+			// 提及自己不记录
+			if user.ID == req.ActorID || mentioned[user.ID] {
+				continue
+			}
+
+			mention := &model.Mention{
+				UserID:     user.ID,
+				ActorID:    req.ActorID,
+				TargetType: req.TargetType,
+				TargetID:   req.TargetID,
+				PostID:     req.PostID,
+				CreatedAt:  time.Now(),
+			}
+			if err := s.mentionSQL.InsertMention(ctx, mention); err != nil {
+				return fmt.Errorf("保存提及记录失败: %w", err)
+			}
+
+			event := &notificationservice.NotificationEvent{
+				UserID:  user.ID,
+				ActorID: req.ActorID,
+				Type:    model.NotificationTypeMention,
+				PostID:  req.PostID,
+			}
+			if req.TargetType == model.MentionTargetComment {
+				event.CommentID = req.TargetID
+			}
+			if err := s.notificationService.Notify(ctx, event); err != nil {
+				fmt.Printf("创建提及通知失败: %v\n", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteMentions 删除目标内容的提及记录
+func (s *mentionService) DeleteMentions(ctx context.Context, targetType model.MentionTarget, targetID uint) error {
+	return s.mentionSQL.DeleteMentions(ctx, targetType, targetID)
+}
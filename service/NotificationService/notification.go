@@ -0,0 +1,371 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	"blog/model"
+	streamservice "blog/service/StreamService"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotificationNotFound    = errors.New("通知不存在")
+	ErrInvalidNotificationType = errors.New("无效的通知类型")
+	ErrRateLimited             = errors.New("操作过于频繁，请稍后再试")
+)
+
+type NotificationService interface {
+	// 记录事件
+	Notify(ctx context.Context, event *NotificationEvent) error
+
+	// 收件箱
+	ListNotifications(ctx context.Context, userID uint, unreadOnly bool, page, size int) ([]*model.Notification, int64, error)
+	CountUnread(ctx context.Context, userID uint) (int64, error)
+	MarkRead(ctx context.Context, userID, id uint) error
+	MarkAllRead(ctx context.Context, userID uint) (int64, error)
+
+	// 偏好设置
+	GetPreferences(ctx context.Context, userID uint) (map[model.NotificationType]bool, error)
+	UpdatePreferences(ctx context.Context, userID uint, prefs map[model.NotificationType]bool) (map[model.NotificationType]bool, error)
+}
+
+// NotificationEvent 通知事件
+type NotificationEvent struct {
+	UserID    uint // 接收者
+	ActorID   uint // 触发者
+	Type      model.NotificationType
+	PostID    uint
+	CommentID uint
+}
+
+type notificationService struct {
+	notificationSQL mysql.NotificationSQL
+
+	// 数据库
+	db *gorm.DB
+
+	// 分布式锁管理器
+	lockManager *utils.LockManager
+
+	// 限流器
+	rateLimiter *utils.RateLimiter
+
+	// 实时推送
+	streamService streamservice.StreamService
+}
+
+func NewNotificationService(
+	notificationSQL mysql.NotificationSQL,
+	db *gorm.DB,
+	lockManager *utils.LockManager,
+	rateLimiter *utils.RateLimiter,
+	streamService streamservice.StreamService,
+) NotificationService {
+	return &notificationService{
+		notificationSQL: notificationSQL,
+		db:              db,
+		lockManager:     lockManager,
+		rateLimiter:     rateLimiter,
+		streamService:   streamService,
+	}
+}
+
+// ValidNotificationType 检查通知类型是否有效
+func ValidNotificationType(t model.NotificationType) bool {
+	for _, nt := range model.NotificationTypes {
+		if nt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKey 获取可聚合通知的分组键，不可聚合的通知返回空字符串
+func groupKey(event *NotificationEvent) string {
+	switch event.Type {
+	case model.NotificationTypeLike, model.NotificationTypeStar:
+		return fmt.Sprintf("%s:post:%d", event.Type, event.PostID)
+	case model.NotificationTypeFollow:
+		return string(event.Type)
+	default:
+		return ""
+	}
+}
+
+// describeNotification 生成通知展示文案
+func describeNotification(n *model.Notification) string {
+	actor := "有人"
+	if n.Actor != nil && n.Actor.Name != "" {
+		actor = n.Actor.Name
+	}
+	if n.ActorCount > 1 {
+		actor = fmt.Sprintf("%s等%d人", actor, n.ActorCount)
+	}
+
+	title := ""
+	if n.Post != nil && n.Post.Title != "" {
+		title = "《" + n.Post.Title + "》"
+	}
+
+	switch n.Type {
+	case model.NotificationTypeReply:
+		return fmt.Sprintf("%s回复了你在%s下的评论", actor, title)
+	case model.NotificationTypeComment:
+		return fmt.Sprintf("%s评论了你的文章%s", actor, title)
+	case model.NotificationTypeLike:
+		return fmt.Sprintf("%s赞了你的文章%s", actor, title)
+	case model.NotificationTypeStar:
+		return fmt.Sprintf("%s收藏了你的文章%s", actor, title)
+	case model.NotificationTypeFollow:
+		return fmt.Sprintf("%s关注了你", actor)
+	case model.NotificationTypeMention:
+		if n.CommentID > 0 {
+			return fmt.Sprintf("%s在%s的评论中提到了你", actor, title)
+		}
+		return fmt.Sprintf("%s在文章%s中提到了你", actor, title)
+	default:
+		return actor
+	}
+}
+
+// isEnabled 检查用户是否开启了该类型的通知
+func (s *notificationService) isEnabled(ctx context.Context, userID uint, t model.NotificationType) (bool, error) {
+	prefs, err := s.notificationSQL.FindPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range prefs {
+		if p.Type == t {
+			return p.Enabled, nil
+		}
+	}
+	return true, nil
+}
+
+// Notify 记录通知事件（同组未读通知聚合为一条）
+func (s *notificationService) Notify(ctx context.Context, event *NotificationEvent) error {
+	// 不通知自己
+	if event.UserID == 0 || event.UserID == event.ActorID {
+		return nil
+	}
+
+	enabled, err := s.isEnabled(ctx, event.UserID, event.Type)
+	if err != nil {
+		return fmt.Errorf("获取通知偏好失败: %w", err)
+	}
+	if !enabled {
+		return nil
+	}
+
+	if err := s.store(ctx, event); err != nil {
+		return err
+	}
+
+	// 实时推送给接收者
+	unread, err := s.CountUnread(ctx, event.UserID)
+	if err != nil {
+		fmt.Printf("获取未读通知数失败: %v\n", err)
+	}
+	data := map[string]interface{}{
+		"type":       event.Type,
+		"actor_id":   event.ActorID,
+		"post_id":    event.PostID,
+		"comment_id": event.CommentID,
+		"unread":     unread,
+	}
+	if err := s.streamService.Publish(ctx, streamservice.EventNotification, event.UserID, 0, data); err != nil {
+		fmt.Printf("推送通知失败: %v\n", err)
+	}
+
+	return nil
+}
+
+// store 保存通知，可聚合的通知合并到同组的未读通知中
+func (s *notificationService) store(ctx context.Context, event *NotificationEvent) error {
+	key := groupKey(event)
+	if key == "" {
+		return s.notificationSQL.InsertNotification(ctx, &model.Notification{
+			UserID:     event.UserID,
+			ActorID:    event.ActorID,
+			Type:       event.Type,
+			PostID:     event.PostID,
+			CommentID:  event.CommentID,
+			ActorCount: 1,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		})
+	}
+
+	// 使用分布式锁保护聚合过程
+	lockKey := fmt.Sprintf("notification_group:%d:%s", event.UserID, key)
+	return s.lockManager.GetLock(lockKey, 5*time.Second).Mutex(ctx, func() error {
+		existing, err := s.notificationSQL.FindNotifications(ctx,
+			"user_id = ? AND group_key = ? AND is_read = ?", event.UserID, key, false)
+		if err != nil {
+			return fmt.Errorf("查询未读通知失败: %w", err)
+		}
+
+		if len(existing) > 0 {
+			n := existing[0]
+			// 同一个人重复触发（如取消后再次点赞）不增加人数：按去重后的触发者计数
+			if err := s.notificationSQL.AddNotificationActor(ctx, n.ID, event.ActorID); err != nil {
+				return fmt.Errorf("记录通知触发者失败: %w", err)
+			}
+			count, err := s.notificationSQL.CountNotificationActors(ctx, n.ID)
+			if err != nil {
+				return fmt.Errorf("统计通知触发者失败: %w", err)
+			}
+
+			return s.notificationSQL.UpdateNotification(ctx, n.ID, map[string]interface{}{
+				"actor_id":    event.ActorID,
+				"actor_count": count,
+				"updated_at":  time.Now(),
+			})
+		}
+
+		n := &model.Notification{
+			UserID:     event.UserID,
+			ActorID:    event.ActorID,
+			Type:       event.Type,
+			PostID:     event.PostID,
+			CommentID:  event.CommentID,
+			GroupKey:   key,
+			ActorCount: 1,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		}
+		if err := s.notificationSQL.InsertNotification(ctx, n); err != nil {
+			return err
+		}
+		if err := s.notificationSQL.AddNotificationActor(ctx, n.ID, event.ActorID); err != nil {
+			return fmt.Errorf("记录通知触发者失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListNotifications 分页获取通知（按最近活动时间倒序）
+func (s *notificationService) ListNotifications(ctx context.Context, userID uint, unreadOnly bool, page, size int) ([]*model.Notification, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+
+	// 限流检查
+	rateLimitKey := fmt.Sprintf("list_notifications:user:%d", userID)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Minute,
+		MaxRequests: 120,
+	}
+
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return nil, 0, ErrRateLimited
+	}
+
+	offset := (page - 1) * size
+
+	query := s.db.WithContext(ctx).Model(&model.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取通知总数失败: %w", err)
+	}
+
+	var notifications []*model.Notification
+	err := query.
+		Preload("Actor", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, avatar_url")
+		}).
+		Preload("Post", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, title, slug")
+		}).
+		Order("updated_at DESC").
+		Limit(size).
+		Offset(offset).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取通知列表失败: %w", err)
+	}
+
+	for _, n := range notifications {
+		n.Message = describeNotification(n)
+	}
+
+	return notifications, total, nil
+}
+
+// CountUnread 获取未读通知数
+func (s *notificationService) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	return s.notificationSQL.CountNotifications(ctx, "user_id = ? AND is_read = ?", userID, false)
+}
+
+// MarkRead 标记单条通知为已读
+func (s *notificationService) MarkRead(ctx context.Context, userID, id uint) error {
+	notifications, err := s.notificationSQL.FindNotifications(ctx, "id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("查询通知失败: %w", err)
+	}
+	if len(notifications) == 0 {
+		return ErrNotificationNotFound
+	}
+
+	_, err = s.notificationSQL.MarkNotificationsRead(ctx, userID, id)
+	return err
+}
+
+// MarkAllRead 标记全部通知为已读
+func (s *notificationService) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	return s.notificationSQL.MarkNotificationsRead(ctx, userID)
+}
+
+// GetPreferences 获取通知偏好（未设置的类型默认开启）
+func (s *notificationService) GetPreferences(ctx context.Context, userID uint) (map[model.NotificationType]bool, error) {
+	prefs, err := s.notificationSQL.FindPreferences(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取通知偏好失败: %w", err)
+	}
+
+	result := make(map[model.NotificationType]bool, len(model.NotificationTypes))
+	for _, t := range model.NotificationTypes {
+		result[t] = true
+	}
+	for _, p := range prefs {
+		if _, ok := result[p.Type]; ok {
+			result[p.Type] = p.Enabled
+		}
+	}
+	return result, nil
+}
+
+// UpdatePreferences 更新通知偏好
+func (s *notificationService) UpdatePreferences(ctx context.Context, userID uint, prefs map[model.NotificationType]bool) (map[model.NotificationType]bool, error) {
+	for t := range prefs {
+		if !ValidNotificationType(t) {
+			return nil, ErrInvalidNotificationType
+		}
+	}
+
+	for t, enabled := range prefs {
+		pref := &model.NotificationPreference{
+			UserID:    userID,
+			Type:      t,
+			Enabled:   enabled,
+			UpdatedAt: time.Now(),
+		}
+		if err := s.notificationSQL.SavePreference(ctx, pref); err != nil {
+			return nil, fmt.Errorf("保存通知偏好失败: %w", err)
+		}
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
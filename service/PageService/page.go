@@ -0,0 +1,551 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	"blog/model"
+	themepkg "blog/pkg/theme"
+	postservice "blog/service/PostService"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPageNotFound = errors.New("页面不存在")
+	ErrRateLimited  = errors.New("操作过于频繁，请稍后再试")
+)
+
+type PageService interface {
+	// 首页和文章详情
+	Home(ctx context.Context, page int) (*Page, error)
+	Post(ctx context.Context, slug string) (*Page, error)
+
+	// 分类/标签/作者的文章列表
+	Category(ctx context.Context, slug string, page int) (*Page, error)
+	Tag(ctx context.Context, slug string, page int) (*Page, error)
+	Author(ctx context.Context, username string, page int) (*Page, error)
+
+	// NotFound 404页面
+	NotFound() *Page
+	// Redirect 旧链接（如迁移前的文章地址）跳转的目标，没有时返回空字符串
+	Redirect(ctx context.Context, path string) (string, error)
+
+	// 根据已加载的数据组装页面（文章按发布时间倒序），供静态导出使用
+	PostPage(post *model.Post) *Page
+	HomePage(posts []*model.Post, pagination *Pagination) *Page
+	CategoryPage(category *model.Category, posts []*model.Post, pagination *Pagination) *Page
+	TagPage(tag *model.Tag, posts []*model.Post, pagination *Pagination) *Page
+	AuthorPage(user *model.User, posts []*model.Post, pagination *Pagination) *Page
+	Paginate(path string, page int, total int64) *Pagination
+
+	// Render 用主题模板渲染页面
+	Render(page *Page) ([]byte, error)
+}
+
+// Options 页面配置
+type Options struct {
+	Title       string
+	Description string
+	BaseURL     string
+	Language    string
+	PageSize    int // 列表页每页文章数
+
+	// 分页地址使用 /page/2/ 形式（静态导出），否则为 ?page=2
+	StaticPaging bool
+}
+
+// Site 站点信息（模板中为 .Site）
+type Site struct {
+	Title       string
+	Description string
+	URL         string
+	Language    string
+}
+
+// Pagination 列表分页
+type Pagination struct {
+	Page       int
+	TotalPages int
+	Total      int64
+	Size       int    // 每页文章数
+	URL        string // 当前页地址
+	PrevURL    string
+	NextURL    string
+}
+
+// Page 模板数据
+type Page struct {
+	Template string
+	Site     Site
+	Meta     themepkg.Meta
+
+	// 文章详情页
+	Post    *model.Post
+	Content string // 正文HTML
+
+	// 列表页
+	Posts      []*model.Post
+	Category   *model.Category
+	Tag        *model.Tag
+	Author     *model.User
+	Pagination *Pagination
+}
+
+type pageService struct {
+	categorySQL mysql.CategorySQL
+	tagSQL      mysql.TagSQL
+	userSQL     mysql.UserSQL
+
+	// 文章服务（浏览量统计）
+	postService postservice.PostService
+
+	// 数据库
+	db *gorm.DB
+
+	// 限流器
+	rateLimiter *utils.RateLimiter
+
+	// 主题模板
+	theme *themepkg.Theme
+
+	options Options
+	site    Site
+}
+
+func NewPageService(
+	categorySQL mysql.CategorySQL,
+	tagSQL mysql.TagSQL,
+	userSQL mysql.UserSQL,
+	postService postservice.PostService,
+	db *gorm.DB,
+	rateLimiter *utils.RateLimiter,
+	theme *themepkg.Theme,
+	options Options,
+) PageService {
+	options.BaseURL = strings.TrimRight(options.BaseURL, "/")
+	if options.PageSize < 1 {
+		options.PageSize = 10
+	}
+	return &pageService{
+		categorySQL: categorySQL,
+		tagSQL:      tagSQL,
+		userSQL:     userSQL,
+		postService: postService,
+		db:          db,
+		rateLimiter: rateLimiter,
+		theme:       theme,
+		options:     options,
+		site: Site{
+			Title:       options.Title,
+			Description: options.Description,
+			URL:         options.BaseURL,
+			Language:    options.Language,
+		},
+	}
+}
+
+// allow 页面限流
+func (s *pageService) allow(ctx context.Context) error {
+	ip := utils.GetIPFromContext(ctx)
+	rateLimitKey := fmt.Sprintf("page:ip:%s", ip)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Minute,
+		MaxRequests: 300,
+	}
+
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// absURL 站内路径转为绝对地址
+func (s *pageService) absURL(path string) string {
+	if path == "" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return s.options.BaseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+// pageURL 列表第page页的地址，第一页不带分页参数
+func (s *pageService) pageURL(path string, page int) string {
+	if page <= 1 {
+		return s.absURL(path)
+	}
+	if s.options.StaticPaging {
+		return s.absURL(strings.TrimRight(path, "/") + "/page/" + strconv.Itoa(page) + "/")
+	}
+	return s.absURL(path) + "?page=" + strconv.Itoa(page)
+}
+
+// Home 首页：最新公开文章
+func (s *pageService) Home(ctx context.Context, page int) (*Page, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	posts, pagination, err := s.list(ctx, s.db, "/", page)
+	if err != nil {
+		return nil, err
+	}
+	return s.HomePage(posts, pagination), nil
+}
+
+// Post 文章详情（只展示公开且未被隐藏的文章）
+func (s *pageService) Post(ctx context.Context, slug string) (*Page, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	var post model.Post
+	err := s.db.WithContext(ctx).
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, avatar_url, bio")
+		}).
+		Preload("Category").
+		Preload("Tags").
+		Preload("Cover").
+		Where("slug = ? AND visibility = ? AND hidden = ?", slug, model.VisibilityPublic, false).
+		First(&post).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPageNotFound
+		}
+		return nil, fmt.Errorf("获取文章失败: %w", err)
+	}
+
+	// 异步增加浏览量
+	viewer := utils.GetViewerKey(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.postService.IncrementViews(ctx, post.ID, viewer)
+	}()
+
+	return s.PostPage(&post), nil
+}
+
+// Category 分类文章列表
+func (s *pageService) Category(ctx context.Context, slug string, page int) (*Page, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	category, err := s.categorySQL.GetCategoryBySlug(ctx, slug)
+	if err != nil || category == nil {
+		return nil, ErrPageNotFound
+	}
+
+	posts, pagination, err := s.list(ctx, s.db.Where("category_id = ?", category.ID), utils.CategoryPath(category.Slug), page)
+	if err != nil {
+		return nil, err
+	}
+	return s.CategoryPage(category, posts, pagination), nil
+}
+
+// Tag 标签文章列表
+func (s *pageService) Tag(ctx context.Context, slug string, page int) (*Page, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.tagSQL.GetTagBySlug(ctx, slug)
+	if err != nil || tag == nil {
+		return nil, ErrPageNotFound
+	}
+
+	posts, pagination, err := s.list(ctx, s.db.Where("id IN (?)",
+		s.db.Model(&model.PostTag{}).Select("post_id").Where("tag_id = ?", tag.ID)), utils.TagPath(tag.Slug), page)
+	if err != nil {
+		return nil, err
+	}
+	return s.TagPage(tag, posts, pagination), nil
+}
+
+// Author 作者主页
+func (s *pageService) Author(ctx context.Context, username string, page int) (*Page, error) {
+	if err := s.allow(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userSQL.GetUserByName(ctx, username)
+	if err != nil || user == nil || user.Status != model.UserStatusActive {
+		return nil, ErrPageNotFound
+	}
+
+	posts, pagination, err := s.list(ctx, s.db.Where("user_id = ?", user.ID), utils.UserPath(user.Name), page)
+	if err != nil {
+		return nil, err
+	}
+	return s.AuthorPage(user, posts, pagination), nil
+}
+
+// NotFound 404页面，不允许收录
+func (s *pageService) NotFound() *Page {
+	return &Page{
+		Template: themepkg.PageNotFound,
+		Site:     s.site,
+		Meta: themepkg.Meta{
+			Title:   "页面不存在",
+			Type:    "website",
+			NoIndex: true,
+		},
+	}
+}
+
+// Redirect 旧链接跳转的目标
+func (s *pageService) Redirect(ctx context.Context, path string) (string, error) {
+	var redirect model.Redirect
+	err := s.db.WithContext(ctx).Where("path = ?", utils.RedirectPath(path)).Limit(1).Find(&redirect).Error
+	if err != nil {
+		return "", fmt.Errorf("查询旧链接跳转失败: %w", err)
+	}
+	return redirect.Target, nil
+}
+
+// Render 用主题模板渲染页面
+func (s *pageService) Render(page *Page) ([]byte, error) {
+	return s.theme.Render(page.Template, page)
+}
+
+// PostPage 文章详情页
+func (s *pageService) PostPage(post *model.Post) *Page {
+	content := post.Rendered
+	if content == "" {
+		content = html.EscapeString(post.Content)
+	}
+
+	author := post.AuthorName
+	if post.Author != nil && post.Author.Name != "" {
+		author = post.Author.Name
+	}
+
+	p := &Page{
+		Template: themepkg.PagePost,
+		Site:     s.site,
+		Post:     post,
+		Content:  content,
+		Meta: themepkg.Meta{
+			Title:       post.Title,
+			Description: utils.Summarize(post.Summary, post.Content),
+			Canonical:   s.absURL(utils.PostPath(post.Slug)),
+			Type:        "article",
+			Author:      author,
+			Published:   post.CreatedAt,
+			Modified:    post.UpdatedAt,
+		},
+	}
+	for _, tag := range post.Tags {
+		p.Meta.Keywords = append(p.Meta.Keywords, tag.Name)
+	}
+	// 分享图优先用封面，没有封面时用作者头像
+	if post.Cover != nil {
+		p.Meta.Image = s.absURL(post.Cover.URL)
+		p.Meta.ImageWidth, p.Meta.ImageHeight = post.Cover.Width, post.Cover.Height
+	} else if post.Author != nil && post.Author.AvatarURL != "" {
+		p.Meta.Image = s.absURL(post.Author.AvatarURL)
+	}
+
+	posting := map[string]interface{}{
+		"@context":         "https://schema.org",
+		"@type":            "BlogPosting",
+		"headline":         post.Title,
+		"description":      p.Meta.Description,
+		"url":              p.Meta.Canonical,
+		"mainEntityOfPage": map[string]interface{}{"@type": "WebPage", "@id": p.Meta.Canonical},
+		"datePublished":    post.CreatedAt.Format(time.RFC3339),
+		"dateModified":     post.UpdatedAt.Format(time.RFC3339),
+		"inLanguage":       s.options.Language,
+		"author": map[string]interface{}{
+			"@type": "Person",
+			"name":  author,
+			"url":   s.absURL(utils.UserPath(author)),
+		},
+		"publisher": map[string]interface{}{
+			"@type": "Organization",
+			"name":  s.options.Title,
+			"url":   s.absURL("/"),
+		},
+	}
+	if post.Category != nil {
+		posting["articleSection"] = post.Category.Name
+	}
+	if len(p.Meta.Keywords) > 0 {
+		posting["keywords"] = strings.Join(p.Meta.Keywords, ",")
+	}
+	if p.Meta.Image != "" {
+		posting["image"] = p.Meta.Image
+	}
+	p.Meta.JSONLD = posting
+	return p
+}
+
+// HomePage 首页
+func (s *pageService) HomePage(posts []*model.Post, pagination *Pagination) *Page {
+	p := s.listPage(themepkg.PageIndex, posts, pagination)
+	p.Meta.Title = s.options.Title
+	p.Meta.Description = s.options.Description
+	p.Meta.JSONLD = map[string]interface{}{
+		"@context":    "https://schema.org",
+		"@type":       "WebSite",
+		"name":        s.options.Title,
+		"description": s.options.Description,
+		"url":         s.absURL("/"),
+		"inLanguage":  s.options.Language,
+	}
+	return p
+}
+
+// CategoryPage 分类页
+func (s *pageService) CategoryPage(category *model.Category, posts []*model.Post, pagination *Pagination) *Page {
+	p := s.listPage(themepkg.PageCategory, posts, pagination)
+	p.Category = category
+	p.Meta.Title = fmt.Sprintf("分类：%s", category.Name)
+	p.Meta.Description = fmt.Sprintf("分类「%s」下的文章", category.Name)
+	p.Meta.JSONLD = s.collection(p)
+	return p
+}
+
+// TagPage 标签页
+func (s *pageService) TagPage(tag *model.Tag, posts []*model.Post, pagination *Pagination) *Page {
+	p := s.listPage(themepkg.PageTag, posts, pagination)
+	p.Tag = tag
+	p.Meta.Title = fmt.Sprintf("标签：%s", tag.Name)
+	p.Meta.Description = fmt.Sprintf("标签「%s」下的文章", tag.Name)
+	p.Meta.JSONLD = s.collection(p)
+	return p
+}
+
+// AuthorPage 作者主页
+func (s *pageService) AuthorPage(user *model.User, posts []*model.Post, pagination *Pagination) *Page {
+	p := s.listPage(themepkg.PageAuthor, posts, pagination)
+	p.Author = user
+	p.Meta.Title = user.Name
+	p.Meta.Description = user.Bio
+	if p.Meta.Description == "" {
+		p.Meta.Description = fmt.Sprintf("%s的文章", user.Name)
+	}
+	p.Meta.Type = "profile"
+	if user.AvatarURL != "" {
+		p.Meta.Image = s.absURL(user.AvatarURL)
+	}
+	p.Meta.JSONLD = map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    "ProfilePage",
+		"url":      p.Meta.Canonical,
+		"mainEntity": map[string]interface{}{
+			"@type":       "Person",
+			"name":        user.Name,
+			"description": p.Meta.Description,
+			"url":         s.absURL(utils.UserPath(user.Name)),
+		},
+	}
+	return p
+}
+
+// Paginate 计算path下第page页的分页信息
+func (s *pageService) Paginate(path string, page int, total int64) *Pagination {
+	size := int64(s.options.PageSize)
+	pagination := &Pagination{
+		Page:       page,
+		TotalPages: int((total + size - 1) / size),
+		Total:      total,
+		Size:       s.options.PageSize,
+		URL:        s.pageURL(path, page),
+	}
+	if page > 1 {
+		pagination.PrevURL = s.pageURL(path, page-1)
+	}
+	if page < pagination.TotalPages {
+		pagination.NextURL = s.pageURL(path, page+1)
+	}
+	return pagination
+}
+
+// listPage 列表页的公共字段
+func (s *pageService) listPage(template string, posts []*model.Post, pagination *Pagination) *Page {
+	return &Page{
+		Template:   template,
+		Site:       s.site,
+		Posts:      posts,
+		Pagination: pagination,
+		Meta: themepkg.Meta{
+			Canonical: pagination.URL,
+			Type:      "website",
+			Prev:      pagination.PrevURL,
+			Next:      pagination.NextURL,
+		},
+	}
+}
+
+// list 公开文章列表的分页（与 ListPosts 的过滤条件一致）
+func (s *pageService) list(ctx context.Context, query *gorm.DB, path string, page int) ([]*model.Post, *Pagination, error) {
+	if page < 1 {
+		page = 1
+	}
+	size := s.options.PageSize
+
+	var total int64
+	err := query.WithContext(ctx).
+		Model(&model.Post{}).
+		Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false).
+		Count(&total).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("统计文章失败: %w", err)
+	}
+
+	pagination := s.Paginate(path, page, total)
+	// 超出范围的页码视为不存在（第一页总是存在）
+	if page > 1 && page > pagination.TotalPages {
+		return nil, nil, ErrPageNotFound
+	}
+
+	var posts []*model.Post
+	err = query.WithContext(ctx).
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, avatar_url")
+		}).
+		Preload("Category").
+		Preload("Tags").
+		Preload("Cover").
+		Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false).
+		Order("created_at DESC").
+		Limit(size).
+		Offset((page - 1) * size).
+		Find(&posts).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取文章列表失败: %w", err)
+	}
+	return posts, pagination, nil
+}
+
+// collection 分类/标签页的结构化数据
+func (s *pageService) collection(p *Page) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(p.Posts))
+	for i, post := range p.Posts {
+		items = append(items, map[string]interface{}{
+			"@type":    "ListItem",
+			"position": (p.Pagination.Page-1)*s.options.PageSize + i + 1,
+			"url":      s.absURL(utils.PostPath(post.Slug)),
+			"name":     post.Title,
+		})
+	}
+	return map[string]interface{}{
+		"@context":    "https://schema.org",
+		"@type":       "CollectionPage",
+		"name":        p.Meta.Title,
+		"description": p.Meta.Description,
+		"url":         p.Meta.Canonical,
+		"mainEntity": map[string]interface{}{
+			"@type":           "ItemList",
+			"itemListElement": items,
+		},
+	}
+}
@@ -3,7 +3,16 @@ package service
 import (
 	mysql "blog/dao/mysql"
 	redis "blog/dao/redis"
+	searchdao "blog/dao/search"
 	"blog/model"
+	counterservice "blog/service/CounterService"
+	mediaservice "blog/service/MediaService"
+	mentionservice "blog/service/MentionService"
+	notificationservice "blog/service/NotificationService"
+	relatedservice "blog/service/RelatedService"
+	seriesservice "blog/service/SeriesService"
+	streamservice "blog/service/StreamService"
+	trendingservice "blog/service/TrendingService"
 	"blog/utils"
 	"context"
 	"errors"
@@ -29,8 +38,28 @@ var (
 	ErrPostNotStarred      = errors.New("还没有收藏此帖子")
 	ErrRateLimited         = errors.New("操作过于频繁，请稍后再试")
 	ErrOperationInProgress = errors.New("操作正在进行中，请稍后再试")
+	ErrNotAdmin            = errors.New("需要管理员权限")
+	ErrInvalidSearchSort   = errors.New("无效的排序方式")
 )
 
+// 搜索排序方式
+const (
+	SearchSortRelevance  = "relevance"
+	SearchSortDate       = "date"
+	SearchSortPopularity = "popularity"
+)
+
+const (
+	// 按热度排序时最多从索引中取出的候选帖子数
+	maxPopularityCandidates = 1000
+	// 搜索联想默认/最大返回条数
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 20
+)
+
+// popularityOrder 帖子热度排序表达式（与 popularityScore 一致）
+const popularityOrder = "clicktimes + liketimes * 3 + staredtimes * 5 + comment_numbers * 2 DESC, id DESC"
+
 // PostService 接口 - 包含所有帖子功能
 type PostService interface {
 	// 帖子基本功能
@@ -42,7 +71,11 @@ type PostService interface {
 	ListPosts(ctx context.Context, page, size int) ([]*model.Post, int64, error)
 	ListPostsByCategory(ctx context.Context, categoryID uint, page, size int) ([]*model.Post, int64, error)
 	ListPostsByTag(ctx context.Context, tagID uint, page, size int) ([]*model.Post, int64, error)
-	SearchPosts(ctx context.Context, keyword string, page, size int) ([]*model.Post, int64, error)
+	SearchPosts(ctx context.Context, req *SearchPostsRequest) (*SearchPostsResult, error)
+	SuggestSearch(ctx context.Context, prefix string, limit int) ([]*redis.Suggestion, error)
+
+	// WalkPublicPosts 按ID顺序分批遍历所有公开文章（静态导出等后台任务使用，不限流）
+	WalkPublicPosts(ctx context.Context, batchSize int, fn func(posts []*model.Post) error) error
 
 	// 统计功能
 	LikePost(ctx context.Context, postID uint) error
@@ -59,21 +92,31 @@ type PostService interface {
 	IncrementComments(ctx context.Context, postID uint) error
 	DecrementComments(ctx context.Context, postID uint) error
 
-	IncrementViews(ctx context.Context, postID uint) error
+	// IncrementViews 记录一次浏览，viewer 为访客标识（见 utils.GetViewerKey）
+	IncrementViews(ctx context.Context, postID uint, viewer string) error
 	GetPostViews(ctx context.Context, postID uint) (uint, error)
 
 	GetPostStats(ctx context.Context, postID uint) (*PostStats, error)
+
+	// 审核
+	SetPostHidden(ctx context.Context, postID uint, hiddenBy string) error
+
+	// 全文索引
+	RebuildSearchIndex(ctx context.Context) (int, error)
 }
 
 // 统计数据结构
 type PostStats struct {
-	PostID    uint `json:"post_id"`
-	Likes     uint `json:"likes"`
-	Stars     uint `json:"stars"`
-	Comments  uint `json:"comments"`
-	Views     uint `json:"views"`
-	IsLiked   bool `json:"is_liked"`   // 当前用户是否点赞
-	IsStarred bool `json:"is_starred"` // 当前用户是否收藏
+	PostID        uint  `json:"post_id"`
+	Likes         uint  `json:"likes"`
+	Stars         uint  `json:"stars"`
+	Comments      uint  `json:"comments"`
+	Views         uint  `json:"views"`          // 去重浏览量（每位访客每天计一次）
+	PageViews     uint  `json:"page_views"`     // 原始浏览量
+	TodayVisitors int64 `json:"today_visitors"` // 今天的去重访客数
+	WeekVisitors  int64 `json:"week_visitors"`  // 最近7天的去重访客数
+	IsLiked       bool  `json:"is_liked"`       // 当前用户是否点赞
+	IsStarred     bool  `json:"is_starred"`     // 当前用户是否收藏
 }
 
 // 请求结构体
@@ -85,6 +128,7 @@ type CreatePostRequest struct {
 	CategoryID uint   `json:"category_id" binding:"required"`
 	TagIDs     []uint `json:"tag_ids,omitempty"`
 	Visibility string `json:"visibility,omitempty" binding:"omitempty,oneof=public private password friends"`
+	CoverID    uint   `json:"cover_id,omitempty"` // 媒体库中的图片ID
 }
 
 type UpdatePostRequest struct {
@@ -95,6 +139,41 @@ type UpdatePostRequest struct {
 	CategoryID *uint   `json:"category_id,omitempty"`
 	TagIDs     *[]uint `json:"tag_ids,omitempty"`
 	Visibility *string `json:"visibility,omitempty" binding:"omitempty,oneof=public private password friends"`
+	CoverID    *uint   `json:"cover_id,omitempty"` // 0表示去掉封面
+}
+
+// SearchPostsRequest 搜索请求（关键词为空时按过滤条件列出帖子）
+type SearchPostsRequest struct {
+	Keyword    string
+	CategoryID uint
+	TagIDs     []uint
+	AuthorID   uint
+	From       time.Time
+	To         time.Time
+	Sort       string
+	Page       int
+	Size       int
+}
+
+// FacetCount 分面统计项
+type FacetCount struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets 搜索结果按分类/标签的统计
+type SearchFacets struct {
+	Categories []*FacetCount `json:"categories"`
+	Tags       []*FacetCount `json:"tags"`
+}
+
+// SearchPostsResult 搜索结果
+type SearchPostsResult struct {
+	Posts  []*model.Post
+	Total  int64
+	Facets *SearchFacets
 }
 
 // Service实现结构体
@@ -113,6 +192,7 @@ type postService struct {
 	likeCache    redis.LikeCache
 	starCache    redis.StarCache
 	commentCache redis.CommentCache
+	suggestCache redis.SuggestCache
 
 	// 分布式锁管理器
 	lockManager *utils.LockManager
@@ -120,6 +200,33 @@ type postService struct {
 	// 限流器
 	rateLimiter *utils.RateLimiter
 
+	// @提及
+	mentionService mentionservice.MentionService
+
+	// 媒体库（文章引用的文件）
+	mediaService mediaservice.MediaService
+
+	// 系列文章（上一篇/下一篇导航）
+	seriesService seriesservice.SeriesService
+
+	// 相关文章推荐
+	relatedService relatedservice.RelatedService
+
+	// 热门文章
+	trendingService trendingservice.TrendingService
+
+	// 计数写缓冲（点赞/收藏/评论/浏览数定时写入数据库）
+	counterService counterservice.CounterService
+
+	// 通知
+	notificationService notificationservice.NotificationService
+
+	// 实时推送
+	streamService streamservice.StreamService
+
+	// 全文索引
+	postIndex searchdao.PostIndex
+
 	// 缓存读取锁（本地锁，用于缓存读保护）
 	readCacheLock sync.RWMutex
 	// 热点数据缓存
@@ -142,26 +249,46 @@ func NewPostService(
 	likeCache redis.LikeCache,
 	starCache redis.StarCache,
 	commentCache redis.CommentCache,
+	suggestCache redis.SuggestCache,
 	lockManager *utils.LockManager,
 	rateLimiter *utils.RateLimiter,
+	mentionService mentionservice.MentionService,
+	mediaService mediaservice.MediaService,
+	seriesService seriesservice.SeriesService,
+	relatedService relatedservice.RelatedService,
+	trendingService trendingservice.TrendingService,
+	counterService counterservice.CounterService,
+	notificationService notificationservice.NotificationService,
+	streamService streamservice.StreamService,
+	postIndex searchdao.PostIndex,
 ) PostService {
 	return &postService{
-		postSQL:       postSQL,
-		userSQL:       userSQL,
-		categorySQL:   categorySQL,
-		tagSQL:        tagSQL,
-		likeSQL:       likeSQL,
-		starSQL:       starSQL,
-		commentSQL:    commentSQL,
-		db:            db,
-		viewCache:     viewCache,
-		likeCache:     likeCache,
-		starCache:     starCache,
-		commentCache:  commentCache,
-		lockManager:   lockManager,
-		rateLimiter:   rateLimiter,
-		hotPostsCache: make(map[uint]*model.Post),
-		hotPostsTTL:   make(map[uint]time.Time),
+		postSQL:             postSQL,
+		userSQL:             userSQL,
+		categorySQL:         categorySQL,
+		tagSQL:              tagSQL,
+		likeSQL:             likeSQL,
+		starSQL:             starSQL,
+		commentSQL:          commentSQL,
+		db:                  db,
+		viewCache:           viewCache,
+		likeCache:           likeCache,
+		starCache:           starCache,
+		commentCache:        commentCache,
+		suggestCache:        suggestCache,
+		lockManager:         lockManager,
+		rateLimiter:         rateLimiter,
+		mentionService:      mentionService,
+		mediaService:        mediaService,
+		seriesService:       seriesService,
+		relatedService:      relatedService,
+		trendingService:     trendingService,
+		counterService:      counterService,
+		notificationService: notificationService,
+		streamService:       streamService,
+		postIndex:           postIndex,
+		hotPostsCache:       make(map[uint]*model.Post),
+		hotPostsTTL:         make(map[uint]time.Time),
 	}
 }
 
@@ -299,6 +426,7 @@ func (s *postService) queryPostWithAssociations(ctx context.Context, postID uint
 		}).
 		Preload("Category").
 		Preload("Tags").
+		Preload("Cover").
 		First(&post, postID).Error
 
 	if err != nil {
@@ -344,11 +472,21 @@ func (s *postService) CreatePost(ctx context.Context, req *CreatePostRequest) (*
 		}
 	}
 
+	// 检查封面图片
+	var coverID *uint
+	if req.CoverID != 0 {
+		if _, err := s.mediaService.GetCover(ctx, currentUser.ID, req.CoverID); err != nil {
+			return nil, err
+		}
+		coverID = &req.CoverID
+	}
+
 	// 5. 处理slug（如果没传则自动生成）
 	slug := ""
 	if req.Slug != "" {
 		slug = utils.SanitizeSlug(req.Slug)
-	} else {
+	}
+	if slug == "" {
 		slug = utils.GenerateSlug(title)
 	}
 
@@ -395,15 +533,18 @@ func (s *postService) CreatePost(ctx context.Context, req *CreatePostRequest) (*
 		visibility = model.VisibilityPublic
 	}
 
-	// 9. 创建帖子对象
+	// 9. 解析@提及并创建帖子对象
+	mentioned := s.mentionService.ResolveMentions(ctx, req.Content)
 	post := &model.Post{
 		Title:      title,
 		Slug:       slug,
 		Content:    req.Content,
+		Rendered:   mentionservice.RenderMentions(req.Content, mentioned),
 		Summary:    summary,
 		UserID:     currentUser.ID,
 		AuthorName: currentUser.Name,
 		CategoryID: req.CategoryID,
+		CoverID:    coverID,
 		Visibility: visibility,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
@@ -438,6 +579,26 @@ func (s *postService) CreatePost(ctx context.Context, req *CreatePostRequest) (*
 		return nil, err
 	}
 
+	// 保存提及记录并通知被提及用户
+	if err := s.mentionService.RecordMentions(ctx, &mentionservice.RecordMentionsRequest{
+		ActorID:    currentUser.ID,
+		TargetType: model.MentionTargetPost,
+		TargetID:   post.ID,
+		PostID:     post.ID,
+		Users:      mentioned,
+	}); err != nil {
+		fmt.Printf("保存帖子提及失败: %v\n", err)
+	}
+
+	// 记录引用的媒体文件
+	if err := s.mediaService.SyncPostReferences(ctx, post.ID, currentUser.ID, req.Content, coverID); err != nil {
+		fmt.Printf("保存帖子文件引用失败: %v\n", err)
+	}
+
+	// 更新全文索引，后台计算相关文章
+	s.syncSearchIndex(ctx, post.ID)
+	s.relatedService.Refresh(post.ID)
+
 	// 11. 获取完整的帖子信息
 	fullPost, err := s.getPostWithAssociations(ctx, post.ID)
 	if err != nil {
@@ -447,6 +608,33 @@ func (s *postService) CreatePost(ctx context.Context, req *CreatePostRequest) (*
 	return fullPost, nil
 }
 
+// canViewHidden 被举报隐藏的帖子只有作者和管理员可见
+func (s *postService) canViewHidden(ctx context.Context, post *model.Post) bool {
+	if !post.Hidden {
+		return true
+	}
+	currentUser, err := s.getCurrentUser(ctx)
+	if err != nil {
+		return false
+	}
+	return currentUser.ID == post.UserID || currentUser.Relation == model.UserRoleAdmin
+}
+
+// withSeriesNav 附加所在系列的导航；热点缓存中的帖子是共享的，复制后再修改
+func (s *postService) withSeriesNav(ctx context.Context, post *model.Post) *model.Post {
+	nav, err := s.seriesService.PostNav(ctx, post.ID)
+	if err != nil {
+		fmt.Printf("获取帖子系列导航失败: %v\n", err)
+		return post
+	}
+	if nav == nil {
+		return post
+	}
+	withNav := *post
+	withNav.Series = nav
+	return &withNav
+}
+
 // GetPost 获取帖子详情（带缓存和限流）
 func (s *postService) GetPost(ctx context.Context, id uint) (*model.Post, error) {
 	// 限流检查：按IP限制获取频率
@@ -465,12 +653,17 @@ func (s *postService) GetPost(ctx context.Context, id uint) (*model.Post, error)
 	if err != nil {
 		return nil, err
 	}
+	if !s.canViewHidden(ctx, post) {
+		return nil, ErrPostNotFound
+	}
+	post = s.withSeriesNav(ctx, post)
 
-	// 异步增加浏览量（不阻塞返回）
+	// 异步增加浏览量（不阻塞返回），访客标识在请求结束前取出
+	viewer := utils.GetViewerKey(ctx)
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		_ = s.IncrementViews(ctx, id)
+		_ = s.IncrementViews(ctx, id, viewer)
 	}()
 
 	return post, nil
@@ -497,21 +690,23 @@ func (s *postService) GetPostBySlug(ctx context.Context, slug string) (*model.Po
 		}).
 		Preload("Category").
 		Preload("Tags").
+		Preload("Cover").
 		Where("slug = ?", slug).
 		First(&post).Error
 
-	if err != nil {
+	if err != nil || !s.canViewHidden(ctx, &post) {
 		return nil, ErrPostNotFound
 	}
 
 	// 异步增加浏览量
+	viewer := utils.GetViewerKey(ctx)
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		_ = s.IncrementViews(ctx, post.ID)
+		_ = s.IncrementViews(ctx, post.ID, viewer)
 	}()
 
-	return &post, nil
+	return s.withSeriesNav(ctx, &post), nil
 }
 
 // UpdatePost 更新帖子（带分布式锁）
@@ -543,8 +738,11 @@ func (s *postService) UpdatePost(ctx context.Context, id uint, req *UpdatePostRe
 		}
 	}
 
+	var mentioned []*model.User
 	if req.Content != nil && *req.Content != post.Content {
+		mentioned = s.mentionService.ResolveMentions(ctx, *req.Content)
 		updates["content"] = *req.Content
+		updates["rendered"] = mentionservice.RenderMentions(*req.Content, mentioned)
 	}
 
 	if req.Summary != nil && *req.Summary != post.Summary {
@@ -585,6 +783,22 @@ func (s *postService) UpdatePost(ctx context.Context, id uint, req *UpdatePostRe
 		updates["visibility"] = *req.Visibility
 	}
 
+	coverID := post.CoverID
+	if req.CoverID != nil && (post.CoverID == nil || *req.CoverID != *post.CoverID) {
+		if *req.CoverID == 0 {
+			if post.CoverID != nil {
+				coverID = nil
+				updates["cover_id"] = nil
+			}
+		} else {
+			if _, err := s.mediaService.GetCover(ctx, post.UserID, *req.CoverID); err != nil {
+				return nil, err
+			}
+			coverID = req.CoverID
+			updates["cover_id"] = *req.CoverID
+		}
+	}
+
 	// 如果没有更新内容，直接返回
 	if len(updates) == 0 {
 		return s.getPostWithAssociations(ctx, id)
@@ -613,6 +827,41 @@ func (s *postService) UpdatePost(ctx context.Context, id uint, req *UpdatePostRe
 		return nil, err
 	}
 
+	// 内容变化时更新提及：新增的通知被提及用户（已提及过的不会重复通知），删掉的移除提及记录
+	if _, ok := updates["content"]; ok {
+		if err := s.mentionService.RecordMentions(ctx, &mentionservice.RecordMentionsRequest{
+			ActorID:    currentUser.ID,
+			TargetType: model.MentionTargetPost,
+			TargetID:   id,
+			PostID:     id,
+			Users:      mentioned,
+		}); err != nil {
+			fmt.Printf("保存帖子提及失败: %v\n", err)
+		}
+	}
+
+	// 内容或封面变化时更新引用的媒体文件
+	_, contentChanged := updates["content"]
+	_, coverChanged := updates["cover_id"]
+	if contentChanged || coverChanged {
+		content := post.Content
+		if contentChanged {
+			content = *req.Content
+		}
+		if err := s.mediaService.SyncPostReferences(ctx, id, post.UserID, content, coverID); err != nil {
+			fmt.Printf("保存帖子文件引用失败: %v\n", err)
+		}
+	}
+
+	// 更新全文索引，后台计算相关文章
+	s.syncSearchIndex(ctx, id)
+	s.relatedService.Refresh(id)
+
+	// 可见性变化后取消无权查看者的实时推送
+	if _, ok := updates["visibility"]; ok {
+		s.publishPostAccess(ctx, id)
+	}
+
 	// 5. 获取更新后的帖子
 	return s.getPostWithAssociations(ctx, id)
 }
@@ -631,8 +880,7 @@ func (s *postService) DeletePost(ctx context.Context, id uint) error {
 		return err
 	}
 
-	if post.UserID != currentUser.ID {
-		// 这里可以添加管理员权限检查
+	if post.UserID != currentUser.ID && currentUser.Relation != model.UserRoleAdmin {
 		return errors.New("没有权限删除此帖子")
 	}
 
@@ -645,8 +893,39 @@ func (s *postService) DeletePost(ctx context.Context, id uint) error {
 		delete(s.hotPostsTTL, id)
 		s.hotPostLock.Unlock()
 
+		// 删除提及记录
+		if err := s.mentionService.DeleteMentions(ctx, model.MentionTargetPost, id); err != nil {
+			fmt.Printf("删除帖子提及失败: %v\n", err)
+		}
+
+		// 删除文件引用，文件可以从媒体库删除
+		if err := s.mediaService.DeletePostReferences(ctx, id); err != nil {
+			fmt.Printf("删除帖子文件引用失败: %v\n", err)
+		}
+
+		// 从所在系列中移除
+		if err := s.seriesService.RemovePost(ctx, id); err != nil {
+			fmt.Printf("从系列中移除帖子失败: %v\n", err)
+		}
+
+		// 清除相关文章推荐
+		if err := s.relatedService.RemovePost(ctx, id); err != nil {
+			fmt.Printf("删除相关文章推荐失败: %v\n", err)
+		}
+
 		// 删除帖子
-		return s.postSQL.DeletePost(ctx, id)
+		if err := s.postSQL.DeletePost(ctx, id); err != nil {
+			return err
+		}
+
+		// 从全文索引和搜索联想中移除
+		if err := s.postIndex.DeletePost(ctx, id); err != nil {
+			fmt.Printf("删除帖子索引失败: %v\n", err)
+		}
+		if err := s.suggestCache.RemoveSuggestion(ctx, suggestTypePost, id); err != nil {
+			fmt.Printf("删除搜索联想失败: %v\n", err)
+		}
+		return nil
 	})
 }
 
@@ -683,7 +962,7 @@ func (s *postService) ListPosts(ctx context.Context, page, size int) ([]*model.P
 	// 统计总数
 	s.db.WithContext(ctx).
 		Model(&model.Post{}).
-		Where("visibility = ?", model.VisibilityPublic).
+		Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false).
 		Count(&total)
 
 	// 查询帖子并预加载关联数据
@@ -693,7 +972,8 @@ func (s *postService) ListPosts(ctx context.Context, page, size int) ([]*model.P
 		}).
 		Preload("Category").
 		Preload("Tags").
-		Where("visibility = ?", model.VisibilityPublic).
+		Preload("Cover").
+		Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false).
 		Order("created_at DESC").
 		Limit(size).
 		Offset(offset).
@@ -723,7 +1003,7 @@ func (s *postService) ListPostsByCategory(ctx context.Context, categoryID uint,
 	// 统计总数
 	s.db.WithContext(ctx).
 		Model(&model.Post{}).
-		Where("category_id = ? AND visibility = ?", categoryID, model.VisibilityPublic).
+		Where("category_id = ? AND visibility = ? AND hidden = ?", categoryID, model.VisibilityPublic, false).
 		Count(&total)
 
 	// 查询帖子并预加载关联数据
@@ -733,7 +1013,8 @@ func (s *postService) ListPostsByCategory(ctx context.Context, categoryID uint,
 		}).
 		Preload("Category").
 		Preload("Tags").
-		Where("category_id = ? AND visibility = ?", categoryID, model.VisibilityPublic).
+		Preload("Cover").
+		Where("category_id = ? AND visibility = ? AND hidden = ?", categoryID, model.VisibilityPublic, false).
 		Order("created_at DESC").
 		Limit(size).
 		Offset(offset).
@@ -764,7 +1045,7 @@ func (s *postService) ListPostsByTag(ctx context.Context, tagID uint, page, size
 	s.db.WithContext(ctx).
 		Model(&model.Post{}).
 		Joins("JOIN post_tags ON posts.id = post_tags.post_id").
-		Where("post_tags.tag_id = ? AND posts.visibility = ?", tagID, model.VisibilityPublic).
+		Where("post_tags.tag_id = ? AND posts.visibility = ? AND posts.hidden = ?", tagID, model.VisibilityPublic, false).
 		Count(&total)
 
 	// 查询帖子并预加载关联数据
@@ -774,8 +1055,9 @@ func (s *postService) ListPostsByTag(ctx context.Context, tagID uint, page, size
 		}).
 		Preload("Category").
 		Preload("Tags").
+		Preload("Cover").
 		Joins("JOIN post_tags ON posts.id = post_tags.post_id").
-		Where("post_tags.tag_id = ? AND posts.visibility = ?", tagID, model.VisibilityPublic).
+		Where("post_tags.tag_id = ? AND posts.visibility = ? AND posts.hidden = ?", tagID, model.VisibilityPublic, false).
 		Order("posts.created_at DESC").
 		Limit(size).
 		Offset(offset).
@@ -788,13 +1070,20 @@ func (s *postService) ListPostsByTag(ctx context.Context, tagID uint, page, size
 	return posts, total, nil
 }
 
-// SearchPosts 搜索帖子
-func (s *postService) SearchPosts(ctx context.Context, keyword string, page, size int) ([]*model.Post, int64, error) {
-	if page < 1 {
-		page = 1
+// SearchPosts 搜索帖子（支持过滤、分面统计和排序）
+func (s *postService) SearchPosts(ctx context.Context, req *SearchPostsRequest) (*SearchPostsResult, error) {
+	if req.Page < 1 {
+		req.Page = 1
 	}
-	if size < 1 || size > 100 {
-		size = 20
+	if req.Size < 1 || req.Size > 100 {
+		req.Size = 20
+	}
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	if req.Sort == "" {
+		req.Sort = SearchSortRelevance
+	}
+	if req.Sort != SearchSortRelevance && req.Sort != SearchSortDate && req.Sort != SearchSortPopularity {
+		return nil, ErrInvalidSearchSort
 	}
 
 	// 限流检查：搜索操作比较消耗资源
@@ -806,47 +1095,283 @@ func (s *postService) SearchPosts(ctx context.Context, keyword string, page, siz
 	}
 
 	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
-		return nil, 0, ErrRateLimited
+		return nil, ErrRateLimited
 	}
 
-	offset := (page - 1) * size
+	offset := (req.Page - 1) * req.Size
 
-	keyword = strings.TrimSpace(keyword)
-	if keyword == "" {
-		return s.ListPosts(ctx, page, size)
+	query := &searchdao.PostSearchQuery{
+		Keyword:    req.Keyword,
+		CategoryID: req.CategoryID,
+		TagIDs:     req.TagIDs,
+		AuthorID:   req.AuthorID,
+		From:       req.From,
+		To:         req.To,
+		Sort:       searchdao.SortRelevance,
+		Facets:     true,
+		Highlight:  true,
+		Offset:     offset,
+		Size:       req.Size,
+	}
+	if req.Sort == SearchSortDate {
+		query.Sort = searchdao.SortDate
+	}
+
+	var (
+		result *searchdao.PostSearchResult
+		total  int64
+		err    error
+	)
+	if req.Sort == SearchSortPopularity {
+		result, total, err = s.searchByPopularity(ctx, query)
+	} else {
+		result, err = s.postIndex.SearchPosts(ctx, query)
+		if result != nil {
+			total = int64(result.Total)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("搜索帖子失败: %w", err)
 	}
 
-	var posts []*model.Post
-	var total int64
+	facets, err := s.resolveFacets(ctx, result.Facets)
+	if err != nil {
+		return nil, fmt.Errorf("获取分面统计失败: %w", err)
+	}
 
-	searchPattern := "%" + keyword + "%"
+	posts, err := s.loadSearchHits(ctx, result.Hits)
+	if err != nil {
+		return nil, err
+	}
 
-	// 统计总数
-	s.db.WithContext(ctx).
+	return &SearchPostsResult{
+		Posts:  posts,
+		Total:  total,
+		Facets: facets,
+	}, nil
+}
+
+// searchByPopularity 先从索引取出全部候选帖子，再按数据库中的互动数据排序分页
+func (s *postService) searchByPopularity(ctx context.Context, query *searchdao.PostSearchQuery) (*searchdao.PostSearchResult, int64, error) {
+	offset, size := query.Offset, query.Size
+
+	candidates := *query
+	candidates.Offset = 0
+	candidates.Size = maxPopularityCandidates
+	candidates.Highlight = false
+	all, err := s.postIndex.SearchPosts(ctx, &candidates)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(all.Total)
+	if total > maxPopularityCandidates {
+		total = maxPopularityCandidates
+	}
+	result := &searchdao.PostSearchResult{Total: all.Total, Facets: all.Facets}
+	if len(all.Hits) == 0 {
+		return result, total, nil
+	}
+
+	ids := make([]uint, 0, len(all.Hits))
+	for _, hit := range all.Hits {
+		ids = append(ids, hit.ID)
+	}
+	var pageIDs []uint
+	err = s.db.WithContext(ctx).
 		Model(&model.Post{}).
-		Where("(title LIKE ? OR content LIKE ? OR summary LIKE ? OR author_name LIKE ?) AND visibility = ?",
-			searchPattern, searchPattern, searchPattern, searchPattern, model.VisibilityPublic).
-		Count(&total)
+		Where("id IN ?", ids).
+		Order(popularityOrder).
+		Limit(size).
+		Offset(offset).
+		Pluck("id", &pageIDs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(pageIDs) == 0 {
+		return result, total, nil
+	}
+
+	// 只为当前页的帖子生成高亮片段
+	page := *query
+	page.IDs = pageIDs
+	page.Facets = false
+	page.Offset = 0
+	page.Size = len(pageIDs)
+	highlighted, err := s.postIndex.SearchPosts(ctx, &page)
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uint]*searchdao.PostHit, len(highlighted.Hits))
+	for _, hit := range highlighted.Hits {
+		byID[hit.ID] = hit
+	}
+	for _, id := range pageIDs {
+		if hit, ok := byID[id]; ok {
+			result.Hits = append(result.Hits, hit)
+		}
+	}
+	return result, total, nil
+}
+
+// resolveFacets 将分面统计中的分类/标签ID转换为名称
+func (s *postService) resolveFacets(ctx context.Context, facets map[string][]*searchdao.FacetTerm) (*SearchFacets, error) {
+	result := &SearchFacets{
+		Categories: []*FacetCount{},
+		Tags:       []*FacetCount{},
+	}
+
+	categoryTerms := facets[searchdao.FacetCategory]
+	if len(categoryTerms) > 0 {
+		ids := make([]uint, 0, len(categoryTerms))
+		for _, t := range categoryTerms {
+			ids = append(ids, t.ID)
+		}
+		var categories []*model.Category
+		if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&categories).Error; err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]*model.Category, len(categories))
+		for _, c := range categories {
+			byID[c.ID] = c
+		}
+		for _, t := range categoryTerms {
+			if c, ok := byID[t.ID]; ok {
+				result.Categories = append(result.Categories, &FacetCount{ID: c.ID, Name: c.Name, Slug: c.Slug, Count: t.Count})
+			}
+		}
+	}
+
+	tagTerms := facets[searchdao.FacetTag]
+	if len(tagTerms) > 0 {
+		ids := make([]uint, 0, len(tagTerms))
+		for _, t := range tagTerms {
+			ids = append(ids, t.ID)
+		}
+		var tags []*model.Tag
+		if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&tags).Error; err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]*model.Tag, len(tags))
+		for _, t := range tags {
+			byID[t.ID] = t
+		}
+		for _, t := range tagTerms {
+			if tag, ok := byID[t.ID]; ok {
+				result.Tags = append(result.Tags, &FacetCount{ID: tag.ID, Name: tag.Name, Slug: tag.Slug, Count: t.Count})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// loadSearchHits 按命中顺序加载帖子，并附加相关度和高亮片段
+func (s *postService) loadSearchHits(ctx context.Context, hits []*searchdao.PostHit) ([]*model.Post, error) {
+	if len(hits) == 0 {
+		return []*model.Post{}, nil
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
 
 	// 查询帖子并预加载关联数据
+	var found []*model.Post
 	err := s.db.WithContext(ctx).
 		Preload("Author", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, name, avatar_url")
 		}).
 		Preload("Category").
 		Preload("Tags").
-		Where("(title LIKE ? OR content LIKE ? OR summary LIKE ? OR author_name LIKE ?) AND visibility = ?",
-			searchPattern, searchPattern, searchPattern, searchPattern, model.VisibilityPublic).
-		Order("created_at DESC").
-		Limit(size).
-		Offset(offset).
-		Find(&posts).Error
+		Preload("Cover").
+		Where("id IN ? AND visibility = ? AND hidden = ?", ids, model.VisibilityPublic, false).
+		Find(&found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*model.Post, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+	posts := make([]*model.Post, 0, len(found))
+	for _, hit := range hits {
+		post, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		post.Score = hit.Score
+		post.Highlights = hit.Highlights
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// SuggestSearch 按前缀联想帖子标题和标签（支持拼音前缀）
+func (s *postService) SuggestSearch(ctx context.Context, prefix string, limit int) ([]*redis.Suggestion, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return []*redis.Suggestion{}, nil
+	}
+	if limit < 1 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+
+	// 限流检查：输入时会频繁请求
+	ip := utils.GetIPFromContext(ctx)
+	rateLimitKey := fmt.Sprintf("search_suggest:ip:%s", ip)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Minute,
+		MaxRequests: 300,
+	}
+
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return nil, ErrRateLimited
+	}
+
+	return s.suggestCache.Suggest(ctx, prefix, limit)
+}
 
+// publishPostAccess 通知各实例帖子的可见性或隐藏状态已变化，取消无权查看者的实时推送
+func (s *postService) publishPostAccess(ctx context.Context, postID uint) {
+	post, err := s.postSQL.GetPostByID(ctx, postID)
 	if err != nil {
-		return nil, 0, err
+		fmt.Printf("获取帖子可见性失败: %v\n", err)
+		return
 	}
 
-	return posts, total, nil
+	access := &streamservice.PostAccess{
+		AuthorID:   post.UserID,
+		Visibility: post.Visibility,
+		Hidden:     post.Hidden,
+	}
+	if err := s.streamService.Publish(ctx, streamservice.EventPostAccess, 0, postID, access); err != nil {
+		fmt.Printf("推送帖子可见性失败: %v\n", err)
+	}
+}
+
+// publishPostStats 推送帖子最新的点赞/收藏数
+func (s *postService) publishPostStats(ctx context.Context, postID uint) {
+	post, err := s.postSQL.GetPostByID(ctx, postID)
+	if err != nil {
+		fmt.Printf("获取帖子计数失败: %v\n", err)
+		return
+	}
+
+	pending := s.pendingCounters(ctx, postID)
+	data := map[string]interface{}{
+		"post_id": postID,
+		"likes":   withPending(post.Liketimes, pending[counterservice.FieldLikes]),
+		"stars":   withPending(post.Staredtimes, pending[counterservice.FieldStars]),
+	}
+	if err := s.streamService.Publish(ctx, streamservice.EventPostStats, 0, postID, data); err != nil {
+		fmt.Printf("推送帖子计数失败: %v\n", err)
+	}
 }
 
 // LikePost 点赞帖子（完整分布式锁实现）
@@ -897,12 +1422,8 @@ func (s *postService) LikePost(ctx context.Context, postID uint) error {
 				return fmt.Errorf("保存点赞记录失败: %w", err)
 			}
 
-			// 6.2 更新帖子点赞数
-			updates := map[string]interface{}{
-				"liketimes":  post.Liketimes + 1,
-				"updated_at": time.Now(),
-			}
-			if err := s.postSQL.UpdatePost(ctx, postID, updates); err != nil {
+			// 6.2 更新帖子点赞数（定时写入数据库）
+			if err := s.counterService.Incr(ctx, postID, counterservice.FieldLikes, 1); err != nil {
 				return fmt.Errorf("更新帖子点赞数失败: %w", err)
 			}
 
@@ -919,10 +1440,30 @@ func (s *postService) LikePost(ctx context.Context, postID uint) error {
 
 			return nil
 		})
+		if err != nil {
+			return err
+		}
 
-		return err
+		// 7. 通知帖子作者
+		if err := s.notificationService.Notify(ctx, &notificationservice.NotificationEvent{
+			UserID:  post.UserID,
+			ActorID: currentUser.ID,
+			Type:    model.NotificationTypeLike,
+			PostID:  postID,
+		}); err != nil {
+			fmt.Printf("创建点赞通知失败: %v\n", err)
+		}
+
+		return nil
 	})
 
+	if err == nil {
+		s.publishPostStats(ctx, postID)
+		if err := s.trendingService.Record(ctx, postID, trendingservice.EventLike); err != nil {
+			fmt.Printf("记录文章热度失败: %v\n", err)
+		}
+	}
+
 	return err
 }
 
@@ -939,8 +1480,7 @@ func (s *postService) UnlikePost(ctx context.Context, postID uint) error {
 
 	err = s.lockManager.GetLock(lockKey, 10*time.Second).Mutex(ctx, func() error {
 		// 3. 检查帖子是否存在
-		post, err := s.postSQL.GetPostByID(ctx, postID)
-		if err != nil {
+		if _, err := s.postSQL.GetPostByID(ctx, postID); err != nil {
 			return ErrPostNotFound
 		}
 
@@ -963,15 +1503,9 @@ func (s *postService) UnlikePost(ctx context.Context, postID uint) error {
 				return fmt.Errorf("删除点赞记录失败: %w", err)
 			}
 
-			// 5.2 更新帖子点赞数
-			if post.Liketimes > 0 {
-				updates := map[string]interface{}{
-					"liketimes":  post.Liketimes - 1,
-					"updated_at": time.Now(),
-				}
-				if err := s.postSQL.UpdatePost(ctx, postID, updates); err != nil {
-					return fmt.Errorf("更新帖子点赞数失败: %w", err)
-				}
+			// 5.2 更新帖子点赞数（定时写入数据库）
+			if err := s.counterService.Incr(ctx, postID, counterservice.FieldLikes, -1); err != nil {
+				return fmt.Errorf("更新帖子点赞数失败: %w", err)
 			}
 
 			// 5.3 从Redis缓存删除
@@ -991,6 +1525,10 @@ func (s *postService) UnlikePost(ctx context.Context, postID uint) error {
 		return err
 	})
 
+	if err == nil {
+		s.publishPostStats(ctx, postID)
+	}
+
 	return err
 }
 
@@ -1002,13 +1540,13 @@ func (s *postService) GetPostLikes(ctx context.Context, postID uint) (uint, erro
 		return uint(count), nil
 	}
 
-	// 2. 从MySQL获取
+	// 2. 从MySQL获取（加上尚未写入的增量）
 	post, err := s.postSQL.GetPostByID(ctx, postID)
 	if err != nil {
 		return 0, ErrPostNotFound
 	}
 
-	return post.Liketimes, nil
+	return withPending(post.Liketimes, s.pendingCounters(ctx, postID)[counterservice.FieldLikes]), nil
 }
 
 // IsPostLiked 检查当前用户是否点赞过帖子
@@ -1082,12 +1620,8 @@ func (s *postService) StarPost(ctx context.Context, postID uint) error {
 				return fmt.Errorf("保存收藏记录失败: %w", err)
 			}
 
-			// 6.2 更新帖子收藏数
-			updates := map[string]interface{}{
-				"staredtimes": post.Staredtimes + 1,
-				"updated_at":  time.Now(),
-			}
-			if err := s.postSQL.UpdatePost(ctx, postID, updates); err != nil {
+			// 6.2 更新帖子收藏数（定时写入数据库）
+			if err := s.counterService.Incr(ctx, postID, counterservice.FieldStars, 1); err != nil {
 				return fmt.Errorf("更新帖子收藏数失败: %w", err)
 			}
 
@@ -1104,10 +1638,30 @@ func (s *postService) StarPost(ctx context.Context, postID uint) error {
 
 			return nil
 		})
+		if err != nil {
+			return err
+		}
 
-		return err
+		// 7. 通知帖子作者
+		if err := s.notificationService.Notify(ctx, &notificationservice.NotificationEvent{
+			UserID:  post.UserID,
+			ActorID: currentUser.ID,
+			Type:    model.NotificationTypeStar,
+			PostID:  postID,
+		}); err != nil {
+			fmt.Printf("创建收藏通知失败: %v\n", err)
+		}
+
+		return nil
 	})
 
+	if err == nil {
+		s.publishPostStats(ctx, postID)
+		if err := s.trendingService.Record(ctx, postID, trendingservice.EventStar); err != nil {
+			fmt.Printf("记录文章热度失败: %v\n", err)
+		}
+	}
+
 	return err
 }
 
@@ -1124,8 +1678,7 @@ func (s *postService) UnstarPost(ctx context.Context, postID uint) error {
 
 	err = s.lockManager.GetLock(lockKey, 10*time.Second).Mutex(ctx, func() error {
 		// 3. 检查帖子是否存在
-		post, err := s.postSQL.GetPostByID(ctx, postID)
-		if err != nil {
+		if _, err := s.postSQL.GetPostByID(ctx, postID); err != nil {
 			return ErrPostNotFound
 		}
 
@@ -1148,15 +1701,9 @@ func (s *postService) UnstarPost(ctx context.Context, postID uint) error {
 				return fmt.Errorf("删除收藏记录失败: %w", err)
 			}
 
-			// 5.2 更新帖子收藏数
-			if post.Staredtimes > 0 {
-				updates := map[string]interface{}{
-					"staredtimes": post.Staredtimes - 1,
-					"updated_at":  time.Now(),
-				}
-				if err := s.postSQL.UpdatePost(ctx, postID, updates); err != nil {
-					return fmt.Errorf("更新帖子收藏数失败: %w", err)
-				}
+			// 5.2 更新帖子收藏数（定时写入数据库）
+			if err := s.counterService.Incr(ctx, postID, counterservice.FieldStars, -1); err != nil {
+				return fmt.Errorf("更新帖子收藏数失败: %w", err)
 			}
 
 			// 5.3 从Redis缓存删除
@@ -1176,6 +1723,10 @@ func (s *postService) UnstarPost(ctx context.Context, postID uint) error {
 		return err
 	})
 
+	if err == nil {
+		s.publishPostStats(ctx, postID)
+	}
+
 	return err
 }
 
@@ -1187,13 +1738,13 @@ func (s *postService) GetPostStars(ctx context.Context, postID uint) (uint, erro
 		return uint(count), nil
 	}
 
-	// 2. 从MySQL获取
+	// 2. 从MySQL获取（加上尚未写入的增量）
 	post, err := s.postSQL.GetPostByID(ctx, postID)
 	if err != nil {
 		return 0, ErrPostNotFound
 	}
 
-	return post.Staredtimes, nil
+	return withPending(post.Staredtimes, s.pendingCounters(ctx, postID)[counterservice.FieldStars]), nil
 }
 
 // IsPostStarred 检查当前用户是否收藏过帖子
@@ -1227,153 +1778,120 @@ func (s *postService) GetPostCommentsCount(ctx context.Context, postID uint) (ui
 		return uint(count), nil
 	}
 
-	// 2. 从MySQL获取
+	// 2. 从MySQL获取（加上尚未写入的增量）
 	post, err := s.postSQL.GetPostByID(ctx, postID)
 	if err != nil {
 		return 0, ErrPostNotFound
 	}
 
-	return post.CommentNumbers, nil
+	return withPending(post.CommentNumbers, s.pendingCounters(ctx, postID)[counterservice.FieldComments]), nil
 }
 
-// IncrementComments 增加评论数（带分布式锁）
+// IncrementComments 增加评论数（计数定时写入数据库，不需要加锁）
 func (s *postService) IncrementComments(ctx context.Context, postID uint) error {
-	// 使用分布式锁
-	lockKey := fmt.Sprintf("post_comments:%d", postID)
-
-	return s.lockManager.GetLock(lockKey, 5*time.Second).Mutex(ctx, func() error {
-		// 1. 获取帖子
-		post, err := s.postSQL.GetPostByID(ctx, postID)
-		if err != nil {
-			return ErrPostNotFound
-		}
-
-		// 2. 开启事务
-		err = s.db.Transaction(func(tx *gorm.DB) error {
-			// 更新帖子评论数
-			updates := map[string]interface{}{
-				"comment_numbers": post.CommentNumbers + 1,
-				"updated_at":      time.Now(),
-			}
-			if err := s.postSQL.UpdatePost(ctx, postID, updates); err != nil {
-				return fmt.Errorf("更新帖子评论数失败: %w", err)
-			}
+	if _, err := s.postSQL.GetPostByID(ctx, postID); err != nil {
+		return ErrPostNotFound
+	}
 
-			// 更新Redis缓存
-			if err := s.commentCache.IncrCommentCount(ctx, postID); err != nil {
-				fmt.Printf("Redis评论数缓存失败: %v\n", err)
-			}
+	if err := s.counterService.Incr(ctx, postID, counterservice.FieldComments, 1); err != nil {
+		return fmt.Errorf("更新帖子评论数失败: %w", err)
+	}
 
-			// 清除缓存
-			s.hotPostLock.Lock()
-			delete(s.hotPostsCache, postID)
-			delete(s.hotPostsTTL, postID)
-			s.hotPostLock.Unlock()
+	// 更新Redis缓存
+	if err := s.commentCache.IncrCommentCount(ctx, postID); err != nil {
+		fmt.Printf("Redis评论数缓存失败: %v\n", err)
+	}
 
-			return nil
-		})
+	// 清除缓存
+	s.hotPostLock.Lock()
+	delete(s.hotPostsCache, postID)
+	delete(s.hotPostsTTL, postID)
+	s.hotPostLock.Unlock()
 
-		return err
-	})
+	return nil
 }
 
-// DecrementComments 减少评论数（带分布式锁）
+// DecrementComments 减少评论数（写入数据库时保证不小于0）
 func (s *postService) DecrementComments(ctx context.Context, postID uint) error {
-	// 使用分布式锁
-	lockKey := fmt.Sprintf("post_comments:%d", postID)
-
-	return s.lockManager.GetLock(lockKey, 5*time.Second).Mutex(ctx, func() error {
-		// 1. 获取帖子
-		post, err := s.postSQL.GetPostByID(ctx, postID)
-		if err != nil {
-			return ErrPostNotFound
-		}
-
-		// 2. 确保评论数不小于0
-		newCount := uint(0)
-		if post.CommentNumbers > 0 {
-			newCount = post.CommentNumbers - 1
-		}
-
-		// 3. 开启事务
-		err = s.db.Transaction(func(tx *gorm.DB) error {
-			// 更新帖子评论数
-			updates := map[string]interface{}{
-				"comment_numbers": newCount,
-				"updated_at":      time.Now(),
-			}
-			if err := s.postSQL.UpdatePost(ctx, postID, updates); err != nil {
-				return fmt.Errorf("更新帖子评论数失败: %w", err)
-			}
+	if _, err := s.postSQL.GetPostByID(ctx, postID); err != nil {
+		return ErrPostNotFound
+	}
 
-			// 更新Redis缓存
-			if err := s.commentCache.DecrCommentCount(ctx, postID); err != nil {
-				fmt.Printf("Redis评论数缓存失败: %v\n", err)
-			}
+	if err := s.counterService.Incr(ctx, postID, counterservice.FieldComments, -1); err != nil {
+		return fmt.Errorf("更新帖子评论数失败: %w", err)
+	}
 
-			// 清除缓存
-			s.hotPostLock.Lock()
-			delete(s.hotPostsCache, postID)
-			delete(s.hotPostsTTL, postID)
-			s.hotPostLock.Unlock()
+	// 更新Redis缓存
+	if err := s.commentCache.DecrCommentCount(ctx, postID); err != nil {
+		fmt.Printf("Redis评论数缓存失败: %v\n", err)
+	}
 
-			return nil
-		})
+	// 清除缓存
+	s.hotPostLock.Lock()
+	delete(s.hotPostsCache, postID)
+	delete(s.hotPostsTTL, postID)
+	s.hotPostLock.Unlock()
 
-		return err
-	})
+	return nil
 }
 
-// IncrementViews 增加浏览量（带分布式锁）
-func (s *postService) IncrementViews(ctx context.Context, postID uint) error {
-	// 使用分布式锁
-	lockKey := fmt.Sprintf("post_views:%d", postID)
-
-	return s.lockManager.GetLock(lockKey, 3*time.Second).Mutex(ctx, func() error {
-		// 1. 获取帖子
-		post, err := s.postSQL.GetPostByID(ctx, postID)
-		if err != nil {
-			return ErrPostNotFound
-		}
-
-		// 2. 开启事务
-		err = s.db.Transaction(func(tx *gorm.DB) error {
-			// 更新帖子浏览量
-			updates := map[string]interface{}{
-				"clicktimes": post.Clicktimes + 1,
-				"updated_at": time.Now(),
-			}
-			if err := s.postSQL.UpdatePost(ctx, postID, updates); err != nil {
-				return fmt.Errorf("更新帖子浏览量失败: %w", err)
-			}
+// IncrementViews 增加浏览量：原始浏览量每次都加，去重浏览量每位访客每天只加一次。
+// 是否为新访客由Redis的HyperLogLog判断，计数先写Redis再定时写入数据库，不需要加锁
+func (s *postService) IncrementViews(ctx context.Context, postID uint, viewer string) error {
+	// 1. Redis原始浏览量
+	if err := s.viewCache.IncrViewCount(ctx, postID); err != nil {
+		fmt.Printf("Redis浏览量缓存失败: %v\n", err)
+	}
 
-			// 更新Redis缓存
-			if err := s.viewCache.IncrViewCount(ctx, postID); err != nil {
-				fmt.Printf("Redis浏览量缓存失败: %v\n", err)
-			}
+	// 2. 是否为当天的新访客（Redis不可用时只计原始浏览量，避免刷新刷量）
+	unique, err := s.viewCache.AddViewer(ctx, postID, viewer, time.Now())
+	if err != nil {
+		fmt.Printf("记录文章访客失败: %v\n", err)
+	}
 
-			return nil
-		})
+	// 3. 更新帖子浏览量
+	if err := s.counterService.Incr(ctx, postID, counterservice.FieldPageViews, 1); err != nil {
+		return fmt.Errorf("更新帖子浏览量失败: %w", err)
+	}
+	if !unique {
+		return nil
+	}
+	if err := s.counterService.Incr(ctx, postID, counterservice.FieldViews, 1); err != nil {
+		return fmt.Errorf("更新帖子浏览量失败: %w", err)
+	}
 
-		return err
-	})
+	// 4. 去重后的浏览才计入热度
+	if err := s.trendingService.Record(ctx, postID, trendingservice.EventView); err != nil {
+		fmt.Printf("记录文章热度失败: %v\n", err)
+	}
+	return nil
 }
 
-// GetPostViews 获取帖子浏览量（带缓存）
+// GetPostViews 获取帖子去重浏览量
 func (s *postService) GetPostViews(ctx context.Context, postID uint) (uint, error) {
-	// 1. 尝试从Redis获取
-	count, err := s.viewCache.GetViewCount(ctx, postID)
-	if err == nil && count > 0 {
-		return uint(count), nil
-	}
-
-	// 2. 从MySQL获取
 	post, err := s.postSQL.GetPostByID(ctx, postID)
 	if err != nil {
 		return 0, ErrPostNotFound
 	}
 
-	return post.Clicktimes, nil
+	return withPending(post.Clicktimes, s.pendingCounters(ctx, postID)[counterservice.FieldViews]), nil
+}
+
+// pendingCounters 尚未写入数据库的计数增量（读取失败时按没有增量处理）
+func (s *postService) pendingCounters(ctx context.Context, postID uint) map[counterservice.Field]int64 {
+	pending, err := s.counterService.Pending(ctx, postID)
+	if err != nil {
+		fmt.Printf("获取待写入计数失败: %v\n", err)
+	}
+	return pending
+}
+
+// withPending 数据库中的计数加上待写入的增量
+func withPending(count uint, delta int64) uint {
+	if n := int64(count) + delta; n > 0 {
+		return uint(n)
+	}
+	return 0
 }
 
 // GetPostStats 获取帖子综合统计数据（带缓存和并行获取）
@@ -1392,12 +1910,27 @@ func (s *postService) GetPostStats(ctx context.Context, postID uint) (*PostStats
 	var mu sync.Mutex
 	var statsErr error
 
+	pending := s.pendingCounters(ctx, postID)
 	stats := &PostStats{
-		PostID:   postID,
-		Likes:    post.Liketimes,
-		Stars:    post.Staredtimes,
-		Comments: post.CommentNumbers,
-		Views:    post.Clicktimes,
+		PostID:    postID,
+		Likes:     withPending(post.Liketimes, pending[counterservice.FieldLikes]),
+		Stars:     withPending(post.Staredtimes, pending[counterservice.FieldStars]),
+		Comments:  withPending(post.CommentNumbers, pending[counterservice.FieldComments]),
+		Views:     withPending(post.Clicktimes, pending[counterservice.FieldViews]),
+		PageViews: withPending(post.PageViews, pending[counterservice.FieldPageViews]),
+	}
+
+	// 去重访客数（统计失败不影响其他数据）
+	now := time.Now()
+	if count, err := s.viewCache.CountViewers(ctx, postID, now, now); err == nil {
+		stats.TodayVisitors = count
+	} else {
+		fmt.Printf("获取今日访客数失败: %v\n", err)
+	}
+	if count, err := s.viewCache.CountViewers(ctx, postID, now.AddDate(0, 0, -6), now); err == nil {
+		stats.WeekVisitors = count
+	} else {
+		fmt.Printf("获取7日访客数失败: %v\n", err)
 	}
 
 	// 如果用户已登录，并行获取点赞和收藏状态
@@ -1442,3 +1975,229 @@ func (s *postService) GetPostStats(ctx context.Context, postID uint) (*PostStats
 
 	return stats, nil
 }
+
+// searchable 只有公开且未被隐藏的帖子进入全文索引
+func searchable(post *model.Post) bool {
+	return post.Visibility == model.VisibilityPublic && !post.Hidden
+}
+
+// postDocument 帖子转换为索引文档（需预加载Tags）
+func postDocument(post *model.Post) *searchdao.PostDocument {
+	tagIDs := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tagIDs = append(tagIDs, searchdao.KeywordID(tag.ID))
+	}
+	return &searchdao.PostDocument{
+		ID:         post.ID,
+		Title:      post.Title,
+		Summary:    post.Summary,
+		Content:    post.Content,
+		AuthorName: post.AuthorName,
+		CreatedAt:  post.CreatedAt,
+		AuthorID:   searchdao.KeywordID(post.UserID),
+		CategoryID: searchdao.KeywordID(post.CategoryID),
+		TagIDs:     tagIDs,
+	}
+}
+
+// 搜索联想项类型
+const (
+	suggestTypePost = "post"
+	suggestTypeTag  = "tag"
+)
+
+// popularityScore 帖子热度（与 popularityOrder 一致）
+func popularityScore(post *model.Post) float64 {
+	return float64(post.Clicktimes) + float64(post.Liketimes)*3 +
+		float64(post.Staredtimes)*5 + float64(post.CommentNumbers)*2
+}
+
+// suggestKeys 联想匹配键：小写原文，中文额外加上连写拼音
+func suggestKeys(text string) []string {
+	keys := []string{strings.ToLower(strings.TrimSpace(text))}
+	if py := utils.PinyinKey(text); py != "" {
+		keys = append(keys, py)
+	}
+	return keys
+}
+
+// syncPostSuggestions 更新帖子标题及其标签的搜索联想
+func syncPostSuggestions(ctx context.Context, db *gorm.DB, suggestCache redis.SuggestCache, post *model.Post) error {
+	if err := suggestCache.SetSuggestion(ctx, &redis.Suggestion{
+		Type:   suggestTypePost,
+		ID:     post.ID,
+		Text:   post.Title,
+		Weight: popularityScore(post),
+	}, suggestKeys(post.Title)); err != nil {
+		return err
+	}
+	if len(post.Tags) == 0 {
+		return nil
+	}
+
+	// 标签按使用次数排序
+	tagIDs := make([]uint, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	counts, err := countTagPosts(ctx, db, tagIDs)
+	if err != nil {
+		return err
+	}
+	for _, tag := range post.Tags {
+		if err := suggestCache.SetSuggestion(ctx, &redis.Suggestion{
+			Type:   suggestTypeTag,
+			ID:     tag.ID,
+			Text:   tag.Name,
+			Weight: float64(counts[tag.ID]),
+		}, suggestKeys(tag.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countTagPosts 统计标签下的帖子数
+func countTagPosts(ctx context.Context, db *gorm.DB, tagIDs []uint) (map[uint]int64, error) {
+	var rows []struct {
+		TagID uint
+		Count int64
+	}
+	err := db.WithContext(ctx).
+		Model(&model.PostTag{}).
+		Select("tag_id, COUNT(*) AS count").
+		Where("tag_id IN ?", tagIDs).
+		Group("tag_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+	return counts, nil
+}
+
+// syncSearchIndex 按帖子当前状态更新全文索引和搜索联想
+func (s *postService) syncSearchIndex(ctx context.Context, postID uint) {
+	var post model.Post
+	err := s.db.WithContext(ctx).Preload("Tags").First(&post, postID).Error
+	if err != nil || !searchable(&post) {
+		if err := s.postIndex.DeletePost(ctx, postID); err != nil {
+			fmt.Printf("删除帖子索引失败: %v\n", err)
+		}
+		if err := s.suggestCache.RemoveSuggestion(ctx, suggestTypePost, postID); err != nil {
+			fmt.Printf("删除搜索联想失败: %v\n", err)
+		}
+		return
+	}
+
+	if err := s.postIndex.IndexPost(ctx, postDocument(&post)); err != nil {
+		fmt.Printf("更新帖子索引失败: %v\n", err)
+	}
+	if err := syncPostSuggestions(ctx, s.db, s.suggestCache, &post); err != nil {
+		fmt.Printf("更新搜索联想失败: %v\n", err)
+	}
+}
+
+// WalkPublicPosts 按ID顺序分批遍历所有公开文章
+func (s *postService) WalkPublicPosts(ctx context.Context, batchSize int, fn func(posts []*model.Post) error) error {
+	if batchSize < 1 {
+		batchSize = 100
+	}
+
+	var lastID uint
+	for {
+		var posts []*model.Post
+		err := s.db.WithContext(ctx).
+			Preload("Author", func(db *gorm.DB) *gorm.DB {
+				return db.Select("id, name, avatar_url, bio, status")
+			}).
+			Preload("Category").
+			Preload("Tags").
+			Preload("Cover").
+			Where("id > ? AND visibility = ? AND hidden = ?", lastID, model.VisibilityPublic, false).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&posts).Error
+		if err != nil {
+			return fmt.Errorf("遍历文章失败: %w", err)
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+
+		if err := fn(posts); err != nil {
+			return err
+		}
+		if len(posts) < batchSize {
+			return nil
+		}
+		lastID = posts[len(posts)-1].ID
+	}
+}
+
+// RebuildSearchIndex 从数据库重建全文索引（需要管理员权限）
+func (s *postService) RebuildSearchIndex(ctx context.Context) (int, error) {
+	currentUser, err := s.getCurrentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if currentUser.Relation != model.UserRoleAdmin {
+		return 0, ErrNotAdmin
+	}
+
+	return RebuildPostIndex(ctx, s.db, s.postIndex, s.suggestCache)
+}
+
+// RebuildPostIndex 从数据库重建帖子全文索引和搜索联想（也供命令行直接调用）
+func RebuildPostIndex(ctx context.Context, db *gorm.DB, postIndex searchdao.PostIndex, suggestCache redis.SuggestCache) (int, error) {
+	if err := suggestCache.ClearSuggestions(ctx); err != nil {
+		return 0, fmt.Errorf("清空搜索联想失败: %w", err)
+	}
+
+	return postIndex.Rebuild(ctx, func(offset, limit int) ([]*searchdao.PostDocument, error) {
+		var posts []*model.Post
+		err := db.WithContext(ctx).
+			Preload("Tags").
+			Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false).
+			Order("id ASC").
+			Limit(limit).
+			Offset(offset).
+			Find(&posts).Error
+		if err != nil {
+			return nil, err
+		}
+
+		docs := make([]*searchdao.PostDocument, 0, len(posts))
+		for _, post := range posts {
+			docs = append(docs, postDocument(post))
+			if err := syncPostSuggestions(ctx, db, suggestCache, post); err != nil {
+				return nil, fmt.Errorf("更新搜索联想失败: %w", err)
+			}
+		}
+		return docs, nil
+	})
+}
+
+// SetPostHidden 隐藏/恢复帖子（用于举报审核），hiddenBy 为隐藏来源，为空时恢复
+func (s *postService) SetPostHidden(ctx context.Context, postID uint, hiddenBy string) error {
+	updates := map[string]interface{}{"hidden": hiddenBy != "", "hidden_by": hiddenBy}
+	if err := s.postSQL.UpdatePost(ctx, postID, updates); err != nil {
+		return fmt.Errorf("更新帖子隐藏状态失败: %w", err)
+	}
+
+	// 清除缓存
+	s.hotPostLock.Lock()
+	delete(s.hotPostsCache, postID)
+	delete(s.hotPostsTTL, postID)
+	s.hotPostLock.Unlock()
+
+	s.syncSearchIndex(ctx, postID)
+	s.relatedService.Refresh(postID)
+	s.publishPostAccess(ctx, postID)
+
+	return nil
+}
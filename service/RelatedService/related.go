@@ -0,0 +1,526 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	redis "blog/dao/redis"
+	"blog/model"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrPostNotFound = errors.New("文章不存在")
+	ErrNotAdmin     = errors.New("需要管理员权限")
+	ErrRateLimited  = errors.New("操作过于频繁，请稍后再试")
+)
+
+const (
+	// 每篇文章保存的相关文章数，也是接口最多返回的数量
+	maxRelated = 10
+	// 接口默认返回的数量
+	defaultRelated = 5
+	// 每篇文章保存的词数（按出现次数取前面的）
+	maxTerms = 64
+	// 标题中的词按出现多次计算
+	titleWeight = 3
+	// 候选文章数：同分类或有相同标签的，以及最近发布的
+	maxTaggedCandidates = 200
+	maxRecentCandidates = 100
+	// 低于该相似度的不算相关
+	minScore = 0.01
+	// 后台计算的超时时间
+	computeTimeout = time.Minute
+)
+
+// 相似度由内容、标签、分类三部分加权组成
+const (
+	contentWeight  = 0.6
+	tagWeight      = 0.25
+	categoryWeight = 0.15
+)
+
+type RelatedService interface {
+	// ListRelated 文章的相关推荐；预先计算的结果不足时用同分类最新的文章补足
+	ListRelated(ctx context.Context, postID uint, limit int) ([]*model.Post, error)
+
+	// Refresh 在后台重新计算文章的相关推荐（发布、修改后调用，不阻塞请求）
+	Refresh(postID uint)
+	// Compute 重新计算文章的相关推荐，并把它加入相关文章各自的推荐中
+	Compute(ctx context.Context, postID uint) error
+	// RemovePost 文章删除时清除其词频和推荐；其他文章推荐中的该文章在读取时过滤
+	RemovePost(ctx context.Context, postID uint) error
+	// Rebuild 重新计算所有公开文章的相关推荐（需要管理员权限）
+	Rebuild(ctx context.Context, userID uint) (int, error)
+}
+
+type relatedService struct {
+	postSQL mysql.PostSQL
+	userSQL mysql.UserSQL
+
+	// 数据库（候选文章查询）
+	db *gorm.DB
+
+	// 词频和推荐结果
+	relatedCache redis.RelatedCache
+
+	// 分布式锁管理器
+	lockManager *utils.LockManager
+
+	// 限流器
+	rateLimiter *utils.RateLimiter
+}
+
+func NewRelatedService(
+	postSQL mysql.PostSQL,
+	userSQL mysql.UserSQL,
+	db *gorm.DB,
+	relatedCache redis.RelatedCache,
+	lockManager *utils.LockManager,
+	rateLimiter *utils.RateLimiter,
+) RelatedService {
+	return &relatedService{
+		postSQL:      postSQL,
+		userSQL:      userSQL,
+		db:           db,
+		relatedCache: relatedCache,
+		lockManager:  lockManager,
+		rateLimiter:  rateLimiter,
+	}
+}
+
+// public 只推荐公开且未被隐藏的文章
+func public(db *gorm.DB) *gorm.DB {
+	return db.Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false)
+}
+
+// ListRelated 相关推荐
+func (s *relatedService) ListRelated(ctx context.Context, postID uint, limit int) ([]*model.Post, error) {
+	// 限流检查
+	ip := utils.GetIPFromContext(ctx)
+	rateLimitKey := fmt.Sprintf("related_posts:ip:%s", ip)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Minute,
+		MaxRequests: 300,
+	}
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return nil, ErrRateLimited
+	}
+
+	if limit < 1 || limit > maxRelated {
+		limit = defaultRelated
+	}
+
+	post, err := s.postSQL.GetPostByID(ctx, postID)
+	if err != nil || post.Visibility != model.VisibilityPublic || post.Hidden {
+		return nil, ErrPostNotFound
+	}
+
+	ids, err := s.relatedCache.GetRelated(ctx, postID, maxRelated)
+	if err != nil {
+		// 推荐结果读取失败时仍返回同分类的文章
+		fmt.Printf("读取相关文章失败: %v\n", err)
+	}
+
+	var posts []*model.Post
+	if len(ids) > 0 {
+		var found []*model.Post
+		err := s.preload(ctx).Scopes(public).Where("id IN ?", ids).Find(&found).Error
+		if err != nil {
+			return nil, fmt.Errorf("查询相关文章失败: %w", err)
+		}
+		byID := make(map[uint]*model.Post, len(found))
+		for _, p := range found {
+			byID[p.ID] = p
+		}
+		for _, id := range ids {
+			if p, ok := byID[id]; ok && len(posts) < limit {
+				posts = append(posts, p)
+			}
+		}
+	}
+
+	// 同分类最新的文章补足
+	if len(posts) < limit {
+		exclude := []uint{postID}
+		for _, p := range posts {
+			exclude = append(exclude, p.ID)
+		}
+		var recent []*model.Post
+		err := s.preload(ctx).Scopes(public).
+			Where("category_id = ? AND id NOT IN ?", post.CategoryID, exclude).
+			Order("created_at DESC, id DESC").
+			Limit(limit - len(posts)).
+			Find(&recent).Error
+		if err != nil {
+			return nil, fmt.Errorf("查询同分类文章失败: %w", err)
+		}
+		posts = append(posts, recent...)
+	}
+
+	if posts == nil {
+		posts = []*model.Post{}
+	}
+	return posts, nil
+}
+
+// preload 列表展示用的文章查询（不含正文）
+func (s *relatedService) preload(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).
+		Omit("content", "rendered").
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, avatar_url, bio")
+		}).
+		Preload("Category").
+		Preload("Cover")
+}
+
+// Refresh 后台计算，同一篇文章同时只有一个计算在进行
+func (s *relatedService) Refresh(postID uint) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), computeTimeout)
+		defer cancel()
+		if err := s.Compute(ctx, postID); err != nil {
+			fmt.Printf("计算相关文章失败: %v\n", err)
+		}
+	}()
+}
+
+// Compute 计算相关推荐
+func (s *relatedService) Compute(ctx context.Context, postID uint) error {
+	lockKey := fmt.Sprintf("related_compute:%d", postID)
+	return s.lockManager.GetLock(lockKey, computeTimeout).Mutex(ctx, func() error {
+		var post model.Post
+		err := s.db.WithContext(ctx).Preload("Tags").First(&post, postID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (post.Visibility != model.VisibilityPublic || post.Hidden)) {
+			// 不公开的文章不参与推荐
+			return s.RemovePost(ctx, postID)
+		}
+		if err != nil {
+			return fmt.Errorf("获取文章失败: %w", err)
+		}
+
+		terms := extractTerms(&post)
+		if err := s.relatedCache.SetPostTerms(ctx, postID, terms); err != nil {
+			return fmt.Errorf("保存文章词频失败: %w", err)
+		}
+
+		candidates, err := s.candidates(ctx, &post)
+		if err != nil {
+			return err
+		}
+		scores, err := s.score(ctx, &post, terms, candidates)
+		if err != nil {
+			return err
+		}
+
+		if err := s.relatedCache.SetRelated(ctx, postID, scores); err != nil {
+			return fmt.Errorf("保存相关文章失败: %w", err)
+		}
+		// 相似度是对称的，新文章也出现在相关文章的推荐中
+		for id, score := range scores {
+			if err := s.relatedCache.AddRelated(ctx, id, postID, score, maxRelated); err != nil {
+				return fmt.Errorf("保存相关文章失败: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// candidates 候选文章：同分类或有相同标签的，再加上最近发布的（只有内容相似的文章）
+func (s *relatedService) candidates(ctx context.Context, post *model.Post) ([]*model.Post, error) {
+	tagIDs := make([]uint, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	base := func() *gorm.DB {
+		return s.db.WithContext(ctx).
+			Select("id, category_id").
+			Preload("Tags").
+			Scopes(public).
+			Where("id <> ?", post.ID).
+			Order("id DESC")
+	}
+
+	var tagged []*model.Post
+	query := base()
+	if len(tagIDs) > 0 {
+		query = query.Where("category_id = ? OR id IN (?)", post.CategoryID,
+			s.db.Model(&model.PostTag{}).Select("post_id").Where("tag_id IN ?", tagIDs))
+	} else {
+		query = query.Where("category_id = ?", post.CategoryID)
+	}
+	if err := query.Limit(maxTaggedCandidates).Find(&tagged).Error; err != nil {
+		return nil, fmt.Errorf("查询候选文章失败: %w", err)
+	}
+
+	var recent []*model.Post
+	if err := base().Limit(maxRecentCandidates).Find(&recent).Error; err != nil {
+		return nil, fmt.Errorf("查询候选文章失败: %w", err)
+	}
+
+	seen := make(map[uint]bool, len(tagged)+len(recent))
+	candidates := make([]*model.Post, 0, len(tagged)+len(recent))
+	for _, p := range append(tagged, recent...) {
+		if !seen[p.ID] {
+			seen[p.ID] = true
+			candidates = append(candidates, p)
+		}
+	}
+	return candidates, nil
+}
+
+// score 计算与各候选文章的相似度，返回最高的 maxRelated 篇
+func (s *relatedService) score(ctx context.Context, post *model.Post, terms map[string]float64, candidates []*model.Post) (map[uint]float64, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, c.ID)
+	}
+	candidateTerms, err := s.relatedCache.GetPostTerms(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("读取文章词频失败: %w", err)
+	}
+
+	// 所有出现的词的文档频率
+	seen := make(map[string]bool)
+	var allTerms []string
+	for _, t := range append([]map[string]float64{terms}, mapValues(candidateTerms)...) {
+		for term := range t {
+			if !seen[term] {
+				seen[term] = true
+				allTerms = append(allTerms, term)
+			}
+		}
+	}
+	docFreqs, total, err := s.relatedCache.DocFreqs(ctx, allTerms)
+	if err != nil {
+		return nil, fmt.Errorf("读取文档频率失败: %w", err)
+	}
+	vector := tfidf(terms, docFreqs, total)
+
+	postTags := make(map[uint]bool, len(post.Tags))
+	for _, tag := range post.Tags {
+		postTags[tag.ID] = true
+	}
+
+	type scored struct {
+		id    uint
+		score float64
+	}
+	var results []scored
+	for _, c := range candidates {
+		score := contentWeight * cosine(vector, tfidf(candidateTerms[c.ID], docFreqs, total))
+
+		if len(postTags) > 0 && len(c.Tags) > 0 {
+			shared := 0
+			for _, tag := range c.Tags {
+				if postTags[tag.ID] {
+					shared++
+				}
+			}
+			score += tagWeight * float64(shared) / math.Sqrt(float64(len(postTags)*len(c.Tags)))
+		}
+		if c.CategoryID == post.CategoryID {
+			score += categoryWeight
+		}
+
+		if score >= minScore {
+			results = append(results, scored{id: c.ID, score: score})
+		}
+	}
+
+	// 分数相同时较新的文章在前
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].id > results[j].id
+	})
+	if len(results) > maxRelated {
+		results = results[:maxRelated]
+	}
+
+	scores := make(map[uint]float64, len(results))
+	for _, r := range results {
+		scores[r.id] = r.score
+	}
+	return scores, nil
+}
+
+// RemovePost 清除文章的词频和推荐
+func (s *relatedService) RemovePost(ctx context.Context, postID uint) error {
+	if err := s.relatedCache.DeletePostTerms(ctx, postID); err != nil {
+		return fmt.Errorf("删除文章词频失败: %w", err)
+	}
+	if err := s.relatedCache.DeleteRelated(ctx, postID); err != nil {
+		return fmt.Errorf("删除相关文章失败: %w", err)
+	}
+	return nil
+}
+
+// Rebuild 先保存所有公开文章的词频（文档频率完整后相似度才准确），再逐篇计算
+func (s *relatedService) Rebuild(ctx context.Context, userID uint) (int, error) {
+	user, err := s.userSQL.GetUserByID(ctx, userID)
+	if err != nil || user.Relation != model.UserRoleAdmin {
+		return 0, ErrNotAdmin
+	}
+
+	// 文档频率从头重新统计，修正之前累积的偏差
+	if err := s.relatedCache.ResetPostTerms(ctx); err != nil {
+		return 0, fmt.Errorf("清空文章词频失败: %w", err)
+	}
+
+	var ids []uint
+	err = s.walkPublic(ctx, func(posts []*model.Post) error {
+		for _, post := range posts {
+			// 与 Compute 使用同一把锁，避免同时保存同一篇文章的词频
+			lockKey := fmt.Sprintf("related_compute:%d", post.ID)
+			err := s.lockManager.GetLock(lockKey, computeTimeout).Mutex(ctx, func() error {
+				return s.relatedCache.SetPostTerms(ctx, post.ID, extractTerms(post))
+			})
+			if err != nil {
+				return fmt.Errorf("保存文章词频失败: %w", err)
+			}
+			ids = append(ids, post.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := s.Compute(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// walkPublic 按ID顺序分批遍历公开文章
+func (s *relatedService) walkPublic(ctx context.Context, fn func(posts []*model.Post) error) error {
+	const batchSize = 100
+	var lastID uint
+	for {
+		var posts []*model.Post
+		err := s.db.WithContext(ctx).
+			Scopes(public).
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&posts).Error
+		if err != nil {
+			return fmt.Errorf("查询文章失败: %w", err)
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+		if err := fn(posts); err != nil {
+			return err
+		}
+		if len(posts) < batchSize {
+			return nil
+		}
+		lastID = posts[len(posts)-1].ID
+	}
+}
+
+// extractTerms 文章的词频：标题和正文分词，去掉单字和纯数字，按出现次数保留前 maxTerms 个
+func extractTerms(post *model.Post) map[string]float64 {
+	counts := make(map[string]int)
+	total := 0
+	add := func(text string, weight int) {
+		for _, w := range utils.SegmentWords(text) {
+			if utf8.RuneCountInString(w.Text) < 2 || isNumber(w.Text) {
+				continue
+			}
+			counts[w.Text] += weight
+			total += weight
+		}
+	}
+	add(post.Title, titleWeight)
+	add(post.Content, 1)
+	if total == 0 {
+		return nil
+	}
+
+	terms := make([]string, 0, len(counts))
+	for term := range counts {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if counts[terms[i]] != counts[terms[j]] {
+			return counts[terms[i]] > counts[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > maxTerms {
+		terms = terms[:maxTerms]
+	}
+
+	tf := make(map[string]float64, len(terms))
+	for _, term := range terms {
+		tf[term] = float64(counts[term]) / float64(total)
+	}
+	return tf
+}
+
+func isNumber(text string) bool {
+	for _, r := range text {
+		if !unicode.IsNumber(r) && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// tfidf 词频乘以逆文档频率（平滑，避免除零和负值）
+func tfidf(terms map[string]float64, docFreqs map[string]int64, total int64) map[string]float64 {
+	vector := make(map[string]float64, len(terms))
+	for term, tf := range terms {
+		idf := math.Log(float64(total+1)/float64(docFreqs[term]+1)) + 1
+		vector[term] = tf * idf
+	}
+	return vector
+}
+
+// cosine 余弦相似度
+func cosine(a, b map[string]float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for term, w := range a {
+		dot += w * b[term]
+		normA += w * w
+	}
+	for _, w := range b {
+		normB += w * w
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+func mapValues(m map[uint]map[string]float64) []map[string]float64 {
+	values := make([]map[string]float64, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
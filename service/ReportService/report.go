@@ -0,0 +1,473 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	"blog/model"
+	commentservice "blog/service/CommentService"
+	postservice "blog/service/PostService"
+	userservice "blog/service/UserService"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrReportNotFound      = errors.New("举报不存在")
+	ErrReportTargetMissing = errors.New("举报对象不存在")
+	ErrInvalidReportTarget = errors.New("无效的举报对象类型")
+	ErrInvalidReportReason = errors.New("无效的举报原因")
+	ErrInvalidReportAction = errors.New("无效的处理方式")
+	ErrReportDetailsLong   = errors.New("举报说明不能超过1000个字符")
+	ErrAlreadyReported     = errors.New("你已经举报过该内容")
+	ErrCannotReportSelf    = errors.New("不能举报自己的内容")
+	ErrReportClosed        = errors.New("该举报已处理")
+	ErrNotAdmin            = errors.New("需要管理员权限")
+	ErrRateLimited         = errors.New("操作过于频繁，请稍后再试")
+)
+
+// 请求结构体
+type CreateReportRequest struct {
+	TargetType model.ReportTarget `json:"target_type" binding:"required,oneof=post comment user"`
+	TargetID   uint               `json:"target_id" binding:"required"`
+	Reason     model.ReportReason `json:"reason" binding:"required"`
+	Details    string             `json:"details,omitempty"`
+}
+
+type ReviewReportRequest struct {
+	Action model.ReportAction `json:"action,omitempty"`
+	Note   string             `json:"note,omitempty" binding:"max=1000"`
+}
+
+type ReportService interface {
+	// 用户举报
+	CreateReport(ctx context.Context, reporterID uint, req *CreateReportRequest) (*model.Report, error)
+
+	// 管理员审核队列
+	ListReports(ctx context.Context, adminID uint, status model.ReportStatus, targetType model.ReportTarget, page, size int) ([]*model.Report, int64, error)
+	ResolveReport(ctx context.Context, adminID, reportID uint, req *ReviewReportRequest) (int64, error)
+	DismissReport(ctx context.Context, adminID, reportID uint, req *ReviewReportRequest) (int64, error)
+}
+
+type reportService struct {
+	reportSQL  mysql.ReportSQL
+	postSQL    mysql.PostSQL
+	commentSQL mysql.CommentSQL
+	userSQL    mysql.UserSQL
+
+	// 处理举报时复用各业务服务（保证缓存、计数一致）
+	postService    postservice.PostService
+	commentService commentservice.CommentService
+	userService    userservice.UserService
+
+	// 数据库
+	db *gorm.DB
+
+	// 分布式锁管理器
+	lockManager *utils.LockManager
+
+	// 限流器
+	rateLimiter *utils.RateLimiter
+
+	// 自动隐藏阈值
+	autoHideThreshold int
+}
+
+func NewReportService(
+	reportSQL mysql.ReportSQL,
+	postSQL mysql.PostSQL,
+	commentSQL mysql.CommentSQL,
+	userSQL mysql.UserSQL,
+	postService postservice.PostService,
+	commentService commentservice.CommentService,
+	userService userservice.UserService,
+	db *gorm.DB,
+	lockManager *utils.LockManager,
+	rateLimiter *utils.RateLimiter,
+	autoHideThreshold int,
+) ReportService {
+	return &reportService{
+		reportSQL:         reportSQL,
+		postSQL:           postSQL,
+		commentSQL:        commentSQL,
+		userSQL:           userSQL,
+		postService:       postService,
+		commentService:    commentService,
+		userService:       userService,
+		db:                db,
+		lockManager:       lockManager,
+		rateLimiter:       rateLimiter,
+		autoHideThreshold: autoHideThreshold,
+	}
+}
+
+// ValidReportReason 检查举报原因是否有效
+func ValidReportReason(reason model.ReportReason) bool {
+	for _, r := range model.ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// targetOwner 获取举报对象的作者（用户举报时即被举报用户本身）
+func (s *reportService) targetOwner(ctx context.Context, targetType model.ReportTarget, targetID uint) (uint, error) {
+	switch targetType {
+	case model.ReportTargetPost:
+		post, err := s.postSQL.GetPostByID(ctx, targetID)
+		if err != nil {
+			return 0, ErrReportTargetMissing
+		}
+		return post.UserID, nil
+	case model.ReportTargetComment:
+		comment, err := s.commentSQL.GetCommentByID(ctx, targetID)
+		if err != nil {
+			return 0, ErrReportTargetMissing
+		}
+		return comment.UserID, nil
+	case model.ReportTargetUser:
+		user, err := s.userSQL.GetUserByID(ctx, targetID)
+		if err != nil || user == nil {
+			return 0, ErrReportTargetMissing
+		}
+		return user.ID, nil
+	default:
+		return 0, ErrInvalidReportTarget
+	}
+}
+
+// setHidden 隐藏或恢复举报对象（用户举报不涉及隐藏），hiddenBy 为隐藏来源，为空时恢复
+func (s *reportService) setHidden(ctx context.Context, targetType model.ReportTarget, targetID uint, hiddenBy string) error {
+	switch targetType {
+	case model.ReportTargetPost:
+		return s.postService.SetPostHidden(ctx, targetID, hiddenBy)
+	case model.ReportTargetComment:
+		status := model.CommentStatusPublished
+		if hiddenBy != "" {
+			status = model.CommentStatusHidden
+		}
+		return s.commentService.SetCommentStatus(ctx, targetID, status, hiddenBy)
+	default:
+		return nil
+	}
+}
+
+// hiddenBy 举报对象当前的隐藏来源，未隐藏或对象不存在时为空
+func (s *reportService) hiddenBy(ctx context.Context, targetType model.ReportTarget, targetID uint) string {
+	switch targetType {
+	case model.ReportTargetPost:
+		post, err := s.postSQL.GetPostByID(ctx, targetID)
+		if err != nil || !post.Hidden {
+			return ""
+		}
+		return post.HiddenBy
+	case model.ReportTargetComment:
+		comment, err := s.commentSQL.GetCommentByID(ctx, targetID)
+		if err != nil || comment.Status != model.CommentStatusHidden {
+			return ""
+		}
+		return comment.HiddenBy
+	}
+	return ""
+}
+
+// requireAdmin 检查当前用户是否为管理员
+func (s *reportService) requireAdmin(ctx context.Context, userID uint) error {
+	user, err := s.userSQL.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrNotAdmin
+	}
+	if user.Relation != model.UserRoleAdmin || user.Status == model.UserStatusBanned {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+// CreateReport 举报帖子、评论或用户
+func (s *reportService) CreateReport(ctx context.Context, reporterID uint, req *CreateReportRequest) (*model.Report, error) {
+	// 1. 参数验证
+	if !ValidReportReason(req.Reason) {
+		return nil, ErrInvalidReportReason
+	}
+	details := strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(details) > 1000 {
+		return nil, ErrReportDetailsLong
+	}
+
+	// 2. 用户级限流
+	rateLimitKey := fmt.Sprintf("create_report:user:%d", reporterID)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Hour,
+		MaxRequests: 20, // 每小时最多举报20次
+	}
+
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return nil, ErrRateLimited
+	}
+
+	// 3. 检查举报对象
+	ownerID, err := s.targetOwner(ctx, req.TargetType, req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID == reporterID {
+		return nil, ErrCannotReportSelf
+	}
+
+	report := &model.Report{
+		ReporterID: reporterID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+		Details:    details,
+		Status:     model.ReportStatusPending,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	// 4. 使用分布式锁保护同一对象的举报计数
+	lockKey := fmt.Sprintf("report:%s:%d", req.TargetType, req.TargetID)
+	err = s.lockManager.GetLock(lockKey, 10*time.Second).Mutex(ctx, func() error {
+		count, err := s.reportSQL.CountReports(ctx, "reporter_id = ? AND target_type = ? AND target_id = ?",
+			reporterID, req.TargetType, req.TargetID)
+		if err != nil {
+			return fmt.Errorf("查询举报记录失败: %w", err)
+		}
+		if count > 0 {
+			return ErrAlreadyReported
+		}
+
+		if err := s.reportSQL.InsertReport(ctx, report); err != nil {
+			return fmt.Errorf("保存举报失败: %w", err)
+		}
+
+		// 5. 达到阈值自动隐藏，等待管理员审核
+		if s.autoHideThreshold <= 0 {
+			return nil
+		}
+		pending, err := s.reportSQL.CountReports(ctx, "target_type = ? AND target_id = ? AND status = ?",
+			req.TargetType, req.TargetID, model.ReportStatusPending)
+		if err != nil {
+			return fmt.Errorf("统计举报数失败: %w", err)
+		}
+		if pending >= int64(s.autoHideThreshold) {
+			// 已隐藏的不再修改，避免把管理员隐藏的内容标记为自动隐藏
+			if s.hiddenBy(ctx, req.TargetType, req.TargetID) == "" {
+				if err := s.setHidden(ctx, req.TargetType, req.TargetID, model.HiddenByReports); err != nil {
+					fmt.Printf("自动隐藏被举报内容失败: %v\n", err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// ListReports 获取举报审核队列（最早的举报优先）
+func (s *reportService) ListReports(ctx context.Context, adminID uint, status model.ReportStatus, targetType model.ReportTarget, page, size int) ([]*model.Report, int64, error) {
+	if err := s.requireAdmin(ctx, adminID); err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	if status == "" {
+		status = model.ReportStatusPending
+	}
+	offset := (page - 1) * size
+
+	query := s.db.WithContext(ctx).Model(&model.Report{}).Where("status = ?", status)
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取举报总数失败: %w", err)
+	}
+
+	var reports []*model.Report
+	err := query.
+		Preload("Reporter", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, avatar_url")
+		}).
+		Order("created_at ASC").
+		Limit(size).
+		Offset(offset).
+		Find(&reports).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取举报列表失败: %w", err)
+	}
+
+	return reports, total, nil
+}
+
+// reviewLock 审核同一对象的举报互斥，避免两位管理员同时处理同一对象的不同举报
+func (s *reportService) reviewLock(ctx context.Context, reportID uint) (*utils.DistributedLock, error) {
+	report, err := s.getPendingReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	lockKey := fmt.Sprintf("report_review:%s:%d", report.TargetType, report.TargetID)
+	return s.lockManager.GetLock(lockKey, 30*time.Second), nil
+}
+
+// getPendingReport 获取待处理的举报
+func (s *reportService) getPendingReport(ctx context.Context, reportID uint) (*model.Report, error) {
+	report, err := s.reportSQL.GetReportByID(ctx, reportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("获取举报失败: %w", err)
+	}
+	if report.Status != model.ReportStatusPending {
+		return nil, ErrReportClosed
+	}
+	return report, nil
+}
+
+// applyAction 执行处理动作
+func (s *reportService) applyAction(ctx context.Context, adminID uint, report *model.Report, action model.ReportAction) error {
+	switch action {
+	case model.ReportActionNone:
+		// 仅关闭举报，恢复被自动隐藏的内容（管理员隐藏的保持隐藏）
+		if s.hiddenBy(ctx, report.TargetType, report.TargetID) != model.HiddenByReports {
+			return nil
+		}
+		return s.setHidden(ctx, report.TargetType, report.TargetID, "")
+	case model.ReportActionHide:
+		if report.TargetType == model.ReportTargetUser {
+			return ErrInvalidReportAction
+		}
+		return s.setHidden(ctx, report.TargetType, report.TargetID, model.HiddenByAdmin)
+	case model.ReportActionRemove:
+		// 以管理员身份删除，复用业务服务的清理逻辑
+		adminCtx := context.WithValue(ctx, "user_id", adminID)
+		switch report.TargetType {
+		case model.ReportTargetComment:
+			return s.commentService.DeleteComment(adminCtx, report.TargetID)
+		case model.ReportTargetPost:
+			return s.postService.DeletePost(adminCtx, report.TargetID)
+		default:
+			return ErrInvalidReportAction
+		}
+	case model.ReportActionBan:
+		ownerID, err := s.targetOwner(ctx, report.TargetType, report.TargetID)
+		if err != nil {
+			return err
+		}
+		if ownerID == adminID {
+			return ErrCannotReportSelf
+		}
+		if err := s.userService.SetUserStatus(ctx, ownerID, model.UserStatusBanned); err != nil {
+			return err
+		}
+		// 封禁作者的同时隐藏被举报的内容
+		return s.setHidden(ctx, report.TargetType, report.TargetID, model.HiddenByAdmin)
+	default:
+		return ErrInvalidReportAction
+	}
+}
+
+// closeReports 关闭目标的全部待处理举报
+func (s *reportService) closeReports(ctx context.Context, adminID uint, report *model.Report, status model.ReportStatus, action model.ReportAction, note string) (int64, error) {
+	now := time.Now()
+	return s.reportSQL.UpdatePendingReports(ctx, report.TargetType, report.TargetID, map[string]interface{}{
+		"status":      status,
+		"action":      action,
+		"reviewer_id": adminID,
+		"review_note": strings.TrimSpace(note),
+		"reviewed_at": &now,
+	})
+}
+
+// ResolveReport 处理举报：执行处理动作并关闭同一对象的全部待处理举报
+func (s *reportService) ResolveReport(ctx context.Context, adminID, reportID uint, req *ReviewReportRequest) (int64, error) {
+	if err := s.requireAdmin(ctx, adminID); err != nil {
+		return 0, err
+	}
+
+	lock, err := s.reviewLock(ctx, reportID)
+	if err != nil {
+		return 0, err
+	}
+
+	var closed int64
+	err = lock.Mutex(ctx, func() error {
+		// 加锁后重新读取，举报可能已随同一对象的其他举报一起关闭
+		report, err := s.getPendingReport(ctx, reportID)
+		if err != nil {
+			return err
+		}
+
+		// 未指定处理方式时：用户举报默认封禁，内容举报默认隐藏
+		action := req.Action
+		if action == "" {
+			action = model.ReportActionHide
+			if report.TargetType == model.ReportTargetUser {
+				action = model.ReportActionBan
+			}
+		}
+
+		if err := s.applyAction(ctx, adminID, report, action); err != nil {
+			return err
+		}
+
+		closed, err = s.closeReports(ctx, adminID, report, model.ReportStatusResolved, action, req.Note)
+		if err != nil {
+			return fmt.Errorf("更新举报状态失败: %w", err)
+		}
+		return nil
+	})
+
+	return closed, err
+}
+
+// DismissReport 驳回举报：恢复被自动隐藏的内容并关闭同一对象的全部待处理举报
+func (s *reportService) DismissReport(ctx context.Context, adminID, reportID uint, req *ReviewReportRequest) (int64, error) {
+	if err := s.requireAdmin(ctx, adminID); err != nil {
+		return 0, err
+	}
+
+	lock, err := s.reviewLock(ctx, reportID)
+	if err != nil {
+		return 0, err
+	}
+
+	var closed int64
+	err = lock.Mutex(ctx, func() error {
+		// 加锁后重新读取，举报可能已随同一对象的其他举报一起关闭
+		report, err := s.getPendingReport(ctx, reportID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.applyAction(ctx, adminID, report, model.ReportActionNone); err != nil {
+			return err
+		}
+
+		closed, err = s.closeReports(ctx, adminID, report, model.ReportStatusDismissed, model.ReportActionNone, req.Note)
+		if err != nil {
+			return fmt.Errorf("更新举报状态失败: %w", err)
+		}
+		return nil
+	})
+
+	return closed, err
+}
@@ -0,0 +1,464 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	"blog/model"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrSeriesNotFound     = errors.New("系列不存在")
+	ErrSeriesSlugExists   = errors.New("系列别名已存在")
+	ErrInvalidSeriesTitle = errors.New("系列标题不能为空")
+	ErrNotSeriesAuthor    = errors.New("只有系列作者可以修改")
+	ErrInvalidSeriesPost  = errors.New("只能把自己的文章加入系列")
+	ErrPostInOtherSeries  = errors.New("文章已属于其他系列")
+	ErrTooManySeriesPosts = errors.New("系列中的文章过多")
+	ErrRateLimited        = errors.New("操作过于频繁，请稍后再试")
+)
+
+// 每个系列最多包含的文章数
+const maxSeriesPosts = 200
+
+// 请求结构体
+type CreateSeriesRequest struct {
+	Title       string `json:"title" binding:"required,min=1,max=255"`
+	Slug        string `json:"slug,omitempty" binding:"omitempty,min=1,max=255"`
+	Description string `json:"description,omitempty"`
+	PostIDs     []uint `json:"post_ids,omitempty"` // 按顺序排列
+}
+
+type UpdateSeriesRequest struct {
+	Title       *string `json:"title,omitempty" binding:"omitempty,min=1,max=255"`
+	Slug        *string `json:"slug,omitempty" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description,omitempty"`
+}
+
+type SeriesService interface {
+	// 系列管理（只有作者可以修改）
+	CreateSeries(ctx context.Context, userID uint, req *CreateSeriesRequest) (*model.Series, error)
+	UpdateSeries(ctx context.Context, userID uint, slug string, req *UpdateSeriesRequest) (*model.Series, error)
+	// SetSeriesPosts 按 postIDs 的顺序设置系列中的文章（可用于增删和调整顺序）
+	SetSeriesPosts(ctx context.Context, userID uint, slug string, postIDs []uint) (*model.Series, error)
+	DeleteSeries(ctx context.Context, userID uint, slug string) error
+
+	// 查询：其他人只能看到系列中公开的文章，作者能看到全部
+	GetSeriesBySlug(ctx context.Context, viewerID uint, slug string) (*model.Series, error)
+	ListSeries(ctx context.Context, authorID uint, page, size int) ([]*model.Series, int64, error)
+
+	// 文章所在系列的导航，不属于任何系列时返回nil
+	PostNav(ctx context.Context, postID uint) (*model.SeriesNav, error)
+	// RemovePost 文章删除时从系列中移除
+	RemovePost(ctx context.Context, postID uint) error
+}
+
+type seriesService struct {
+	seriesSQL mysql.SeriesSQL
+	postSQL   mysql.PostSQL
+	userSQL   mysql.UserSQL
+
+	// 数据库（分页查询、预加载文章关联）
+	db *gorm.DB
+
+	// 分布式锁管理器
+	lockManager *utils.LockManager
+
+	// 限流器
+	rateLimiter *utils.RateLimiter
+}
+
+func NewSeriesService(
+	seriesSQL mysql.SeriesSQL,
+	postSQL mysql.PostSQL,
+	userSQL mysql.UserSQL,
+	db *gorm.DB,
+	lockManager *utils.LockManager,
+	rateLimiter *utils.RateLimiter,
+) SeriesService {
+	return &seriesService{
+		seriesSQL:   seriesSQL,
+		postSQL:     postSQL,
+		userSQL:     userSQL,
+		db:          db,
+		lockManager: lockManager,
+		rateLimiter: rateLimiter,
+	}
+}
+
+// isAdmin 是否为管理员（管理员可以管理所有系列）
+func (s *seriesService) isAdmin(ctx context.Context, userID uint) bool {
+	user, err := s.userSQL.GetUserByID(ctx, userID)
+	return err == nil && user != nil && user.Relation == model.UserRoleAdmin
+}
+
+// getOwnSeries 获取可由 userID 修改的系列
+func (s *seriesService) getOwnSeries(ctx context.Context, userID uint, slug string) (*model.Series, error) {
+	series, err := s.seriesSQL.GetSeriesBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSeriesNotFound
+		}
+		return nil, fmt.Errorf("获取系列失败: %w", err)
+	}
+	if series.UserID != userID && !s.isAdmin(ctx, userID) {
+		return nil, ErrNotSeriesAuthor
+	}
+	return series, nil
+}
+
+// CreateSeries 创建系列，可同时指定文章
+func (s *seriesService) CreateSeries(ctx context.Context, userID uint, req *CreateSeriesRequest) (*model.Series, error) {
+	// 1. 用户级限流
+	rateLimitKey := fmt.Sprintf("create_series:user:%d", userID)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Hour,
+		MaxRequests: 20, // 每小时最多创建20个系列
+	}
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return nil, ErrRateLimited
+	}
+
+	// 2. 参数验证
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, ErrInvalidSeriesTitle
+	}
+	postIDs, err := s.checkPosts(ctx, userID, 0, req.PostIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 处理slug（如果没传则自动生成）
+	slug := ""
+	if req.Slug != "" {
+		slug = utils.SanitizeSlug(req.Slug)
+	}
+	if slug == "" {
+		slug = utils.GenerateSlug(title)
+	}
+
+	series := &model.Series{
+		UserID:      userID,
+		Title:       title,
+		Slug:        slug,
+		Description: strings.TrimSpace(req.Description),
+	}
+
+	// 4. 使用分布式锁检查slug是否已存在
+	err = s.lockManager.GetLock("series_slug:"+slug, 5*time.Second).Mutex(ctx, func() error {
+		if existing, err := s.seriesSQL.GetSeriesBySlug(ctx, slug); err == nil && existing != nil {
+			return ErrSeriesSlugExists
+		}
+		if err := s.seriesSQL.InsertSeries(ctx, series); err != nil {
+			return fmt.Errorf("保存系列失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(postIDs) > 0 {
+		if err := s.seriesSQL.ReplaceSeriesPosts(ctx, series.ID, postIDs); err != nil {
+			return nil, fmt.Errorf("保存系列文章失败: %w", err)
+		}
+	}
+
+	return s.GetSeriesBySlug(ctx, userID, series.Slug)
+}
+
+// UpdateSeries 修改系列的标题、别名和简介
+func (s *seriesService) UpdateSeries(ctx context.Context, userID uint, slug string, req *UpdateSeriesRequest) (*model.Series, error) {
+	series, err := s.getOwnSeries(ctx, userID, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, ErrInvalidSeriesTitle
+		}
+		if title != series.Title {
+			updates["title"] = title
+		}
+	}
+	if req.Description != nil && strings.TrimSpace(*req.Description) != series.Description {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+
+	newSlug := series.Slug
+	if req.Slug != nil {
+		if sanitized := utils.SanitizeSlug(*req.Slug); sanitized != "" && sanitized != series.Slug {
+			newSlug = sanitized
+			updates["slug"] = sanitized
+		}
+	}
+
+	if len(updates) == 0 {
+		return s.GetSeriesBySlug(ctx, userID, series.Slug)
+	}
+	updates["updated_at"] = time.Now()
+
+	// 使用分布式锁保护slug更新
+	err = s.lockManager.GetLock("series_slug:"+newSlug, 5*time.Second).Mutex(ctx, func() error {
+		if _, ok := updates["slug"]; ok {
+			if existing, err := s.seriesSQL.GetSeriesBySlug(ctx, newSlug); err == nil && existing != nil {
+				return ErrSeriesSlugExists
+			}
+		}
+		if err := s.seriesSQL.UpdateSeries(ctx, series.ID, updates); err != nil {
+			return fmt.Errorf("更新系列失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetSeriesBySlug(ctx, userID, newSlug)
+}
+
+// SetSeriesPosts 按给定顺序设置系列中的文章
+func (s *seriesService) SetSeriesPosts(ctx context.Context, userID uint, slug string, postIDs []uint) (*model.Series, error) {
+	series, err := s.getOwnSeries(ctx, userID, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同一系列的并发修改按顺序执行
+	lockKey := fmt.Sprintf("series_posts:%d", series.ID)
+	err = s.lockManager.GetLock(lockKey, 10*time.Second).Mutex(ctx, func() error {
+		ids, err := s.checkPosts(ctx, series.UserID, series.ID, postIDs)
+		if err != nil {
+			return err
+		}
+		if err := s.seriesSQL.ReplaceSeriesPosts(ctx, series.ID, ids); err != nil {
+			return fmt.Errorf("保存系列文章失败: %w", err)
+		}
+		return s.seriesSQL.UpdateSeries(ctx, series.ID, map[string]any{"updated_at": time.Now()})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetSeriesBySlug(ctx, userID, series.Slug)
+}
+
+// checkPosts 去重并检查文章都属于作者、且不在其他系列中
+func (s *seriesService) checkPosts(ctx context.Context, authorID, seriesID uint, postIDs []uint) ([]uint, error) {
+	ids := make([]uint, 0, len(postIDs))
+	seen := make(map[uint]bool, len(postIDs))
+	for _, id := range postIDs {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > maxSeriesPosts {
+		return nil, ErrTooManySeriesPosts
+	}
+
+	posts, err := s.postSQL.FindPosts(ctx, "id IN ?", ids)
+	if err != nil {
+		return nil, fmt.Errorf("查询文章失败: %w", err)
+	}
+	if len(posts) != len(ids) {
+		return nil, ErrInvalidSeriesPost
+	}
+	for _, p := range posts {
+		if p.UserID != authorID {
+			return nil, ErrInvalidSeriesPost
+		}
+	}
+
+	entries, err := s.seriesSQL.FindSeriesPosts(ctx, "post_id IN ?", ids)
+	if err != nil {
+		return nil, fmt.Errorf("查询系列文章失败: %w", err)
+	}
+	for _, e := range entries {
+		if e.SeriesID != seriesID {
+			return nil, ErrPostInOtherSeries
+		}
+	}
+	return ids, nil
+}
+
+// DeleteSeries 删除系列，文章本身不受影响
+func (s *seriesService) DeleteSeries(ctx context.Context, userID uint, slug string) error {
+	series, err := s.getOwnSeries(ctx, userID, slug)
+	if err != nil {
+		return err
+	}
+	if err := s.seriesSQL.DeleteSeries(ctx, series.ID); err != nil {
+		return fmt.Errorf("删除系列失败: %w", err)
+	}
+	return nil
+}
+
+// GetSeriesBySlug 获取系列及按顺序排列的文章
+func (s *seriesService) GetSeriesBySlug(ctx context.Context, viewerID uint, slug string) (*model.Series, error) {
+	var series model.Series
+	err := s.db.WithContext(ctx).
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, avatar_url, bio")
+		}).
+		Where("slug = ?", slug).
+		First(&series).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSeriesNotFound
+		}
+		return nil, fmt.Errorf("获取系列失败: %w", err)
+	}
+
+	entries, err := s.seriesSQL.FindSeriesPosts(ctx, "series_id = ?", series.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询系列文章失败: %w", err)
+	}
+	series.Posts = []*model.Post{}
+	if len(entries) == 0 {
+		return &series, nil
+	}
+
+	ids := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.PostID)
+	}
+	query := s.db.WithContext(ctx).
+		Select("id, title, slug, summary, user_id, author_name, category_id, cover_id, visibility, hidden, created_at, updated_at").
+		Preload("Category").
+		Preload("Cover").
+		Where("id IN ?", ids)
+	if viewerID == 0 || viewerID != series.UserID {
+		query = query.Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false)
+	}
+	var posts []*model.Post
+	if err := query.Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("查询系列文章失败: %w", err)
+	}
+
+	postByID := make(map[uint]*model.Post, len(posts))
+	for _, p := range posts {
+		postByID[p.ID] = p
+	}
+	for _, e := range entries {
+		if p, ok := postByID[e.PostID]; ok {
+			series.Posts = append(series.Posts, p)
+		}
+	}
+	return &series, nil
+}
+
+// ListSeries 系列列表（最近更新的在前），authorID 为0时列出所有作者的
+func (s *seriesService) ListSeries(ctx context.Context, authorID uint, page, size int) ([]*model.Series, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	offset := (page - 1) * size
+
+	query := s.db.WithContext(ctx).Model(&model.Series{})
+	if authorID != 0 {
+		query = query.Where("user_id = ?", authorID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取系列总数失败: %w", err)
+	}
+
+	var series []*model.Series
+	err := query.
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, avatar_url, bio")
+		}).
+		Order("updated_at DESC, id DESC").
+		Limit(size).
+		Offset(offset).
+		Find(&series).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取系列列表失败: %w", err)
+	}
+	return series, total, nil
+}
+
+// PostNav 文章在系列中的位置及上一篇/下一篇；只在公开文章之间导航，当前文章本身总是计入
+func (s *seriesService) PostNav(ctx context.Context, postID uint) (*model.SeriesNav, error) {
+	current, err := s.seriesSQL.FindSeriesPosts(ctx, "post_id = ?", postID)
+	if err != nil {
+		return nil, fmt.Errorf("查询文章所在系列失败: %w", err)
+	}
+	if len(current) == 0 {
+		return nil, nil
+	}
+	series, err := s.seriesSQL.GetSeriesByID(ctx, current[0].SeriesID)
+	if err != nil {
+		return nil, fmt.Errorf("获取系列失败: %w", err)
+	}
+
+	entries, err := s.seriesSQL.FindSeriesPosts(ctx, "series_id = ?", series.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询系列文章失败: %w", err)
+	}
+	ids := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.PostID)
+	}
+	var posts []*model.Post
+	err = s.db.WithContext(ctx).
+		Select("id, title, slug").
+		Where("id IN ?", ids).
+		Where("id = ? OR (visibility = ? AND hidden = ?)", postID, model.VisibilityPublic, false).
+		Find(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询系列文章失败: %w", err)
+	}
+	postByID := make(map[uint]*model.Post, len(posts))
+	for _, p := range posts {
+		postByID[p.ID] = p
+	}
+
+	nav := &model.SeriesNav{ID: series.ID, Title: series.Title, Slug: series.Slug}
+	var prev *model.Post
+	for _, e := range entries {
+		p, ok := postByID[e.PostID]
+		if !ok {
+			continue
+		}
+		nav.Total++
+		switch {
+		case p.ID == postID:
+			nav.Position = nav.Total
+			if prev != nil {
+				nav.Prev = &model.SeriesNavEntry{ID: prev.ID, Title: prev.Title, Slug: prev.Slug}
+			}
+		case nav.Position > 0 && nav.Next == nil:
+			nav.Next = &model.SeriesNavEntry{ID: p.ID, Title: p.Title, Slug: p.Slug}
+		}
+		prev = p
+	}
+	return nav, nil
+}
+
+// RemovePost 把文章从所在系列中移除
+func (s *seriesService) RemovePost(ctx context.Context, postID uint) error {
+	if err := s.seriesSQL.RemovePost(ctx, postID); err != nil {
+		return fmt.Errorf("从系列中移除文章失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"blog/model"
+	sitemappkg "blog/pkg/sitemap"
+	"blog/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrSitemapNotFound = errors.New("站点地图不存在")
+
+type SitemapService interface {
+	// Sitemap /sitemap.xml：URL总数不超过单文件上限时直接输出urlset，否则输出站点地图索引
+	Sitemap(ctx context.Context) ([]byte, error)
+	// SitemapPage 分页站点地图，name形如 posts-1.xml
+	SitemapPage(ctx context.Context, name string) ([]byte, error)
+	// Robots /robots.txt
+	Robots() []byte
+	// Files 全部站点地图文件（相对路径 => 内容），供静态导出使用
+	Files(ctx context.Context) (map[string][]byte, error)
+}
+
+// Options 站点地图配置
+type Options struct {
+	BaseURL string
+
+	// 两次检查数据变化的最小间隔，间隔内直接返回缓存
+	CacheTTL time.Duration
+
+	// robots.txt 禁止抓取的路径和附加内容
+	RobotsDisallow []string
+	RobotsExtra    string
+}
+
+// section 站点地图中的一类页面
+type section struct {
+	name string
+	// query 选出 id、loc、last_mod 三列
+	query func(db *gorm.DB) *gorm.DB
+	path  func(loc string) string
+}
+
+// sitemapRow 站点地图查询结果
+type sitemapRow struct {
+	ID      uint
+	Loc     string
+	LastMod time.Time
+}
+
+// pageStat 分页的数据指纹，变化时才重新生成该页
+type pageStat struct {
+	Count   int64
+	LastMod sql.NullString
+	MinID   uint
+	MaxID   uint
+}
+
+// cachedPage 已生成的分页
+type cachedPage struct {
+	stat    pageStat
+	urls    []sitemappkg.URL
+	lastMod time.Time
+	body    []byte
+}
+
+type sitemapService struct {
+	// 数据库
+	db *gorm.DB
+
+	options  Options
+	sections []*section
+
+	// 分页缓存，键为 posts-1 形式的页名
+	lock      sync.Mutex
+	pages     map[string]*cachedPage
+	checkedAt time.Time
+	body      []byte
+	indexed   bool // 入口是否为站点地图索引
+	robots    []byte
+}
+
+func NewSitemapService(db *gorm.DB, options Options) SitemapService {
+	options.BaseURL = strings.TrimRight(options.BaseURL, "/")
+	if options.CacheTTL <= 0 {
+		options.CacheTTL = time.Minute
+	}
+
+	s := &sitemapService{
+		db:      db,
+		options: options,
+		pages:   make(map[string]*cachedPage),
+	}
+	s.sections = []*section{
+		{
+			name: "posts",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Model(&model.Post{}).
+					Select("id, slug AS loc, updated_at AS last_mod").
+					Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false)
+			},
+			path: utils.PostPath,
+		},
+		{
+			name: "categories",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Model(&model.Category{}).Select("id, slug AS loc, updated_at AS last_mod")
+			},
+			path: utils.CategoryPath,
+		},
+		{
+			name: "tags",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Model(&model.Tag{}).Select("id, slug AS loc, updated_at AS last_mod")
+			},
+			path: utils.TagPath,
+		},
+		{
+			// 只收录有公开文章的正常用户
+			name: "users",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Model(&model.User{}).
+					Select("id, name AS loc, updated_at AS last_mod").
+					Where("status = ? AND EXISTS (SELECT 1 FROM posts WHERE posts.user_id = users.id AND posts.visibility = ? AND posts.hidden = ?)",
+						model.UserStatusActive, model.VisibilityPublic, false)
+			},
+			path: utils.UserPath,
+		},
+	}
+	s.robots = s.buildRobots()
+	return s
+}
+
+func pageName(sec *section, page int) string {
+	return fmt.Sprintf("%s-%d", sec.name, page)
+}
+
+// refresh 检查各分页的数据指纹，只重新生成发生变化的分页（调用方持有锁）
+func (s *sitemapService) refresh(ctx context.Context) error {
+	if s.body != nil && time.Since(s.checkedAt) < s.options.CacheTTL {
+		return nil
+	}
+
+	var total int64
+	var names []string
+	alive := make(map[string]bool)
+	for _, sec := range s.sections {
+		var count int64
+		if err := s.db.WithContext(ctx).Table("(?) AS t", sec.query(s.db)).Count(&count).Error; err != nil {
+			return fmt.Errorf("统计%s失败: %w", sec.name, err)
+		}
+		total += count
+
+		pages := int((count + sitemappkg.MaxURLs - 1) / sitemappkg.MaxURLs)
+		for page := 1; page <= pages; page++ {
+			name := pageName(sec, page)
+			if err := s.ensurePage(ctx, sec, page); err != nil {
+				return err
+			}
+			names = append(names, name)
+			alive[name] = true
+		}
+	}
+	for name := range s.pages {
+		if !alive[name] {
+			delete(s.pages, name)
+		}
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+	if total <= sitemappkg.MaxURLs {
+		var urls []sitemappkg.URL
+		for _, name := range names {
+			urls = append(urls, s.pages[name].urls...)
+		}
+		body, err = sitemappkg.RenderURLSet(urls)
+	} else {
+		sitemaps := make([]sitemappkg.Sitemap, 0, len(names))
+		for _, name := range names {
+			sitemaps = append(sitemaps, sitemappkg.Sitemap{
+				Loc:     fmt.Sprintf("%s/sitemaps/%s.xml", s.options.BaseURL, name),
+				LastMod: s.pages[name].lastMod,
+			})
+		}
+		body, err = sitemappkg.RenderIndex(sitemaps)
+	}
+	if err != nil {
+		return fmt.Errorf("生成站点地图失败: %w", err)
+	}
+
+	s.body = body
+	s.indexed = total > sitemappkg.MaxURLs
+	s.checkedAt = time.Now()
+	return nil
+}
+
+// ensurePage 数据指纹未变化时沿用缓存，否则重新查询该页
+func (s *sitemapService) ensurePage(ctx context.Context, sec *section, page int) error {
+	window := func() *gorm.DB {
+		return sec.query(s.db).Order("id").Limit(sitemappkg.MaxURLs).Offset((page - 1) * sitemappkg.MaxURLs)
+	}
+
+	var stat pageStat
+	err := s.db.WithContext(ctx).
+		Table("(?) AS t", window()).
+		Select("COUNT(*) AS count, MAX(last_mod) AS last_mod, COALESCE(MIN(id), 0) AS min_id, COALESCE(MAX(id), 0) AS max_id").
+		Scan(&stat).Error
+	if err != nil {
+		return fmt.Errorf("检查%s站点地图失败: %w", sec.name, err)
+	}
+
+	name := pageName(sec, page)
+	if cached, ok := s.pages[name]; ok && cached.stat == stat {
+		return nil
+	}
+
+	var rows []*sitemapRow
+	if err := window().WithContext(ctx).Scan(&rows).Error; err != nil {
+		return fmt.Errorf("查询%s站点地图失败: %w", sec.name, err)
+	}
+
+	cached := &cachedPage{stat: stat, urls: make([]sitemappkg.URL, 0, len(rows))}
+	for _, row := range rows {
+		cached.urls = append(cached.urls, sitemappkg.URL{
+			Loc:     s.options.BaseURL + sec.path(row.Loc),
+			LastMod: row.LastMod,
+		})
+		if row.LastMod.After(cached.lastMod) {
+			cached.lastMod = row.LastMod
+		}
+	}
+	s.pages[name] = cached
+	return nil
+}
+
+// Sitemap 站点地图入口
+func (s *sitemapService) Sitemap(ctx context.Context) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return s.body, nil
+}
+
+// SitemapPage 分页站点地图
+func (s *sitemapService) SitemapPage(ctx context.Context, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".xml")
+	sep := strings.LastIndex(name, "-")
+	if sep < 0 {
+		return nil, ErrSitemapNotFound
+	}
+	if _, err := strconv.Atoi(name[sep+1:]); err != nil {
+		return nil, ErrSitemapNotFound
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	page, ok := s.pages[name]
+	if !ok {
+		return nil, ErrSitemapNotFound
+	}
+	return page.render()
+}
+
+// render 分页的 urlset，生成后缓存到数据指纹变化为止
+func (p *cachedPage) render() ([]byte, error) {
+	if p.body == nil {
+		body, err := sitemappkg.RenderURLSet(p.urls)
+		if err != nil {
+			return nil, fmt.Errorf("生成站点地图失败: %w", err)
+		}
+		p.body = body
+	}
+	return p.body, nil
+}
+
+// Files 全部站点地图文件：未超过单文件上限时只有 sitemap.xml
+func (s *sitemapService) Files(ctx context.Context) (map[string][]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{"sitemap.xml": s.body}
+	if !s.indexed {
+		return files, nil
+	}
+	for name, page := range s.pages {
+		body, err := page.render()
+		if err != nil {
+			return nil, err
+		}
+		files["sitemaps/"+name+".xml"] = body
+	}
+	return files, nil
+}
+
+// Robots robots.txt 内容（配置不变，启动时生成）
+func (s *sitemapService) Robots() []byte {
+	return s.robots
+}
+
+func (s *sitemapService) buildRobots() []byte {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	if len(s.options.RobotsDisallow) == 0 {
+		b.WriteString("Disallow:\n")
+	}
+	for _, path := range s.options.RobotsDisallow {
+		b.WriteString("Disallow: " + path + "\n")
+	}
+	if extra := strings.TrimSpace(s.options.RobotsExtra); extra != "" {
+		b.WriteString("\n" + extra + "\n")
+	}
+	b.WriteString("\nSitemap: " + s.options.BaseURL + "/sitemap.xml\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,584 @@
+package service
+
+import (
+	"blog/model"
+	blobpkg "blog/pkg/blob"
+	feedpkg "blog/pkg/feed"
+	feedservice "blog/service/FeedService"
+	pageservice "blog/service/PageService"
+	postservice "blog/service/PostService"
+	sitemapservice "blog/service/SitemapService"
+	"blog/utils"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 导出目录下记录上次导出结果的文件，用于增量导出
+const manifestFile = ".export-manifest.json"
+
+// 遍历文章的批大小
+const walkBatchSize = 200
+
+type StaticService interface {
+	// Export 导出静态站点到dir；full为true时忽略上次的导出记录，全部重新生成
+	Export(ctx context.Context, dir string, full bool) (*ExportResult, error)
+}
+
+// Options 静态导出配置
+type Options struct {
+	ThemeDir string // 当前主题目录：static 子目录复制到 /theme/，模板变化时重新生成全部文章页
+	// 上传文件存储，复制到 /uploads/；文件不由站点提供（如对象存储）时为nil
+	Uploads blobpkg.Store
+}
+
+// ExportResult 导出统计
+type ExportResult struct {
+	Posts    int // 公开文章数
+	Rendered int // 本次重新生成的文章页数
+	Written  int // 内容有变化而写入的文件数
+	Removed  int // 删除的过期文件数
+}
+
+// manifest 上次导出的记录
+type manifest struct {
+	Theme string            `json:"theme"` // 主题指纹
+	Posts map[string]string `json:"posts"` // 文章页文件 => 文章指纹
+	Files map[string]string `json:"files"` // 导出的文件 => 内容指纹
+}
+
+type staticService struct {
+	postService    postservice.PostService
+	pageService    pageservice.PageService
+	feedService    feedservice.FeedService
+	sitemapService sitemapservice.SitemapService
+
+	options Options
+}
+
+// NewStaticService pageService 需使用 StaticPaging，分页地址才能对应到导出的文件
+func NewStaticService(
+	postService postservice.PostService,
+	pageService pageservice.PageService,
+	feedService feedservice.FeedService,
+	sitemapService sitemapservice.SitemapService,
+	options Options,
+) StaticService {
+	return &staticService{
+		postService:    postService,
+		pageService:    pageService,
+		feedService:    feedService,
+		sitemapService: sitemapService,
+		options:        options,
+	}
+}
+
+// exporter 一次导出过程
+type exporter struct {
+	dir    string
+	old    *manifest
+	next   *manifest
+	result *ExportResult
+}
+
+// postGroup 分类/标签/作者下的文章
+type postGroup struct {
+	path  string
+	posts []*model.Post
+
+	category *model.Category
+	tag      *model.Tag
+	author   *model.User
+}
+
+// Export 导出静态站点
+func (s *staticService) Export(ctx context.Context, dir string, full bool) (*ExportResult, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建导出目录失败: %w", err)
+	}
+
+	themeHash, err := hashDir(s.options.ThemeDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取主题失败: %w", err)
+	}
+
+	e := &exporter{
+		dir:    dir,
+		old:    &manifest{Posts: map[string]string{}, Files: map[string]string{}},
+		next:   &manifest{Theme: themeHash, Posts: map[string]string{}, Files: map[string]string{}},
+		result: &ExportResult{},
+	}
+	if !full {
+		if err := e.loadManifest(); err != nil {
+			fmt.Printf("读取导出记录失败，将全部重新生成: %v\n", err)
+		}
+	}
+	// 模板变化后所有文章页都要重新生成
+	renderAll := full || e.old.Theme != themeHash
+
+	// 1. 文章页：只重新生成有变化的文章
+	var posts []*model.Post
+	err = s.postService.WalkPublicPosts(ctx, walkBatchSize, func(batch []*model.Post) error {
+		for _, post := range batch {
+			if err := s.exportPost(e, post, renderAll); err != nil {
+				return err
+			}
+		}
+		posts = append(posts, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.result.Posts = len(posts)
+
+	// 列表和订阅源按发布时间倒序
+	sort.SliceStable(posts, func(i, j int) bool {
+		if posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].ID > posts[j].ID
+		}
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+
+	// 2. 列表页和订阅源
+	if err := s.exportList(e, "/", posts, func(p []*model.Post, pg *pageservice.Pagination) *pageservice.Page {
+		return s.pageService.HomePage(p, pg)
+	}); err != nil {
+		return nil, err
+	}
+	for _, format := range feedFormats() {
+		if err := e.writeFeed("/", feedpkg.FileNames[format], format, s.feedService.BuildSiteFeed(format, posts)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, group := range groupPosts(posts) {
+		if err := s.exportGroup(e, group); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. 站点地图、robots.txt、404页面
+	sitemaps, err := s.sitemapService.Files(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for name, body := range sitemaps {
+		if err := e.write(name, body); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.write("robots.txt", s.sitemapService.Robots()); err != nil {
+		return nil, err
+	}
+	notFound, err := s.pageService.Render(s.pageService.NotFound())
+	if err != nil {
+		return nil, fmt.Errorf("渲染404页面失败: %w", err)
+	}
+	if err := e.write("404.html", notFound); err != nil {
+		return nil, err
+	}
+
+	// 4. 主题静态资源和上传文件
+	if err := e.copyDir(filepath.Join(s.options.ThemeDir, "static"), "theme"); err != nil {
+		return nil, err
+	}
+	if s.options.Uploads != nil {
+		if err := e.copyUploads(ctx, s.options.Uploads, "uploads"); err != nil {
+			return nil, err
+		}
+	}
+
+	// 5. 删除本次没有生成的文件（已删除/转为私密的文章、减少的分页等）
+	e.removeStale()
+	if err := e.saveManifest(); err != nil {
+		return nil, err
+	}
+	return e.result, nil
+}
+
+// exportPost 导出文章页，指纹没有变化时沿用上次的文件
+func (s *staticService) exportPost(e *exporter, post *model.Post, renderAll bool) error {
+	file, err := pageFile(utils.PostPath(post.Slug))
+	if err != nil {
+		fmt.Printf("跳过文章%d: %v\n", post.ID, err)
+		return nil
+	}
+
+	fingerprint := postFingerprint(post)
+	e.next.Posts[file] = fingerprint
+	if !renderAll && e.old.Posts[file] == fingerprint && e.exists(file) {
+		e.next.Files[file] = e.old.Files[file]
+		return nil
+	}
+
+	body, err := s.pageService.Render(s.pageService.PostPage(post))
+	if err != nil {
+		return fmt.Errorf("渲染文章%d失败: %w", post.ID, err)
+	}
+	e.result.Rendered++
+	return e.write(file, body)
+}
+
+// exportGroup 分类/标签/作者的列表页和订阅源
+func (s *staticService) exportGroup(e *exporter, group *postGroup) error {
+	err := s.exportList(e, group.path, group.posts, func(p []*model.Post, pg *pageservice.Pagination) *pageservice.Page {
+		switch {
+		case group.category != nil:
+			return s.pageService.CategoryPage(group.category, p, pg)
+		case group.tag != nil:
+			return s.pageService.TagPage(group.tag, p, pg)
+		default:
+			return s.pageService.AuthorPage(group.author, p, pg)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, format := range feedFormats() {
+		var feed *feedpkg.Feed
+		switch {
+		case group.category != nil:
+			feed = s.feedService.BuildCategoryFeed(format, group.category, group.posts)
+		case group.tag != nil:
+			feed = s.feedService.BuildTagFeed(format, group.tag, group.posts)
+		default:
+			feed = s.feedService.BuildAuthorFeed(format, group.author, group.posts)
+		}
+		if err := e.writeFeed(group.path, feedpkg.FileNames[format], format, feed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportList 分页输出列表，第一页为 path/index.html，其余为 path/page/N/index.html
+func (s *staticService) exportList(e *exporter, urlPath string, posts []*model.Post, build func([]*model.Post, *pageservice.Pagination) *pageservice.Page) error {
+	total := int64(len(posts))
+	for page := 1; ; page++ {
+		pagination := s.pageService.Paginate(urlPath, page, total)
+		if page > 1 && page > pagination.TotalPages {
+			return nil
+		}
+
+		start := (page - 1) * pagination.Size
+		end := start + pagination.Size
+		if end > len(posts) {
+			end = len(posts)
+		}
+		body, err := s.pageService.Render(build(posts[start:end], pagination))
+		if err != nil {
+			return fmt.Errorf("渲染列表页 %s 失败: %w", urlPath, err)
+		}
+
+		pageURL := urlPath
+		if page > 1 {
+			pageURL = strings.TrimRight(urlPath, "/") + "/page/" + strconv.Itoa(page)
+		}
+		file, err := pageFile(pageURL)
+		if err != nil {
+			return err
+		}
+		if err := e.write(file, body); err != nil {
+			return err
+		}
+	}
+}
+
+// groupPosts 按分类、标签、作者分组（保持文章顺序）
+func groupPosts(posts []*model.Post) []*postGroup {
+	var groups []*postGroup
+	index := make(map[string]*postGroup)
+	add := func(key string, post *model.Post, create func() *postGroup) {
+		group, ok := index[key]
+		if !ok {
+			group = create()
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.posts = append(group.posts, post)
+	}
+
+	for _, post := range posts {
+		if category := post.Category; category != nil {
+			add("category:"+strconv.Itoa(int(category.ID)), post, func() *postGroup {
+				return &postGroup{path: utils.CategoryPath(category.Slug), category: category}
+			})
+		}
+		for i := range post.Tags {
+			tag := &post.Tags[i]
+			add("tag:"+strconv.Itoa(int(tag.ID)), post, func() *postGroup {
+				return &postGroup{path: utils.TagPath(tag.Slug), tag: tag}
+			})
+		}
+		// 与在线页面一致，只为正常状态的用户生成主页
+		if author := post.Author; author != nil && author.Status == model.UserStatusActive {
+			add("user:"+strconv.Itoa(int(author.ID)), post, func() *postGroup {
+				return &postGroup{path: utils.UserPath(author.Name), author: author}
+			})
+		}
+	}
+	return groups
+}
+
+func feedFormats() []string {
+	return []string{feedpkg.FormatRSS, feedpkg.FormatAtom, feedpkg.FormatJSON}
+}
+
+// postFingerprint 文章页内容相关的字段（包括分类、标签、作者的名称）；
+// 浏览量等计数变化频繁，不参与比较
+func postFingerprint(post *model.Post) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s", post.ID, post.Slug, post.UpdatedAt.UTC().Format(time.RFC3339Nano), post.AuthorName)
+	if post.Author != nil {
+		fmt.Fprintf(h, "\x00a:%s\x00%s", post.Author.Name, post.Author.AvatarURL)
+	}
+	if post.Category != nil {
+		fmt.Fprintf(h, "\x00c:%s\x00%s", post.Category.Name, post.Category.Slug)
+	}
+	for _, tag := range post.Tags {
+		fmt.Fprintf(h, "\x00t:%s\x00%s", tag.Name, tag.Slug)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// pageFile 页面地址对应的导出文件（目录下的 index.html），清理后不会超出导出目录
+func pageFile(urlPath string) (string, error) {
+	p, err := url.PathUnescape(urlPath)
+	if err != nil {
+		return "", fmt.Errorf("无效的页面地址 %q: %w", urlPath, err)
+	}
+	return strings.TrimPrefix(path.Join(path.Clean("/"+p), "index.html"), "/"), nil
+}
+
+// loadManifest 读取上次的导出记录
+func (e *exporter) loadManifest() error {
+	body, err := os.ReadFile(filepath.Join(e.dir, manifestFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return err
+	}
+	if m.Posts != nil {
+		e.old.Posts = m.Posts
+	}
+	if m.Files != nil {
+		e.old.Files = m.Files
+	}
+	e.old.Theme = m.Theme
+	return nil
+}
+
+func (e *exporter) saveManifest() error {
+	body, err := json.MarshalIndent(e.next, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(e.dir, manifestFile), body); err != nil {
+		return fmt.Errorf("保存导出记录失败: %w", err)
+	}
+	return nil
+}
+
+func (e *exporter) exists(file string) bool {
+	_, err := os.Stat(filepath.Join(e.dir, filepath.FromSlash(file)))
+	return err == nil
+}
+
+// write 写入导出文件，内容和上次相同时不改动（便于 rsync 等增量同步）
+func (e *exporter) write(file string, body []byte) error {
+	sum := sha1.Sum(body)
+	hash := hex.EncodeToString(sum[:])
+	e.next.Files[file] = hash
+	if e.old.Files[file] == hash && e.exists(file) {
+		return nil
+	}
+
+	if err := writeFileAtomic(filepath.Join(e.dir, filepath.FromSlash(file)), body); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", file, err)
+	}
+	e.result.Written++
+	return nil
+}
+
+// writeFeed 渲染订阅源并写入页面 urlPath 下的 name 文件
+func (e *exporter) writeFeed(urlPath, name, format string, feed *feedpkg.Feed) error {
+	body, err := feedpkg.Render(format, feed)
+	if err != nil {
+		return fmt.Errorf("渲染订阅源失败: %w", err)
+	}
+	file, err := pageFile(urlPath)
+	if err != nil {
+		return err
+	}
+	return e.write(path.Join(path.Dir(file), name), body)
+}
+
+// copyDir 复制目录到导出目录的 target 下，大小和修改时间都没变的文件跳过
+func (e *exporter) copyDir(src, target string) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+
+		file := path.Join(target, filepath.ToSlash(rel))
+		stamp := fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+		e.next.Files[file] = stamp
+		if e.old.Files[file] == stamp && e.exists(file) {
+			return nil
+		}
+
+		if err := copyFile(p, filepath.Join(e.dir, filepath.FromSlash(file))); err != nil {
+			return fmt.Errorf("复制 %s 失败: %w", p, err)
+		}
+		e.result.Written++
+		return nil
+	})
+}
+
+// copyUploads 复制上传存储中的文件，大小和修改时间没变的跳过
+func (e *exporter) copyUploads(ctx context.Context, store blobpkg.Store, target string) error {
+	return store.Walk(ctx, "", func(obj blobpkg.Object) error {
+		file := path.Join(target, obj.Key)
+		stamp := fmt.Sprintf("%d-%d", obj.Size, obj.ModTime.UnixNano())
+		e.next.Files[file] = stamp
+		if e.old.Files[file] == stamp && e.exists(file) {
+			return nil
+		}
+
+		rc, err := store.Open(ctx, obj.Key)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", obj.Key, err)
+		}
+		defer rc.Close()
+		if err := writeFile(filepath.Join(e.dir, filepath.FromSlash(file)), rc); err != nil {
+			return fmt.Errorf("复制 %s 失败: %w", obj.Key, err)
+		}
+		e.result.Written++
+		return nil
+	})
+}
+
+// removeStale 删除上次导出、这次没有生成的文件，以及因此变空的目录
+func (e *exporter) removeStale() {
+	for file := range e.old.Files {
+		if _, ok := e.next.Files[file]; ok {
+			continue
+		}
+
+		full := filepath.Join(e.dir, filepath.FromSlash(file))
+		if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("删除过期文件失败: %v\n", err)
+			continue
+		}
+		e.result.Removed++
+
+		// 目录非空时 Remove 会失败，到此为止
+		for dir := filepath.Dir(full); dir != filepath.Clean(e.dir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+}
+
+// writeFileAtomic 先写临时文件再改名，导出过程中站点不会读到写了一半的文件
+func writeFileAtomic(name string, body []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dst, in)
+}
+
+func writeFile(dst string, in io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// hashDir 目录下所有文件内容的指纹
+func hashDir(dir string) (string, error) {
+	h := sha1.New()
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		body, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(body))
+		h.Write(body)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	"blog/model"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var ErrInvalidTicket = errors.New("无效或已过期的连接凭证")
+
+// StreamChannel 实时事件的Redis发布订阅频道（多实例之间共享）
+const StreamChannel = "blog:stream"
+
+// MaxWatchedPosts 单个连接最多关注的帖子数
+const MaxWatchedPosts = 50
+
+// subscriberBuffer 每个订阅者的事件缓冲区大小，写满后丢弃事件，避免慢客户端拖住分发
+const subscriberBuffer = 64
+
+const (
+	// 连接凭证的有效期（只能使用一次）
+	ticketTTL = 30 * time.Second
+	// 订阅频道失败后的重试间隔
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+type EventType string
+
+const (
+	EventComment      EventType = "comment"      // 帖子有新评论
+	EventPostStats    EventType = "post_stats"   // 帖子点赞/收藏数变化
+	EventNotification EventType = "notification" // 用户收到新通知
+
+	// EventPostAccess 帖子可见性或隐藏状态变化，只在实例之间传递，不推送给客户端
+	EventPostAccess EventType = "post_access"
+)
+
+// PostAccess 帖子的访问控制信息（EventPostAccess 的数据）
+type PostAccess struct {
+	AuthorID   uint             `json:"author_id"`
+	Visibility model.Visibility `json:"visibility"`
+	Hidden     bool             `json:"hidden"`
+}
+
+// Event 实时事件
+type Event struct {
+	Type EventType `json:"type"`
+	// UserID 接收者，为0时按帖子分发
+	UserID    uint            `json:"user_id,omitempty"`
+	PostID    uint            `json:"post_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Subscriber 本实例上的一个SSE连接
+type Subscriber struct {
+	UserID uint
+	Events chan *Event
+	// Watched 注册时实际关注的帖子（去掉了无权查看的）
+	Watched []uint
+
+	admin bool
+	// posts 只在分发协程中读写（注册前除外）
+	posts map[uint]bool
+}
+
+// canWatch 作者和管理员可以关注隐藏或不公开的帖子，其他人只能关注公开且未隐藏的帖子
+func (s *Subscriber) canWatch(access *PostAccess) bool {
+	if s.admin || access.AuthorID == s.UserID {
+		return true
+	}
+	return access.Visibility == model.VisibilityPublic && !access.Hidden
+}
+
+// Watching 是否关注了该帖子
+func (s *Subscriber) Watching(postID uint) bool {
+	return s.posts[postID]
+}
+
+type StreamService interface {
+	// Publish 发布事件到所有实例
+	Publish(ctx context.Context, eventType EventType, userID, postID uint, data interface{}) error
+
+	// IssueTicket 签发一次性的连接凭证（EventSource 无法设置请求头，凭证代替 token 放在查询参数中）
+	IssueTicket(ctx context.Context, userID uint) (string, error)
+	// RedeemTicket 使用连接凭证，返回签发给的用户
+	RedeemTicket(ctx context.Context, ticket string) (uint, error)
+
+	// Subscribe 在本实例上注册订阅者，只关注该用户有权查看的帖子
+	Subscribe(ctx context.Context, userID uint, postIDs []uint) (*Subscriber, error)
+	Unsubscribe(sub *Subscriber)
+
+	// Run 监听Redis频道并把事件分发给本实例的订阅者，直到ctx结束（订阅失败时重试）
+	Run(ctx context.Context) error
+}
+
+type streamService struct {
+	rdb redis.UniversalClient
+
+	userSQL mysql.UserSQL
+
+	// 数据库（查询关注帖子的可见性）
+	db *gorm.DB
+
+	subscribers map[*Subscriber]struct{}
+	lock        sync.RWMutex
+}
+
+func NewStreamService(rdb redis.UniversalClient, userSQL mysql.UserSQL, db *gorm.DB) StreamService {
+	return &streamService{
+		rdb:         rdb,
+		userSQL:     userSQL,
+		db:          db,
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+// ticketKey 连接凭证的Redis键
+func ticketKey(ticket string) string {
+	return fmt.Sprintf("stream:ticket:%s", ticket)
+}
+
+// IssueTicket 签发连接凭证
+func (s *streamService) IssueTicket(ctx context.Context, userID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成连接凭证失败: %w", err)
+	}
+	ticket := hex.EncodeToString(buf)
+
+	if err := s.rdb.Set(ctx, ticketKey(ticket), userID, ticketTTL).Err(); err != nil {
+		return "", fmt.Errorf("保存连接凭证失败: %w", err)
+	}
+	return ticket, nil
+}
+
+// RedeemTicket 读取并删除凭证，同一凭证只能使用一次
+func (s *streamService) RedeemTicket(ctx context.Context, ticket string) (uint, error) {
+	if ticket == "" {
+		return 0, ErrInvalidTicket
+	}
+
+	var get *redis.StringCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, ticketKey(ticket))
+		pipe.Del(ctx, ticketKey(ticket))
+		return nil
+	})
+	if err == redis.Nil {
+		return 0, ErrInvalidTicket
+	}
+	if err != nil {
+		return 0, fmt.Errorf("读取连接凭证失败: %w", err)
+	}
+
+	userID, err := get.Uint64()
+	if err != nil {
+		return 0, ErrInvalidTicket
+	}
+	return uint(userID), nil
+}
+
+// Publish 发布事件到所有实例
+func (s *streamService) Publish(ctx context.Context, eventType EventType, userID, postID uint, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化事件数据失败: %w", err)
+	}
+
+	payload, err := json.Marshal(&Event{
+		Type:      eventType,
+		UserID:    userID,
+		PostID:    postID,
+		Data:      raw,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %w", err)
+	}
+
+	return s.rdb.Publish(ctx, StreamChannel, payload).Err()
+}
+
+// Subscribe 在本实例上注册订阅者，无权查看的帖子直接忽略
+func (s *streamService) Subscribe(ctx context.Context, userID uint, postIDs []uint) (*Subscriber, error) {
+	if len(postIDs) > MaxWatchedPosts {
+		postIDs = postIDs[:MaxWatchedPosts]
+	}
+
+	sub := &Subscriber{
+		UserID: userID,
+		Events: make(chan *Event, subscriberBuffer),
+		posts:  make(map[uint]bool, len(postIDs)),
+	}
+
+	if len(postIDs) > 0 {
+		user, err := s.userSQL.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户失败: %w", err)
+		}
+		sub.admin = user.Relation == model.UserRoleAdmin
+
+		var posts []model.Post
+		err = s.db.WithContext(ctx).
+			Select("id, user_id, visibility, hidden").
+			Where("id IN ?", postIDs).
+			Find(&posts).Error
+		if err != nil {
+			return nil, fmt.Errorf("查询关注的帖子失败: %w", err)
+		}
+		for _, post := range posts {
+			access := &PostAccess{AuthorID: post.UserID, Visibility: post.Visibility, Hidden: post.Hidden}
+			if sub.canWatch(access) {
+				sub.posts[post.ID] = true
+				sub.Watched = append(sub.Watched, post.ID)
+			}
+		}
+	}
+
+	s.lock.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.lock.Unlock()
+
+	return sub, nil
+}
+
+// Unsubscribe 注销订阅者
+func (s *streamService) Unsubscribe(sub *Subscriber) {
+	s.lock.Lock()
+	delete(s.subscribers, sub)
+	s.lock.Unlock()
+}
+
+// Run 监听Redis频道并分发事件，订阅失败或连接断开后按指数退避重试
+func (s *streamService) Run(ctx context.Context) error {
+	delay := minRetryDelay
+	for {
+		err := s.listen(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			// 订阅成功过，重新从最短间隔开始
+			delay = minRetryDelay
+		} else {
+			fmt.Printf("订阅实时事件频道失败，%v后重试: %v\n", delay, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// listen 订阅频道并分发事件，直到频道关闭或ctx结束；订阅失败时返回错误
+func (s *streamService) listen(ctx context.Context) error {
+	pubsub := s.rdb.Subscribe(ctx, StreamChannel)
+	defer pubsub.Close()
+
+	// 等待订阅确认
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				fmt.Printf("解析实时事件失败: %v\n", err)
+				continue
+			}
+			s.dispatch(&event)
+		}
+	}
+}
+
+// dispatch 把事件发送给匹配的订阅者
+func (s *streamService) dispatch(event *Event) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if event.Type == EventPostAccess {
+		s.revoke(event)
+		return
+	}
+
+	for sub := range s.subscribers {
+		if event.UserID != 0 {
+			if sub.UserID != event.UserID {
+				continue
+			}
+		} else if !sub.Watching(event.PostID) {
+			continue
+		}
+
+		select {
+		case sub.Events <- event:
+		default:
+			// 缓冲区已满，丢弃事件
+		}
+	}
+}
+
+// revoke 帖子变为不可见后，取消无权查看的订阅者对它的关注
+func (s *streamService) revoke(event *Event) {
+	var access PostAccess
+	if err := json.Unmarshal(event.Data, &access); err != nil {
+		fmt.Printf("解析帖子可见性失败: %v\n", err)
+		return
+	}
+
+	for sub := range s.subscribers {
+		if sub.Watching(event.PostID) && !sub.canWatch(&access) {
+			delete(sub.posts, event.PostID)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	mysql "blog/dao/mysql"
+	redis "blog/dao/redis"
+	"blog/model"
+	"blog/utils"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 错误定义
+var (
+	ErrInvalidWindow = errors.New("无效的时间范围")
+	ErrNotAdmin      = errors.New("需要管理员权限")
+	ErrRateLimited   = errors.New("操作过于频繁，请稍后再试")
+)
+
+// 统计的时间范围
+const (
+	WindowDay   = "24h"
+	WindowWeek  = "7d"
+	WindowMonth = "30d"
+)
+
+// Event 计入热度的行为
+type Event string
+
+const (
+	EventView    Event = "view"
+	EventLike    Event = "like"
+	EventStar    Event = "star"
+	EventComment Event = "comment"
+)
+
+// eventScores 各行为的热度分数（与文章热度排序的权重一致）
+var eventScores = map[Event]float64{
+	EventView:    1,
+	EventLike:    3,
+	EventStar:    5,
+	EventComment: 2,
+}
+
+// window 时间范围内按小时或按天分桶，分桶的分数按距今的时间衰减
+type window struct {
+	hourly   bool
+	span     time.Duration
+	halfLife time.Duration // 经过该时间分数减半
+}
+
+var windows = map[string]window{
+	WindowDay:   {hourly: true, span: 24 * time.Hour, halfLife: 6 * time.Hour},
+	WindowWeek:  {hourly: false, span: 7 * 24 * time.Hour, halfLife: 2 * 24 * time.Hour},
+	WindowMonth: {hourly: false, span: 30 * 24 * time.Hour, halfLife: 7 * 24 * time.Hour},
+}
+
+const (
+	// 接口默认/最多返回的数量
+	defaultTrendingLimit = 10
+	maxTrendingLimit     = 50
+	// 合并后的排行缓存时间
+	rankTTL = time.Minute
+	// 重建的超时时间（同时也是锁的有效期）
+	rebuildTimeout = 5 * time.Minute
+)
+
+type TrendingService interface {
+	// ListTrending 时间范围内热度最高的公开文章
+	ListTrending(ctx context.Context, window string, limit int) ([]*model.Post, error)
+
+	// Record 记录一次浏览/点赞/收藏/评论
+	Record(ctx context.Context, postID uint, event Event) error
+
+	// Rebuild 从数据库重新统计点赞、收藏和评论的热度（需要管理员权限）
+	Rebuild(ctx context.Context, userID uint) (int, error)
+	// Run 启动时及之后每隔 interval 从数据库重建一次，多个实例同时只有一个在重建
+	Run(ctx context.Context, interval time.Duration) error
+}
+
+type trendingService struct {
+	userSQL mysql.UserSQL
+
+	// 数据库（重建统计和文章查询）
+	db *gorm.DB
+
+	// 分桶热度
+	trendingCache redis.TrendingCache
+
+	// 分布式锁管理器
+	lockManager *utils.LockManager
+
+	// 限流器
+	rateLimiter *utils.RateLimiter
+}
+
+func NewTrendingService(
+	userSQL mysql.UserSQL,
+	db *gorm.DB,
+	trendingCache redis.TrendingCache,
+	lockManager *utils.LockManager,
+	rateLimiter *utils.RateLimiter,
+) TrendingService {
+	return &trendingService{
+		userSQL:       userSQL,
+		db:            db,
+		trendingCache: trendingCache,
+		lockManager:   lockManager,
+		rateLimiter:   rateLimiter,
+	}
+}
+
+// bucketSize 分桶的时长
+func (w window) bucketSize() time.Duration {
+	if w.hourly {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// bucketIndex t 所在分桶的序号
+func (w window) bucketIndex(t time.Time) int64 {
+	return t.Unix() / int64(w.bucketSize()/time.Second)
+}
+
+// buckets 时间范围内的分桶及权重：以分桶的中点计算距今的时间，按半衰期衰减
+func (w window) buckets(now time.Time) []redis.TrendingBucket {
+	size := w.bucketSize()
+	first, last := w.bucketIndex(now.Add(-w.span)), w.bucketIndex(now)
+
+	buckets := make([]redis.TrendingBucket, 0, last-first+1)
+	for index := first; index <= last; index++ {
+		mid := time.Unix(index*int64(size/time.Second), 0).Add(size / 2)
+		age := now.Sub(mid)
+		if age < 0 {
+			age = 0
+		}
+		buckets = append(buckets, redis.TrendingBucket{
+			Hourly: w.hourly,
+			Index:  index,
+			Weight: math.Pow(0.5, float64(age)/float64(w.halfLife)),
+		})
+	}
+	return buckets
+}
+
+// ListTrending 热门文章
+func (s *trendingService) ListTrending(ctx context.Context, name string, limit int) ([]*model.Post, error) {
+	// 限流检查
+	ip := utils.GetIPFromContext(ctx)
+	rateLimitKey := fmt.Sprintf("trending_posts:ip:%s", ip)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Minute,
+		MaxRequests: 300,
+	}
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return nil, ErrRateLimited
+	}
+
+	if name == "" {
+		name = WindowDay
+	}
+	w, ok := windows[name]
+	if !ok {
+		return nil, ErrInvalidWindow
+	}
+	if limit < 1 || limit > maxTrendingLimit {
+		limit = defaultTrendingLimit
+	}
+
+	// 多取一些，去掉已删除或不公开的文章后仍然够数
+	ids, err := s.trendingCache.RangeTrending(ctx, name, w.buckets(time.Now()), rankTTL, limit*2)
+	if err != nil {
+		return nil, fmt.Errorf("读取热门文章失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return []*model.Post{}, nil
+	}
+
+	var found []*model.Post
+	err = s.db.WithContext(ctx).
+		Omit("content", "rendered").
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, avatar_url, bio")
+		}).
+		Preload("Category").
+		Preload("Tags").
+		Preload("Cover").
+		Where("visibility = ? AND hidden = ?", model.VisibilityPublic, false).
+		Where("id IN ?", ids).
+		Find(&found).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询热门文章失败: %w", err)
+	}
+
+	byID := make(map[uint]*model.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	posts := make([]*model.Post, 0, limit)
+	for _, id := range ids {
+		if p, ok := byID[id]; ok && len(posts) < limit {
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
+
+// Record 计入当前的小时和天分桶
+func (s *trendingService) Record(ctx context.Context, postID uint, event Event) error {
+	score, ok := eventScores[event]
+	if !ok {
+		return fmt.Errorf("未知的热度事件: %s", event)
+	}
+	return s.trendingCache.IncrTrending(ctx, postID, time.Now(), score, event == EventView)
+}
+
+// Rebuild 管理员手动重建
+func (s *trendingService) Rebuild(ctx context.Context, userID uint) (int, error) {
+	user, err := s.userSQL.GetUserByID(ctx, userID)
+	if err != nil || user.Relation != model.UserRoleAdmin {
+		return 0, ErrNotAdmin
+	}
+
+	var count int
+	err = s.lockManager.GetLock("trending_rebuild", rebuildTimeout).Mutex(ctx, func() error {
+		count, err = s.rebuild(ctx)
+		return err
+	})
+	return count, err
+}
+
+// Run 定时重建
+func (s *trendingService) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.lockManager.GetLock("trending_rebuild", rebuildTimeout).Mutex(ctx, func() error {
+			rebuildCtx, cancel := context.WithTimeout(ctx, rebuildTimeout)
+			defer cancel()
+			_, err := s.rebuild(rebuildCtx)
+			return err
+		})
+		// 其他实例正在重建时跳过本次
+		if err != nil && !errors.Is(err, utils.ErrLockNotAcquired) {
+			fmt.Printf("重建热门文章失败: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rebuild 按点赞、收藏、评论的时间重新统计各分桶的互动分数，浏览量没有时间记录，只在Redis中累计；
+// 重建修正取消点赞/收藏、删除或隐藏评论后多计的分数，以及Redis数据丢失的情况，返回有互动的文章数
+func (s *trendingService) rebuild(ctx context.Context) (int, error) {
+	now := time.Now()
+	hourly, daily := windows[WindowDay], windows[WindowMonth]
+	firstHour, lastHour := hourly.bucketIndex(now.Add(-hourly.span)), hourly.bucketIndex(now)
+	firstDay, lastDay := daily.bucketIndex(now.Add(-daily.span)), daily.bucketIndex(now)
+	since := time.Unix(firstDay*86400, 0)
+
+	hourScores := make(map[int64]map[uint]float64)
+	dayScores := make(map[int64]map[uint]float64)
+	posts := make(map[uint]bool)
+	add := func(postID uint, at time.Time, score float64) {
+		if index := hourly.bucketIndex(at); index >= firstHour {
+			if hourScores[index] == nil {
+				hourScores[index] = make(map[uint]float64)
+			}
+			hourScores[index][postID] += score
+		}
+		index := daily.bucketIndex(at)
+		if dayScores[index] == nil {
+			dayScores[index] = make(map[uint]float64)
+		}
+		dayScores[index][postID] += score
+		posts[postID] = true
+	}
+
+	sources := []struct {
+		event Event
+		query *gorm.DB
+	}{
+		{EventLike, s.db.Model(&model.UserLikePost{})},
+		{EventStar, s.db.Model(&model.UserStarPost{})},
+		{EventComment, s.db.Model(&model.Comment{}).Where("status = ?", model.CommentStatusPublished)},
+	}
+	for _, source := range sources {
+		if err := scanEvents(ctx, source.query, since, func(postID uint, at time.Time) {
+			add(postID, at, eventScores[source.event])
+		}); err != nil {
+			return 0, fmt.Errorf("统计%s失败: %w", source.event, err)
+		}
+	}
+
+	if err := s.trendingCache.ResetTrendingInteractions(ctx, true, firstHour, lastHour, hourScores); err != nil {
+		return 0, fmt.Errorf("保存热门文章失败: %w", err)
+	}
+	if err := s.trendingCache.ResetTrendingInteractions(ctx, false, firstDay, lastDay, dayScores); err != nil {
+		return 0, fmt.Errorf("保存热门文章失败: %w", err)
+	}
+	return len(posts), nil
+}
+
+// scanEvents 逐行读取 since 之后的记录（文章ID和时间）
+func scanEvents(ctx context.Context, query *gorm.DB, since time.Time, fn func(postID uint, at time.Time)) error {
+	rows, err := query.WithContext(ctx).
+		Select("post_id, created_at").
+		Where("created_at >= ?", since).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID uint
+		var createdAt time.Time
+		if err := rows.Scan(&postID, &createdAt); err != nil {
+			return err
+		}
+		fn(postID, createdAt)
+	}
+	return rows.Err()
+}
@@ -3,13 +3,18 @@ package service
 import (
 	dao "blog/dao/mysql"
 	"blog/model"
+	avatarpkg "blog/pkg/avatar"
+	blobpkg "blog/pkg/blob"
+	imagingpkg "blog/pkg/imaging"
+	notificationservice "blog/service/NotificationService"
 	"blog/utils"
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"net/http"
-	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +33,27 @@ var (
 	ErrInvalidEmail       = errors.New("邮箱格式不正确")
 	ErrInvalidUsername    = errors.New("用户名长度2-50个字符，不能全是空格")
 	ErrRateLimited        = errors.New("操作过于频繁，请稍后再试")
+	ErrCannotFollowSelf   = errors.New("不能关注自己")
+	ErrAlreadyFollowing   = errors.New("已经关注过该用户")
+	ErrNotFollowing       = errors.New("尚未关注该用户")
+	ErrUnsupportedImage   = imagingpkg.ErrUnsupportedFormat
+	ErrImageTooLarge      = imagingpkg.ErrImageTooLarge
+)
+
+// AvatarOptions 头像配置
+type AvatarOptions struct {
+	// 上传头像生成的尺寸
+	Sizes []int
+	// 没有头像的用户生成的默认头像样式：initials 或 identicon
+	DefaultStyle string
+}
+
+// 头像尺寸限制
+const (
+	defaultAvatarSize = 256
+	maxAvatarSize     = 1024
+	// 头像原图的像素数上限，头像最大只有 1024 像素，不需要接受太大的原图
+	maxAvatarPixels = 16 * 1000 * 1000
 )
 
 // 请求结构体
@@ -80,11 +106,22 @@ type UserService interface {
 	UploadAvatar(ctx context.Context, userID uint, fileBytes []byte, fileName string) (string, error)
 	DeleteAvatar(ctx context.Context, userID uint) error
 	GetAvatarURL(ctx context.Context, userID uint) (string, error)
+	AvatarURLForSize(avatarURL string, size int) string
+	DefaultAvatarURL(ctx context.Context, userID uint, name string) (string, error)
+	// 关注
+	FollowUser(ctx context.Context, userID uint, username string) error
+	UnfollowUser(ctx context.Context, userID uint, username string) error
+	// 审核
+	SetUserStatus(ctx context.Context, userID uint, status model.UserStatus) error
 }
 
 // 实现
 type userService struct {
-	userSQL dao.UserSQL
+	userSQL   dao.UserSQL
+	followSQL dao.FollowSQL
+
+	// 通知服务
+	notificationService notificationservice.NotificationService
 
 	// 分布式锁管理器
 	lockManager *utils.LockManager
@@ -92,6 +129,14 @@ type userService struct {
 	// 限流器
 	rateLimiter *utils.RateLimiter
 
+	// 上传文件存储（头像）
+	uploads blobpkg.Store
+	// 头像尺寸，从小到大
+	avatarSizes []int
+	// 默认头像样式，已生成的默认头像 key
+	defaultAvatarStyle string
+	defaultAvatars     sync.Map
+
 	// 用户信息缓存
 	userCache     map[uint]*model.User
 	userCacheTTL  map[uint]time.Time
@@ -104,14 +149,39 @@ type userService struct {
 	usernameLock sync.RWMutex
 }
 
-func NewUserService(userSQL dao.UserSQL, lockManager *utils.LockManager, rateLimiter *utils.RateLimiter) UserService {
+func NewUserService(
+	userSQL dao.UserSQL,
+	followSQL dao.FollowSQL,
+	notificationService notificationservice.NotificationService,
+	lockManager *utils.LockManager,
+	rateLimiter *utils.RateLimiter,
+	uploads blobpkg.Store,
+	avatarOptions AvatarOptions,
+) UserService {
+	sizes := make([]int, 0, len(avatarOptions.Sizes))
+	for _, size := range avatarOptions.Sizes {
+		if size > 0 && size <= maxAvatarSize {
+			sizes = append(sizes, size)
+		}
+	}
+	slices.Sort(sizes)
+	sizes = slices.Compact(sizes)
+	if len(sizes) == 0 {
+		sizes = []int{defaultAvatarSize}
+	}
+
 	return &userService{
-		userSQL:      userSQL,
-		lockManager:  lockManager,
-		rateLimiter:  rateLimiter,
-		userCache:    make(map[uint]*model.User),
-		userCacheTTL: make(map[uint]time.Time),
-		usernameToID: make(map[string]uint),
+		userSQL:             userSQL,
+		followSQL:           followSQL,
+		notificationService: notificationService,
+		lockManager:         lockManager,
+		rateLimiter:         rateLimiter,
+		uploads:             uploads,
+		avatarSizes:         sizes,
+		defaultAvatarStyle:  avatarOptions.DefaultStyle,
+		userCache:           make(map[uint]*model.User),
+		userCacheTTL:        make(map[uint]time.Time),
+		usernameToID:        make(map[string]uint),
 	}
 }
 
@@ -820,6 +890,8 @@ func (s *userService) GetUserByID(ctx context.Context, userID uint) (*model.User
 }
 
 // UploadAvatar 上传头像
+// 图片解码后按 EXIF 方向摆正、从中间裁成正方形，生成配置的各个尺寸并以 WebP 保存（重新编码同时去掉了元数据），
+// 用户资料中保存最大尺寸的地址
 func (s *userService) UploadAvatar(ctx context.Context, userID uint, fileBytes []byte, fileName string) (string, error) {
 	// 1. 获取当前用户
 	user, err := s.GetUserByID(ctx, userID)
@@ -827,66 +899,45 @@ func (s *userService) UploadAvatar(ctx context.Context, userID uint, fileBytes [
 		return "", ErrUserNotFound
 	}
 
-	// 2. 验证文件类型（可选）
-	contentType := http.DetectContentType(fileBytes[:512])
-	allowedTypes := map[string]bool{
-		"image/jpeg": true,
-		"image/png":  true,
-		"image/gif":  true,
-		"image/webp": true,
-	}
-
-	if !allowedTypes[contentType] {
-		return "", errors.New("不支持的图片格式，仅支持 JPEG、PNG、GIF、WebP")
-	}
-
-	// 3. 生成唯一文件名
-	fileExt := ""
-	switch contentType {
-	case "image/jpeg":
-		fileExt = ".jpg"
-	case "image/png":
-		fileExt = ".png"
-	case "image/gif":
-		fileExt = ".gif"
-	case "image/webp":
-		fileExt = ".webp"
-	default:
-		fileExt = ".jpg"
-	}
-
-	// 使用用户ID和时间戳生成唯一文件名
-	timestamp := time.Now().Unix()
-	newFileName := fmt.Sprintf("avatar_%d_%d%s", userID, timestamp, fileExt)
-
-	// 4. 创建上传目录（如果不存在）
-	uploadDir := "./uploads/avatars"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return "", fmt.Errorf("创建上传目录失败: %w", err)
+	// 2. 解码图片（同时校验格式和尺寸）
+	img, _, err := imagingpkg.DecodeLimit(fileBytes, maxAvatarPixels)
+	if err != nil {
+		return "", err
 	}
+	square := imagingpkg.CropSquare(img)
 
-	// 5. 保存文件
-	filePath := fmt.Sprintf("%s/%s", uploadDir, newFileName)
-	if err := os.WriteFile(filePath, fileBytes, 0644); err != nil {
-		return "", fmt.Errorf("保存头像文件失败: %w", err)
+	// 3. 生成各尺寸并保存，使用用户ID和时间戳生成唯一文件名
+	base := fmt.Sprintf("avatars/avatar_%d_%d", userID, time.Now().Unix())
+	keys := make([]string, 0, len(s.avatarSizes))
+	for _, size := range s.avatarSizes {
+		var buf bytes.Buffer
+		if err := imagingpkg.EncodeWebP(&buf, imagingpkg.Resize(square, size, size)); err != nil {
+			s.deleteUploads(ctx, keys)
+			return "", fmt.Errorf("生成头像失败: %w", err)
+		}
+		key := fmt.Sprintf("%s_%d.webp", base, size)
+		if err := s.uploads.Put(ctx, key, &buf, int64(buf.Len()), "image/webp"); err != nil {
+			s.deleteUploads(ctx, keys)
+			return "", fmt.Errorf("保存头像文件失败: %w", err)
+		}
+		keys = append(keys, key)
 	}
+	key := keys[len(keys)-1]
 
-	// 6. 如果用户已有头像，删除旧头像
-	if user.AvatarURL != "" {
-		oldAvatarPath := strings.TrimPrefix(user.AvatarURL, "/uploads/")
-		oldAvatarPath = "./uploads/" + oldAvatarPath
-
-		// 检查文件是否存在，如果存在则删除
-		if _, err := os.Stat(oldAvatarPath); err == nil {
-			if err := os.Remove(oldAvatarPath); err != nil {
-				// 记录错误但不中断流程
-				fmt.Printf("删除旧头像失败: %v\n", err)
-			}
+	// 4. 如果用户已有头像，删除旧头像的所有尺寸（不是本存储的地址时忽略）
+	for _, oldKey := range s.avatarKeys(user.AvatarURL) {
+		// 同一秒内重复上传时文件名相同，已被新头像覆盖
+		if slices.Contains(keys, oldKey) {
+			continue
+		}
+		if err := s.uploads.Delete(ctx, oldKey); err != nil {
+			// 记录错误但不中断流程
+			fmt.Printf("删除旧头像失败: %v\n", err)
 		}
 	}
 
-	// 7. 更新用户头像URL
-	avatarURL := fmt.Sprintf("/uploads/avatars/%s", newFileName)
+	// 6. 更新用户头像URL
+	avatarURL := s.uploads.URL(key)
 	updates := map[string]interface{}{
 		"avatar_url": avatarURL,
 		"updated_at": time.Now(),
@@ -910,7 +961,7 @@ func (s *userService) UploadAvatar(ctx context.Context, userID uint, fileBytes [
 
 	if err != nil {
 		// 如果更新失败，删除已上传的文件
-		os.Remove(filePath)
+		s.deleteUploads(ctx, keys)
 		return "", err
 	}
 
@@ -930,12 +981,9 @@ func (s *userService) DeleteAvatar(ctx context.Context, userID uint) error {
 		return nil
 	}
 
-	// 3. 删除头像文件
-	avatarPath := strings.TrimPrefix(user.AvatarURL, "/uploads/")
-	avatarPath = "./uploads/" + avatarPath
-
-	if _, err := os.Stat(avatarPath); err == nil {
-		if err := os.Remove(avatarPath); err != nil {
+	// 3. 删除头像文件（所有尺寸）
+	for _, key := range s.avatarKeys(user.AvatarURL) {
+		if err := s.uploads.Delete(ctx, key); err != nil {
 			return fmt.Errorf("删除头像文件失败: %w", err)
 		}
 	}
@@ -974,3 +1022,190 @@ func (s *userService) GetAvatarURL(ctx context.Context, userID uint) (string, er
 
 	return user.AvatarURL, nil
 }
+
+// avatarKeyPattern 上传头像各尺寸的 key：avatars/avatar_{用户ID}_{时间戳}_{尺寸}.webp
+var avatarKeyPattern = regexp.MustCompile(`^(avatars/avatar_\d+_\d+)_(\d+)\.webp$`)
+
+// AvatarURLForSize 头像指定尺寸的地址：选不小于 size 的最小尺寸，都比 size 小时选最大的
+// size 为 0、外部地址或旧版本上传的单个文件时原样返回
+func (s *userService) AvatarURLForSize(avatarURL string, size int) string {
+	if size <= 0 {
+		return avatarURL
+	}
+	key, ok := s.uploads.Key(avatarURL)
+	if !ok {
+		return avatarURL
+	}
+	m := avatarKeyPattern.FindStringSubmatch(key)
+	if m == nil {
+		return avatarURL
+	}
+
+	best := s.avatarSizes[len(s.avatarSizes)-1]
+	for _, candidate := range s.avatarSizes {
+		if candidate >= size {
+			best = candidate
+			break
+		}
+	}
+	return s.uploads.URL(fmt.Sprintf("%s_%d.webp", m[1], best))
+}
+
+// DefaultAvatarURL 没有上传头像的用户的默认头像，按用户ID和名字生成，首次请求时写入上传存储
+// 名字改变后生成新文件
+func (s *userService) DefaultAvatarURL(ctx context.Context, userID uint, name string) (string, error) {
+	seed := fmt.Sprintf("%d:%s", userID, name)
+	sum := sha256.Sum256([]byte(s.defaultAvatarStyle + "\x00" + seed))
+	key := fmt.Sprintf("avatars/default/%x.svg", sum[:10])
+	if _, ok := s.defaultAvatars.Load(key); ok {
+		return s.uploads.URL(key), nil
+	}
+
+	f, err := s.uploads.Open(ctx, key)
+	switch {
+	case err == nil:
+		f.Close()
+	case errors.Is(err, blobpkg.ErrNotFound):
+		data := avatarpkg.Generate(s.defaultAvatarStyle, seed, name)
+		if err := s.uploads.Put(ctx, key, bytes.NewReader(data), int64(len(data)), avatarpkg.ContentType); err != nil {
+			return "", fmt.Errorf("保存默认头像失败: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("读取默认头像失败: %w", err)
+	}
+
+	s.defaultAvatars.Store(key, struct{}{})
+	return s.uploads.URL(key), nil
+}
+
+// avatarKeys 头像地址对应的所有存储文件，不是本存储的地址时为空
+func (s *userService) avatarKeys(avatarURL string) []string {
+	key, ok := s.uploads.Key(avatarURL)
+	if !ok {
+		return nil
+	}
+	m := avatarKeyPattern.FindStringSubmatch(key)
+	if m == nil {
+		return []string{key}
+	}
+
+	// 尺寸配置可能改过，保存的尺寸也要删除
+	keys := []string{key}
+	for _, size := range s.avatarSizes {
+		if k := fmt.Sprintf("%s_%d.webp", m[1], size); k != key {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// deleteUploads 删除已上传的文件，失败时只记录
+func (s *userService) deleteUploads(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.uploads.Delete(ctx, key); err != nil {
+			fmt.Printf("删除上传文件失败: %v\n", err)
+		}
+	}
+}
+
+// getFollowTarget 根据用户名获取被关注用户
+func (s *userService) getFollowTarget(ctx context.Context, userID uint, username string) (*model.User, error) {
+	target, err := s.userSQL.GetUserByName(ctx, sanitizeUsername(username))
+	if err != nil || target == nil {
+		return nil, ErrUserNotFound
+	}
+	if target.ID == userID {
+		return nil, ErrCannotFollowSelf
+	}
+	return target, nil
+}
+
+// FollowUser 关注用户
+func (s *userService) FollowUser(ctx context.Context, userID uint, username string) error {
+	// 1. 用户级限流
+	rateLimitKey := fmt.Sprintf("follow_user:user:%d", userID)
+	rateLimitConfig := utils.LimitConfig{
+		WindowSize:  time.Minute,
+		MaxRequests: 30,
+	}
+
+	if err := s.rateLimiter.Allow(ctx, rateLimitKey, rateLimitConfig); err != nil {
+		return ErrRateLimited
+	}
+
+	// 2. 获取被关注用户
+	target, err := s.getFollowTarget(ctx, userID, username)
+	if err != nil {
+		return err
+	}
+
+	// 3. 使用分布式锁防止重复关注
+	lockKey := fmt.Sprintf("user_follow:%d:%d", userID, target.ID)
+	err = s.lockManager.GetLock(lockKey, 5*time.Second).Mutex(ctx, func() error {
+		follows, err := s.followSQL.FindFollows(ctx, "user_id = ? AND following_id = ?", userID, target.ID)
+		if err != nil {
+			return fmt.Errorf("查询关注记录失败: %w", err)
+		}
+		if len(follows) > 0 {
+			return ErrAlreadyFollowing
+		}
+
+		if err := s.followSQL.InsertFollow(ctx, userID, target.ID); err != nil {
+			return fmt.Errorf("保存关注记录失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 4. 通知被关注用户
+	if err := s.notificationService.Notify(ctx, &notificationservice.NotificationEvent{
+		UserID:  target.ID,
+		ActorID: userID,
+		Type:    model.NotificationTypeFollow,
+	}); err != nil {
+		fmt.Printf("创建关注通知失败: %v\n", err)
+	}
+
+	return nil
+}
+
+// UnfollowUser 取消关注用户
+func (s *userService) UnfollowUser(ctx context.Context, userID uint, username string) error {
+	target, err := s.getFollowTarget(ctx, userID, username)
+	if err != nil {
+		return err
+	}
+
+	lockKey := fmt.Sprintf("user_follow:%d:%d", userID, target.ID)
+	return s.lockManager.GetLock(lockKey, 5*time.Second).Mutex(ctx, func() error {
+		follows, err := s.followSQL.FindFollows(ctx, "user_id = ? AND following_id = ?", userID, target.ID)
+		if err != nil {
+			return fmt.Errorf("查询关注记录失败: %w", err)
+		}
+		if len(follows) == 0 {
+			return ErrNotFollowing
+		}
+
+		return s.followSQL.DeleteFollow(ctx, userID, target.ID)
+	})
+}
+
+// SetUserStatus 更新用户状态（如举报审核后封禁）
+func (s *userService) SetUserStatus(ctx context.Context, userID uint, status model.UserStatus) error {
+	lockKey := fmt.Sprintf("user_update:%d", userID)
+	return s.lockManager.GetLock(lockKey, 5*time.Second).Mutex(ctx, func() error {
+		if err := s.userSQL.UpdateUser(ctx, userID, map[string]interface{}{"status": status}); err != nil {
+			return fmt.Errorf("更新用户状态失败: %w", err)
+		}
+
+		// 清除缓存，确保登录时读取最新状态
+		s.userCacheLock.Lock()
+		delete(s.userCache, userID)
+		delete(s.userCacheTTL, userID)
+		s.userCacheLock.Unlock()
+
+		return nil
+	})
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -42,48 +44,122 @@ func queryIPLocation(ip string) (*IPLocation, error) {
 	return &location, nil
 }
 
-// GetIPFromContext 从上下文中获取客户端IP
-func GetIPFromContext(ctx context.Context) string {
+// requestFromContext 从上下文中获取HTTP请求
+func requestFromContext(ctx context.Context) *http.Request {
 	// 尝试从Gin上下文中获取
 	if ginCtx, ok := ctx.Value("ginContext").(*gin.Context); ok {
-		return GetClientIP(ginCtx.Request)
+		return ginCtx.Request
 	}
 
 	// 尝试从标准context中获取
 	if req, ok := ctx.Value("httpRequest").(*http.Request); ok {
+		return req
+	}
+
+	return nil
+}
+
+// GetIPFromContext 从上下文中获取客户端IP
+func GetIPFromContext(ctx context.Context) string {
+	if req := requestFromContext(ctx); req != nil {
 		return GetClientIP(req)
 	}
 
 	return "unknown"
 }
 
-// GetClientIP 获取客户端真实IP
-func GetClientIP(r *http.Request) string {
-	// 尝试从X-Forwarded-For获取
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			clientIP := strings.TrimSpace(ips[0])
-			if net.ParseIP(clientIP) != nil {
-				return clientIP
+// GetViewerKey 访客标识（用于去重统计）：登录用户为用户ID，游客为IP和User-Agent的哈希，不保存原始IP
+func GetViewerKey(ctx context.Context) string {
+	if userID, err := GetCurrentUserIDFromContext(ctx); err == nil && userID > 0 {
+		return fmt.Sprintf("u:%d", userID)
+	}
+
+	ip, userAgent := "unknown", ""
+	if req := requestFromContext(ctx); req != nil {
+		ip, userAgent = GetClientIP(req), req.UserAgent()
+	}
+	sum := sha256.Sum256([]byte(ip + "|" + userAgent))
+	return "v:" + hex.EncodeToString(sum[:8])
+}
+
+// trustedProxies 可信的反向代理，只有直接来自这些地址的请求才读取 X-Forwarded-For/X-Real-IP，
+// 否则客户端可以伪造请求头绕过按IP的限流和访客去重
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies 设置可信的反向代理（IP或CIDR）
+func SetTrustedProxies(proxies []string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("无效的代理地址: %s", proxy)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
 			}
+			proxy = fmt.Sprintf("%s/%d", ip.String(), bits)
 		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("无效的代理地址: %s", proxy)
+		}
+		nets = append(nets, ipNet)
 	}
+	trustedProxies = nets
+	return nil
+}
 
-	// 尝试从X-Real-IP获取
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		if net.ParseIP(xri) != nil {
-			return xri
+// isTrustedProxy 是否为可信的反向代理
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
 		}
 	}
+	return false
+}
 
-	// 从RemoteAddr获取
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+// GetClientIP 获取客户端真实IP：请求直接来自可信代理时，从 X-Forwarded-For 右侧开始跳过可信代理，
+// 取第一个不可信的地址（与gin的 ClientIP 一致）；否则使用连接的对端地址
+func GetClientIP(r *http.Request) string {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		return r.RemoteAddr
+		remoteIP = r.RemoteAddr
+	}
+	if !isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	// 尝试从X-Forwarded-For获取
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		ips := strings.Split(xff, ",")
+		clientIP := ""
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(ips[i])
+			if net.ParseIP(ip) == nil {
+				break
+			}
+			clientIP = ip
+			if !isTrustedProxy(ip) {
+				break
+			}
+		}
+		if clientIP != "" {
+			return clientIP
+		}
+	}
+
+	// 尝试从X-Real-IP获取
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xri) != nil {
+		return xri
 	}
 
-	return ip
+	return remoteIP
 }
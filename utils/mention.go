@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 提及前不能是字母数字（避免把邮箱地址识别为提及）
+var mentionRegex = regexp.MustCompile(`(^|[^A-Za-z0-9_.\-@])@([\p{L}\p{N}_\-.]+)`)
+
+const (
+	// 单条内容最多解析的提及数
+	MaxMentions = 10
+	// 候选用户名最大长度（字符数）
+	maxMentionRunes = 50
+)
+
+// ParseMentions 解析内容中的@用户名候选（去重，保持出现顺序）
+func ParseMentions(content string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range mentionRegex.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(m[2], ".-")
+		if utf8.RuneCountInString(name) < 2 || utf8.RuneCountInString(name) > maxMentionRunes {
+			continue
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) >= MaxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// MentionPrefixes 返回候选用户名及其更短的前缀（由长到短）
+// 中文等不以空格分词的文字中，@张三你好 需要依次尝试 张三你好、张三你、张三
+func MentionPrefixes(name string) []string {
+	runes := []rune(name)
+	hasHan := false
+	for _, r := range runes {
+		if unicode.Is(unicode.Han, r) {
+			hasHan = true
+			break
+		}
+	}
+	if !hasHan {
+		return []string{name}
+	}
+
+	prefixes := make([]string, 0, len(runes))
+	for i := len(runes); i >= 2; i-- {
+		prefixes = append(prefixes, string(runes[:i]))
+	}
+	return prefixes
+}
+
+// RenderMentions 将内容转义为HTML，并把已解析的@用户名渲染为用户主页链接
+// names 为实际存在的用户名集合
+func RenderMentions(content string, names map[string]bool) string {
+	escaped := html.EscapeString(content)
+	if len(names) == 0 {
+		return escaped
+	}
+
+	return mentionRegex.ReplaceAllStringFunc(escaped, func(match string) string {
+		m := mentionRegex.FindStringSubmatch(match)
+		prefix, candidate := m[1], m[2]
+		for _, name := range MentionPrefixes(strings.TrimRight(candidate, ".-")) {
+			if !names[name] {
+				continue
+			}
+			rest := strings.TrimPrefix(candidate, name)
+			return prefix + `<a class="mention" href="` + UserPath(name) + `">@` + name + `</a>` + rest
+		}
+		return match
+	})
+}
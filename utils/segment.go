@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-ego/gse"
+)
+
+// Word 分词结果（Start/End为字节偏移）
+type Word struct {
+	Text  string
+	Start int
+	End   int
+}
+
+var (
+	segmenter     gse.Segmenter
+	segmenterOnce sync.Once
+	segmenterErr  error
+)
+
+// loadSegmenter 加载中文分词词典（约2秒，首次使用时加载）
+func loadSegmenter() error {
+	segmenterOnce.Do(func() {
+		segmenter.SkipLog = true
+		segmenterErr = segmenter.LoadDictEmbed("zh_s")
+		if segmenterErr != nil {
+			fmt.Printf("加载分词词典失败，改用简单分词: %v\n", segmenterErr)
+		}
+	})
+	return segmenterErr
+}
+
+// WarmUpSegmenter 预加载分词词典，避免首次搜索/发帖时等待
+func WarmUpSegmenter() error {
+	return loadSegmenter()
+}
+
+// isWord 分词结果中是否包含字母或数字（过滤空白和标点）
+func isWord(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// SegmentWords 中英文混合分词，只返回词语（英文转为小写）
+func SegmentWords(text string) []Word {
+	if text == "" {
+		return nil
+	}
+	if err := loadSegmenter(); err != nil {
+		return fallbackWords(text)
+	}
+	return toWords(segmenter.Segment([]byte(text)), 0)
+}
+
+// SegmentSearchWords 用于搜索的分词：长词额外拆出其中的短词，提高召回率
+// 例如 "中华人民共和国" -> 中华人民共和国、中华、人民共和国
+func SegmentSearchWords(text string) []Word {
+	words := SegmentWords(text)
+	if segmenterErr != nil {
+		return words
+	}
+	result := make([]Word, 0, len(words))
+	for _, w := range words {
+		result = append(result, w)
+		if utf8.RuneCountInString(w.Text) <= 2 {
+			continue
+		}
+		subWords := toWords(segmenter.ModeSegment([]byte(w.Text), true), w.Start)
+		if len(subWords) > 1 {
+			result = append(result, subWords...)
+		}
+	}
+	return result
+}
+
+// toWords 过滤空白和标点，offset为片段在原文中的起始偏移
+func toWords(segments []gse.Segment, offset int) []Word {
+	words := make([]Word, 0, len(segments))
+	for _, s := range segments {
+		t := s.Token().Text()
+		if !isWord(t) {
+			continue
+		}
+		words = append(words, Word{Text: t, Start: offset + s.Start(), End: offset + s.End()})
+	}
+	return words
+}
+
+// fallbackWords 词典加载失败时的简单分词：连续的字母数字为一个词，汉字按相邻两字切分，
+// 索引和搜索使用相同的切分，搜索效果变差但仍然可用
+func fallbackWords(text string) []Word {
+	var words []Word
+	wordStart := -1 // 当前字母数字词的起始偏移
+	var han []Word  // 当前连续汉字，每个字一项
+	flushWord := func(end int) {
+		if wordStart >= 0 {
+			words = append(words, Word{Text: strings.ToLower(text[wordStart:end]), Start: wordStart, End: end})
+			wordStart = -1
+		}
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			words = append(words, han[0])
+		}
+		for i := 0; i+1 < len(han); i++ {
+			words = append(words, Word{Text: han[i].Text + han[i+1].Text, Start: han[i].Start, End: han[i+1].End})
+		}
+		han = han[:0]
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		end := i + size
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord(i)
+			han = append(han, Word{Text: text[i:end], Start: i, End: end})
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			flushHan()
+			if wordStart < 0 {
+				wordStart = i
+			}
+		default:
+			flushWord(i)
+			flushHan()
+		}
+		i = end
+	}
+	flushWord(len(text))
+	flushHan()
+	return words
+}
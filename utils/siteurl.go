@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"net/url"
+	"path"
+)
+
+// 站点页面路径（订阅源、站点地图等共用）
+
+// PostPath 文章页面路径
+func PostPath(slug string) string {
+	return "/posts/" + url.PathEscape(slug)
+}
+
+// CategoryPath 分类页面路径
+func CategoryPath(slug string) string {
+	return "/categories/" + url.PathEscape(slug)
+}
+
+// TagPath 标签页面路径
+func TagPath(slug string) string {
+	return "/tags/" + url.PathEscape(slug)
+}
+
+// UserPath 用户主页路径
+func UserPath(name string) string {
+	return "/users/" + url.PathEscape(name)
+}
+
+// RedirectPath 规范化旧链接路径：以 / 开头，去掉末尾的 /
+func RedirectPath(p string) string {
+	return path.Clean("/" + p)
+}
@@ -1,36 +1,142 @@
 package utils
 
 import (
+	"crypto/rand"
+	"math/big"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// SlugMode 中文slug生成方式
+type SlugMode string
+
+const (
+	// SlugModePinyin 中文转为拼音，词与词之间用连字符分隔
+	SlugModePinyin SlugMode = "pinyin"
+	// SlugModeStrip 直接去掉非ASCII字符
+	SlugModeStrip SlugMode = "strip"
+)
+
+// DefaultSlugMode 默认的slug生成方式，启动时由配置设置
+var DefaultSlugMode = SlugModePinyin
+
+// 短ID长度和字符集
+const (
+	shortIDLength   = 8
+	shortIDAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
 )
 
-// GenerateSlug 从中文/英文生成URL友好的slug
+var (
+	slugInvalidRegex = regexp.MustCompile("[^a-z0-9-]+")
+	slugHyphenRegex  = regexp.MustCompile("-+")
+	pinyinArgs       = pinyin.NewArgs()
+)
+
+// GenerateSlug 从中文/英文生成URL友好的slug，结果为空时使用短ID
 func GenerateSlug(input string) string {
+	if slug := buildSlug(input, DefaultSlugMode); slug != "" {
+		return slug
+	}
+	return ShortID()
+}
+
+// SanitizeSlug 清理用户输入的slug（结果可能为空，由调用方决定如何回退）
+func SanitizeSlug(input string) string {
+	return buildSlug(input, DefaultSlugMode)
+}
+
+// buildSlug 按指定方式生成slug
+func buildSlug(input string, mode SlugMode) string {
 	// 1. 去除首尾空格
 	trimmed := strings.TrimSpace(input)
 
-	// 2. 转换为小写
+	// 2. 中文转拼音（按词分隔）
+	if mode == SlugModePinyin && hasHan(trimmed) {
+		trimmed = transliterate(trimmed)
+	}
+
+	// 3. 转换为小写
 	lower := strings.ToLower(trimmed)
 
-	// 3. 替换空格为连字符
+	// 4. 替换空格为连字符
 	withHyphens := strings.ReplaceAll(lower, " ", "-")
 
-	// 4. 移除特殊字符，只保留字母、数字、连字符
-	reg := regexp.MustCompile("[^a-z0-9-]+")
-	cleaned := reg.ReplaceAllString(withHyphens, "")
+	// 5. 移除特殊字符，只保留字母、数字、连字符
+	cleaned := slugInvalidRegex.ReplaceAllString(withHyphens, "")
 
-	// 5. 移除连续的连字符
-	reg = regexp.MustCompile("-+")
-	final := reg.ReplaceAllString(cleaned, "-")
+	// 6. 移除连续的连字符
+	final := slugHyphenRegex.ReplaceAllString(cleaned, "-")
 
-	// 6. 移除首尾的连字符
+	// 7. 移除首尾的连字符
 	final = strings.Trim(final, "-")
 
 	return final
 }
 
-// SanitizeSlug 清理用户输入的slug
-func SanitizeSlug(input string) string {
-	return GenerateSlug(input)
+// hasHan 是否包含汉字
+func hasHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// transliterate 分词后将每个词转为拼音，词之间用空格分隔
+// 例如 "我的第一篇博客" -> "wo de diyipian boke"
+func transliterate(s string) string {
+	words := SegmentWords(s)
+	if len(words) == 0 {
+		return pinyinWord(s)
+	}
+
+	parts := make([]string, 0, len(words))
+	for _, w := range words {
+		parts = append(parts, pinyinWord(w.Text))
+	}
+	return strings.Join(parts, " ")
+}
+
+// pinyinWord 将单个词中的汉字转为拼音（不带声调），其他字符原样保留
+func pinyinWord(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if !unicode.Is(unicode.Han, r) {
+			b.WriteRune(r)
+			continue
+		}
+		if py := pinyin.LazyPinyin(string(r), pinyinArgs); len(py) > 0 {
+			b.WriteString(py[0])
+		}
+	}
+	return b.String()
+}
+
+// PinyinKey 中文文本的连写拼音（如 "博客系统" -> "bokexitong"），不含汉字时返回空字符串
+func PinyinKey(input string) string {
+	if !hasHan(input) {
+		return ""
+	}
+	return strings.ReplaceAll(buildSlug(input, SlugModePinyin), "-", "")
+}
+
+// ShortID 生成8位随机短ID（小写字母和数字）
+func ShortID() string {
+	b := make([]byte, shortIDLength)
+	max := big.NewInt(int64(len(shortIDAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			// 随机数不可用时退化为时间戳
+			return strconv.FormatInt(time.Now().UnixNano(), 36)
+		}
+		b[i] = shortIDAlphabet[n.Int64()]
+	}
+	return string(b)
 }
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SummaryRunes 没有摘要时从正文截取的长度（字符数）
+const SummaryRunes = 200
+
+// Summarize 文章摘要，没有填写时从正文截取
+func Summarize(summary, content string) string {
+	if summary = strings.TrimSpace(summary); summary != "" {
+		return summary
+	}
+	content = strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(content) <= SummaryRunes {
+		return content
+	}
+	return string([]rune(content)[:SummaryRunes]) + "…"
+}